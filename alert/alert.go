@@ -0,0 +1,285 @@
+// Package alert sends notifications for device events matching
+// alerting rules to webhooks, email and push services.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultCooldown is the minimum time between two notifications of
+// a rule for the same device.
+const DefaultCooldown = 15 * time.Minute
+
+// Rule selects the events to notify about.
+type Rule struct {
+	Name string `json:"name"`
+	// Event is the watch event type, eg online, error or setpoint
+	Event string `json:"event"`
+	// Devices are GUIDs, names, aliases or group names, all devices
+	// when empty
+	Devices []string `json:"devices"`
+	// From and To are shell patterns matched against the old and new
+	// value, eg "false" or "2?". Error events without To only match
+	// when an error is reported, not when it is cleared.
+	From *string `json:"from"`
+	To   *string `json:"to"`
+	// Above and Below match numeric new values
+	Above *float64 `json:"above"`
+	Below *float64 `json:"below"`
+	// Message is a text/template executed with the event, a
+	// description of the change when empty
+	Message  string        `json:"message"`
+	Cooldown time.Duration `json:"cooldown"`
+	// Sinks are the names of the sinks to notify, all sinks when empty
+	Sinks []string `json:"sinks"`
+}
+
+// Config is the alerting configuration.
+type Config struct {
+	Cooldown time.Duration `json:"cooldown"`
+	Rules    []Rule        `json:"rules"`
+	Sinks    []SinkConfig  `json:"sinks"`
+}
+
+// Alert is a notification sent to sinks.
+type Alert struct {
+	Rule    string      `json:"rule"`
+	Title   string      `json:"title"`
+	Message string      `json:"message"`
+	Event   watch.Event `json:"event"`
+}
+
+// rule is a Rule with its devices resolved and templates parsed.
+type rule struct {
+	Rule
+	devices  map[string]bool
+	message  *template.Template
+	cooldown time.Duration
+}
+
+// state is what was last sent for a rule and device.
+type state struct {
+	sent time.Time
+	// value is the new value of the last notification, it is cleared
+	// when the rule stops matching
+	value string
+}
+
+// Dispatcher matches events against rules and notifies sinks.
+type Dispatcher struct {
+	Sinks map[string]Sink
+
+	rules []*rule
+	mu    sync.Mutex
+	state map[string]*state
+}
+
+// eventTypes are the event types rules may select.
+var eventTypes = map[watch.EventType]bool{
+	watch.Power:              true,
+	watch.Mode:               true,
+	watch.Setpoint:           true,
+	watch.InsideTemperature:  true,
+	watch.OutsideTemperature: true,
+	watch.ErrorState:         true,
+	watch.Online:             true,
+}
+
+// New creates a Dispatcher from the config. Device names, aliases and
+// groups of the rules are resolved to GUIDs once.
+func New(client *cloudcontrol.Client, config Config) (*Dispatcher, error) {
+	d := &Dispatcher{Sinks: map[string]Sink{}, state: map[string]*state{}}
+	for _, sc := range config.Sinks {
+		if _, found := d.Sinks[sc.Name]; found || sc.Name == "" {
+			return nil, fmt.Errorf("error: alert sink name %q is empty or used more than once", sc.Name)
+		}
+		sink, err := sc.Sink()
+		if err != nil {
+			return nil, err
+		}
+		d.Sinks[sc.Name] = sink
+	}
+
+	cooldown := config.Cooldown
+	if cooldown == 0 {
+		cooldown = DefaultCooldown
+	}
+	for _, r := range config.Rules {
+		if !eventTypes[watch.EventType(r.Event)] {
+			return nil, fmt.Errorf("error: alert rule %q has unknown event %q", r.Name, r.Event)
+		}
+		for _, name := range r.Sinks {
+			if _, found := d.Sinks[name]; !found {
+				return nil, fmt.Errorf("error: alert rule %q has unknown sink %q", r.Name, name)
+			}
+		}
+		for _, pattern := range []*string{r.From, r.To} {
+			if pattern == nil {
+				continue
+			}
+			if _, err := path.Match(*pattern, ""); err != nil {
+				return nil, fmt.Errorf("error: alert rule %q has invalid pattern %q: %w", r.Name, *pattern, err)
+			}
+		}
+
+		compiled := &rule{Rule: r, cooldown: cooldown}
+		if r.Cooldown != 0 {
+			compiled.cooldown = r.Cooldown
+		}
+		if r.Message != "" {
+			t, err := template.New(r.Name).Parse(r.Message)
+			if err != nil {
+				return nil, fmt.Errorf("error: alert rule %q has invalid message: %w", r.Name, err)
+			}
+			compiled.message = t
+		}
+		if len(r.Devices) > 0 {
+			compiled.devices = map[string]bool{}
+			for _, device := range r.Devices {
+				guids, err := client.ResolveDevices(device)
+				if err != nil {
+					return nil, fmt.Errorf("error: alert rule %q: %w", r.Name, err)
+				}
+				for _, guid := range guids {
+					compiled.devices[guid] = true
+				}
+			}
+		}
+		d.rules = append(d.rules, compiled)
+	}
+
+	return d, nil
+}
+
+// match reports whether the pattern matches the value.
+func match(pattern *string, value interface{}) bool {
+	if pattern == nil {
+		return true
+	}
+	ok, _ := path.Match(*pattern, fmt.Sprint(value))
+	return ok
+}
+
+// matches reports whether the event of a selected device matches the
+// conditions of the rule.
+func (r *rule) matches(e watch.Event) bool {
+	if r.To == nil && e.Type == watch.ErrorState && e.New == "" {
+		return false
+	}
+	if !match(r.From, e.Old) || !match(r.To, e.New) {
+		return false
+	}
+	if r.Above != nil || r.Below != nil {
+		v, ok := e.New.(float64)
+		if !ok || (r.Above != nil && v <= *r.Above) || (r.Below != nil && v >= *r.Below) {
+			return false
+		}
+	}
+	return true
+}
+
+// alert creates the notification of an event.
+func (r *rule) alert(e watch.Event) Alert {
+	name := e.DeviceName
+	if name == "" {
+		name = e.DeviceGUID
+	}
+	a := Alert{
+		Rule:    r.Name,
+		Title:   fmt.Sprintf("%s: %s", name, r.Name),
+		Message: fmt.Sprintf("%s %s changed from %v to %v", name, e.Type, e.Old, e.New),
+		Event:   e,
+	}
+	if r.message != nil {
+		var b bytes.Buffer
+		if err := r.message.Execute(&b, e); err != nil {
+			log.Errorf("Alert rule %q message failed: %v", r.Name, err)
+		} else {
+			a.Message = b.String()
+		}
+	}
+	return a
+}
+
+// Handle notifies the sinks of all rules matching the event. A rule
+// notifies once per device until it stops matching, and not more than
+// once per cool-down. Cool-downs use the event time.
+func (d *Dispatcher) Handle(ctx context.Context, e watch.Event) error {
+	var firstErr error
+	for i, r := range d.rules {
+		if watch.EventType(r.Event) != e.Type || (r.devices != nil && !r.devices[e.DeviceGUID]) {
+			continue
+		}
+
+		key := fmt.Sprintf("%d/%s", i, e.DeviceGUID)
+		value := fmt.Sprint(e.New)
+		d.mu.Lock()
+		s, found := d.state[key]
+		if !found {
+			s = &state{}
+			d.state[key] = s
+		}
+		send := false
+		switch {
+		case !r.matches(e):
+			s.value = ""
+		case s.value == value:
+			log.Debugf("Alert %q for %s is a duplicate", r.Name, e.DeviceGUID)
+		case !s.sent.IsZero() && e.Time.Sub(s.sent) < r.cooldown:
+			log.Debugf("Alert %q for %s is cooling down", r.Name, e.DeviceGUID)
+		default:
+			s.sent, s.value = e.Time, value
+			send = true
+		}
+		d.mu.Unlock()
+
+		if send {
+			if err := d.notify(ctx, r, r.alert(e)); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// notify sends an alert to the sinks of a rule.
+func (d *Dispatcher) notify(ctx context.Context, r *rule, a Alert) error {
+	names := r.Sinks
+	if len(names) == 0 {
+		for name := range d.Sinks {
+			names = append(names, name)
+		}
+	}
+
+	var firstErr error
+	for _, name := range names {
+		if err := d.Sinks[name].Send(ctx, a); err != nil {
+			log.Errorf("Sending alert %q to %s failed: %v", a.Rule, name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error: sending alert to %s: %w", name, err)
+			}
+			continue
+		}
+		log.Infof("Sent alert %q to %s", a.Rule, name)
+	}
+
+	return firstErr
+}
+
+// Run handles events until the channel is closed.
+func (d *Dispatcher) Run(ctx context.Context, events <-chan watch.Event) {
+	for e := range events {
+		_ = d.Handle(ctx, e)
+	}
+}
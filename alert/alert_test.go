@@ -0,0 +1,281 @@
+package alert_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/alert"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// receiver records the requests sent to it.
+type receiver struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	line := req.Method + " " + req.URL.Path
+	for _, h := range []string{"Title", "Priority", "Authorization", "X-Gotify-Key", "X-Custom"} {
+		if v := req.Header.Get(h); v != "" {
+			line += " " + h + "=" + v
+		}
+	}
+	r.mu.Lock()
+	r.requests = append(r.requests, line+" "+strings.TrimSpace(string(body)))
+	r.mu.Unlock()
+}
+
+func cloud() *httptest.Server {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"device1","deviceName":"Living"},{"deviceGuid":"device2","deviceName":"Office"}]}]}`))
+	})
+	return httptest.NewServer(handler)
+}
+
+func str(s string) *string { return &s }
+
+func TestDispatcher(t *testing.T) {
+	ts := cloud()
+	defer ts.Close()
+	client := cloudcontrol.NewClient(ts.URL)
+	hook, push, gotify := &receiver{}, &receiver{}, &receiver{}
+	hookServer, pushServer, gotifyServer := httptest.NewServer(hook), httptest.NewServer(push), httptest.NewServer(gotify)
+	defer hookServer.Close()
+	defer pushServer.Close()
+	defer gotifyServer.Close()
+
+	above := 25.0
+	config := alert.Config{
+		Sinks: []alert.SinkConfig{
+			{Name: "hook", Type: "webhook", URL: hookServer.URL + "/hook", Headers: map[string]string{"X-Custom": "yes"},
+				Body: `{"text":{{json .Message}},"device":{{json .Event.DeviceName}}}`},
+			{Name: "push", Type: "ntfy", URL: pushServer.URL + "/panasonic", Token: "secret", Priority: 4},
+			{Name: "gotify", Type: "gotify", URL: gotifyServer.URL + "/", Token: "app"},
+		},
+		Rules: []alert.Rule{
+			{Name: "offline", Event: "online", To: str("false"), Sinks: []string{"hook", "push"}},
+			{Name: "error", Event: "error", Devices: []string{"Living"}, Message: "{{.DeviceName}} reports {{.New}}", Sinks: []string{"gotify"}},
+			{Name: "hot", Event: "setpoint", Above: &above, Cooldown: 5 * time.Minute, Sinks: []string{"push"}},
+		},
+	}
+	d, err := alert.New(&client, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(minutes int, device string, typ watch.EventType, old, new interface{}) watch.Event {
+		return watch.Event{Time: start.Add(time.Duration(minutes) * time.Minute), DeviceGUID: device, DeviceName: map[string]string{"device1": "Living", "device2": "Office"}[device], Type: typ, Old: old, New: new}
+	}
+	events := []watch.Event{
+		event(0, "device1", watch.Online, true, false),  // sent
+		event(1, "device2", watch.Online, true, false),  // sent, other device
+		event(2, "device1", watch.Online, false, true),  // no match
+		event(3, "device1", watch.Online, true, false),  // cooling down
+		event(20, "device1", watch.Online, true, false), // sent
+		event(21, "device1", watch.ErrorState, "", "H11"),
+		event(22, "device1", watch.ErrorState, "H11", ""), // error cleared
+		event(23, "device2", watch.ErrorState, "", "H11"), // other device
+		event(30, "device1", watch.Setpoint, 21.0, 22.0),  // below threshold
+		event(31, "device1", watch.Setpoint, 22.0, 26.0),  // sent
+		event(32, "device1", watch.Setpoint, 26.0, 27.0),  // cooling down
+		event(40, "device1", watch.Setpoint, 27.0, 27.5),  // sent
+		event(50, "device1", watch.Setpoint, 27.0, 27.5),  // duplicate
+	}
+	for _, e := range events {
+		if err := d.Handle(context.Background(), e); err != nil {
+			t.Fatalf("Handle(%v): %v", e, err)
+		}
+	}
+
+	wantHook := []string{
+		`POST /hook X-Custom=yes {"text":"Living online changed from true to false","device":"Living"}`,
+		`POST /hook X-Custom=yes {"text":"Office online changed from true to false","device":"Office"}`,
+		`POST /hook X-Custom=yes {"text":"Living online changed from true to false","device":"Living"}`,
+	}
+	if diff := cmp.Diff(wantHook, hook.requests); diff != "" {
+		t.Errorf("webhook mismatch (-want +got):\n%s", diff)
+	}
+	wantPush := []string{
+		"POST /panasonic Title=Living: offline Priority=4 Authorization=Bearer secret Living online changed from true to false",
+		"POST /panasonic Title=Office: offline Priority=4 Authorization=Bearer secret Office online changed from true to false",
+		"POST /panasonic Title=Living: offline Priority=4 Authorization=Bearer secret Living online changed from true to false",
+		"POST /panasonic Title=Living: hot Priority=4 Authorization=Bearer secret Living setpoint changed from 22 to 26",
+		"POST /panasonic Title=Living: hot Priority=4 Authorization=Bearer secret Living setpoint changed from 27 to 27.5",
+	}
+	if diff := cmp.Diff(wantPush, push.requests); diff != "" {
+		t.Errorf("ntfy mismatch (-want +got):\n%s", diff)
+	}
+	wantGotify := []string{
+		`POST /message X-Gotify-Key=app {"message":"Living reports H11","priority":0,"title":"Living: error"}`,
+	}
+	if diff := cmp.Diff(wantGotify, gotify.requests); diff != "" {
+		t.Errorf("gotify mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultWebhookBody(t *testing.T) {
+	hook := &receiver{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+	sink, err := alert.SinkConfig{Name: "hook", Type: "webhook", URL: server.URL}.Sink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := alert.Alert{Rule: "offline", Title: "title", Message: "message", Event: watch.Event{DeviceGUID: "device1", Type: watch.Online, Old: true, New: false}}
+	if err := sink.Send(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+
+	got := alert.Alert{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(hook.requests[0], "POST / ")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(a, got); diff != "" {
+		t.Errorf("body mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// smtpServer is a stand-in SMTP server accepting a single mail.
+func smtpServer(t *testing.T) (string, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	mail := make(chan []string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := textproto.NewConn(conn)
+		got := []string{}
+		_ = c.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := c.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.Fields(line)[0]) {
+			case "EHLO":
+				_ = c.PrintfLine("250-localhost")
+				_ = c.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				credentials, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+				got = append(got, "AUTH "+strings.ReplaceAll(string(credentials), "\x00", " "))
+				_ = c.PrintfLine("235 OK")
+			case "DATA":
+				_ = c.PrintfLine("354 Go ahead")
+				lines, _ := c.ReadDotLines()
+				for _, l := range lines {
+					if !strings.HasPrefix(l, "Date:") {
+						got = append(got, l)
+					}
+				}
+				_ = c.PrintfLine("250 OK")
+			case "QUIT":
+				_ = c.PrintfLine("221 Bye")
+				mail <- got
+				return
+			default:
+				got = append(got, line)
+				_ = c.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), mail
+}
+
+func TestEmail(t *testing.T) {
+	addr, mail := smtpServer(t)
+	sink, err := alert.SinkConfig{Name: "mail", Type: "email", Host: addr, Username: "user", Password: "pass",
+		From: "panasonic@example.com", To: []string{"me@example.com"}}.Sink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(context.Background(), alert.Alert{Title: "Living: 5 °C", Message: "Living is 5 °C"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"AUTH  user pass",
+		"MAIL FROM:<panasonic@example.com>",
+		"RCPT TO:<me@example.com>",
+		"From: panasonic@example.com",
+		"To: me@example.com",
+		"Subject: =?utf-8?q?Living:_5_=C2=B0C?=",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		"Living is 5 °C",
+	}
+	select {
+	case got := <-mail:
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mail mismatch (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+}
+
+func TestEmailTimeout(t *testing.T) {
+	// The server accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			_, _ = ioutil.ReadAll(conn)
+		}
+	}()
+
+	sink := &alert.Email{Addr: listener.Addr().String(), From: "panasonic@example.com", To: []string{"me@example.com"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := sink.Send(ctx, alert.Alert{Title: "title"}); err == nil {
+		t.Error("TestEmailTimeout() succeeded, want error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("TestEmailTimeout() took %v, want the deadline of the context", elapsed)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []alert.Config{
+		{Rules: []alert.Rule{{Name: "bad", Event: "temperature"}}},
+		{Rules: []alert.Rule{{Name: "bad", Event: "online", Sinks: []string{"missing"}}}},
+		{Rules: []alert.Rule{{Name: "bad", Event: "online", To: str("[")}}},
+		{Sinks: []alert.SinkConfig{{Name: "bad", Type: "pager"}}},
+		{Sinks: []alert.SinkConfig{{Name: "bad", Type: "webhook", URL: "http://localhost", Body: "{{"}}},
+	}
+	for _, config := range tests {
+		if _, err := alert.New(nil, config); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", config)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTimeout is the timeout of sending an alert when the context
+// has no deadline.
+const DefaultTimeout = 10 * time.Second
+
+// Sink delivers alerts.
+type Sink interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// SinkConfig configures a sink. Type is webhook, email, ntfy or gotify.
+type SinkConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// URL of webhook, ntfy topic or gotify server
+	URL string `json:"url"`
+	// Method, Headers and Body of webhooks
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// Token of ntfy and gotify
+	Token    string `json:"token"`
+	Priority int    `json:"priority"`
+	// SMTP server (host:port), credentials and addresses of email
+	Host     string   `json:"host"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Sink creates the sink of the config.
+func (c SinkConfig) Sink() (Sink, error) {
+	switch c.Type {
+	case "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("error: alert sink %q has no url", c.Name)
+		}
+		w := &Webhook{URL: c.URL, Method: c.Method, Headers: c.Headers}
+		if c.Body != "" {
+			t, err := template.New(c.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(c.Body)
+			if err != nil {
+				return nil, fmt.Errorf("error: alert sink %q has invalid body: %w", c.Name, err)
+			}
+			w.Body = t
+		}
+		return w, nil
+	case "email":
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("error: alert sink %q needs host, from and to", c.Name)
+		}
+		return &Email{Addr: c.Host, Username: c.Username, Password: c.Password, From: c.From, To: c.To}, nil
+	case "ntfy":
+		if c.URL == "" {
+			return nil, fmt.Errorf("error: alert sink %q has no url", c.Name)
+		}
+		return &Ntfy{URL: c.URL, Token: c.Token, Priority: c.Priority}, nil
+	case "gotify":
+		if c.URL == "" || c.Token == "" {
+			return nil, fmt.Errorf("error: alert sink %q needs url and token", c.Name)
+		}
+		return &Gotify{URL: c.URL, Token: c.Token, Priority: c.Priority}, nil
+	}
+
+	return nil, fmt.Errorf("error: alert sink %q has unknown type %q", c.Name, c.Type)
+}
+
+// toJSON encodes a value for use in JSON body templates.
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// post sends a request and checks for a successful response.
+func post(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body io.Reader) error {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("error: %s returned %s: %s", url, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// Webhook posts alerts as JSON. The body is the alert, or the Body
+// template executed with the alert.
+type Webhook struct {
+	URL     string
+	Method  string // POST when empty
+	Headers map[string]string
+	Body    *template.Template
+	Client  *http.Client
+}
+
+// Send implements Sink.
+func (w *Webhook) Send(ctx context.Context, a Alert) error {
+	var body bytes.Buffer
+	if w.Body != nil {
+		if err := w.Body.Execute(&body, a); err != nil {
+			return err
+		}
+	} else if err := json.NewEncoder(&body).Encode(a); err != nil {
+		return err
+	}
+
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range w.Headers {
+		headers[k] = v
+	}
+	return post(ctx, w.Client, method, w.URL, headers, &body)
+}
+
+// Email sends alerts by SMTP. Credentials are only sent over TLS or
+// to localhost.
+type Email struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Send implements Sink. The mail is sent within the deadline of the
+// context, or within DefaultTimeout.
+func (e *Email) Send(ctx context.Context, a Alert) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return fmt.Errorf("error: invalid SMTP server %q: %w", e.Addr, err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", a.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", a.Message)
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	// The steps of smtp.SendMail, which cannot be given a deadline
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("error: %s does not support authentication", e.Addr)
+		}
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Ntfy publishes alerts to an ntfy topic URL.
+type Ntfy struct {
+	URL      string
+	Token    string
+	Priority int // 1 to 5, the server default when 0
+	Client   *http.Client
+}
+
+// Send implements Sink.
+func (n *Ntfy) Send(ctx context.Context, a Alert) error {
+	headers := map[string]string{"Title": a.Title, "Tags": string(a.Event.Type)}
+	if n.Priority != 0 {
+		headers["Priority"] = fmt.Sprint(n.Priority)
+	}
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	}
+	return post(ctx, n.Client, http.MethodPost, n.URL, headers, strings.NewReader(a.Message))
+}
+
+// Gotify sends alerts as messages to a Gotify server.
+type Gotify struct {
+	URL      string
+	Token    string // Application token
+	Priority int
+	Client   *http.Client
+}
+
+// Send implements Sink.
+func (g *Gotify) Send(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    a.Title,
+		"message":  a.Message,
+		"priority": g.Priority,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json", "X-Gotify-Key": g.Token}
+	return post(ctx, g.Client, http.MethodPost, strings.TrimSuffix(g.URL, "/")+"/message", headers, bytes.NewReader(body))
+}
@@ -0,0 +1,473 @@
+// Package api serves a small authenticated HTTP JSON API for
+// Panasonic Comfort Cloud devices, so tools can control devices
+// without holding Panasonic credentials themselves.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cache"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Prefix is the path all API routes are served under
+const Prefix = "/api/v1"
+
+// maxBodySize is the largest request body accepted
+const maxBodySize = 64 << 10
+
+// Key is an API key with the devices it may access.
+type Key struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Devices are GUIDs, names, aliases or group names, "*"
+	// gives access to all devices
+	Devices []string `json:"devices"`
+	// Write allows changing the state of the devices
+	Write bool `json:"write"`
+}
+
+// grant is a key with its devices resolved to GUIDs.
+type grant struct {
+	Key
+	all     bool
+	devices map[string]bool
+}
+
+// allows reports if the grant gives access to a device.
+func (g *grant) allows(guid string) bool {
+	return g.all || g.devices[guid]
+}
+
+// Device is a device in the device list.
+type Device struct {
+	DeviceGUID string `json:"deviceGuid"`
+	DeviceName string `json:"deviceName"`
+	GroupName  string `json:"groupName"`
+	Model      string `json:"model"`
+	// SummerHouse is non-zero when summer house mode is enabled
+	SummerHouse int `json:"summerHouse"`
+}
+
+// State is the desired state of a device, fields that are not set
+// are left unchanged.
+type State struct {
+	Power       *string  `json:"power,omitempty"` // on or off
+	Mode        *string  `json:"mode,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	FanSpeed    *string  `json:"fanSpeed,omitempty"`
+}
+
+// Parameters converts the state to control parameters.
+func (s State) Parameters() (pt.DeviceControlParameters, error) {
+	parameters := pt.DeviceControlParameters{}
+	if s.Power != nil {
+		operate := -1
+		for k, v := range pt.Operate {
+			if strings.EqualFold(v, *s.Power) {
+				operate = k
+			}
+		}
+		if operate < 0 {
+			return parameters, fmt.Errorf("error: invalid power %q, use on or off", *s.Power)
+		}
+		parameters.Operate = &operate
+	}
+	if s.Mode != nil {
+		mode, ok := pt.Modes[*s.Mode]
+		if !ok {
+			return parameters, fmt.Errorf("error: invalid mode %q", *s.Mode)
+		}
+		parameters.OperationMode = &mode
+	}
+	if s.Temperature != nil {
+		if *s.Temperature*2 != math.Round(*s.Temperature*2) {
+			return parameters, fmt.Errorf("error: invalid temperature %v, use steps of 0.5", *s.Temperature)
+		}
+		parameters.TemperatureSet = s.Temperature
+	}
+	if s.FanSpeed != nil {
+		speed, ok := pt.FanSpeeds[*s.FanSpeed]
+		if !ok {
+			return parameters, fmt.Errorf("error: invalid fan speed %q", *s.FanSpeed)
+		}
+		parameters.FanSpeed = &speed
+	}
+	if parameters == (pt.DeviceControlParameters{}) {
+		return parameters, fmt.Errorf("error: no state given")
+	}
+
+	return parameters, nil
+}
+
+// temperatureRange returns the setpoint range of a device in a mode,
+// zero when the device reports no range.
+func temperatureRange(d pt.Device, mode int) (float64, float64) {
+	switch mode {
+	case pt.Modes["auto"]:
+		return float64(d.AutoTempMin), float64(d.AutoTempMax)
+	case pt.Modes["dry"]:
+		return float64(d.DryTempMin), float64(d.DryTempMax)
+	case pt.Modes["cool"]:
+		return float64(d.CoolTempMin), float64(d.CoolTempMax)
+	case pt.Modes["heat"]:
+		return float64(d.HeatTempMin), float64(d.HeatTempMax)
+	}
+	return 0, 0
+}
+
+// Error is the body of error responses.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// httpError is an error with the status code to respond with.
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func (e *httpError) Unwrap() error { return e.err }
+
+// withStatus annotates an error with a response status code.
+func withStatus(code int, err error) error {
+	return &httpError{code: code, err: err}
+}
+
+// Server serves the API.
+type Server struct {
+	Client *cloudcontrol.Client
+	Cache  *cache.Cache
+
+	grants []*grant
+}
+
+// New creates a Server for the given keys. Device names, aliases and
+// groups of the keys are resolved to GUIDs once.
+func New(client *cloudcontrol.Client, statuses *cache.Cache, keys []Key) (*Server, error) {
+	s := &Server{Client: client, Cache: statuses}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("error: API key %q has no key", key.Name)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("error: API key %q is used more than once", key.Name)
+		}
+		seen[key.Key] = true
+
+		g := &grant{Key: key, devices: map[string]bool{}}
+		for _, device := range key.Devices {
+			if device == "*" {
+				g.all = true
+				continue
+			}
+			guids, err := client.ResolveDevices(device)
+			if err != nil {
+				return nil, fmt.Errorf("error: API key %q: %w", key.Name, err)
+			}
+			for _, guid := range guids {
+				g.devices[guid] = true
+			}
+		}
+		s.grants = append(s.grants, g)
+	}
+
+	return s, nil
+}
+
+// authenticate returns the grant of the key in the request, given as
+// a bearer token or in the X-API-Key header.
+func (s *Server) authenticate(r *http.Request) (*grant, error) {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key == "" {
+		return nil, withStatus(http.StatusUnauthorized, errors.New("error: missing API key"))
+	}
+	for _, g := range s.grants {
+		if subtle.ConstantTimeCompare([]byte(g.Key.Key), []byte(key)) == 1 {
+			return g, nil
+		}
+	}
+	return nil, withStatus(http.StatusUnauthorized, errors.New("error: invalid API key"))
+}
+
+// request is an authenticated API request.
+type request struct {
+	*http.Request
+	grant  *grant
+	device string // GUID of the device in the path
+}
+
+// route is an API route, also used to generate the OpenAPI spec.
+type route struct {
+	method   string
+	path     string // below Prefix, {id} is a device GUID
+	summary  string
+	write    bool
+	query    []parameter
+	body     string // schema of the request body
+	response string // schema of the response
+	handle   func(s *Server, r *request) (interface{}, error)
+}
+
+// parameter is a query parameter of a route.
+type parameter struct {
+	name, description string
+}
+
+var routes = []route{
+	{
+		method:   http.MethodGet,
+		path:     "/devices",
+		summary:  "List the devices the key has access to",
+		response: "Devices",
+		handle:   (*Server).listDevices,
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/devices/{id}",
+		summary:  "Get the cached status of a device",
+		response: "Status",
+		handle:   (*Server).getStatus,
+	},
+	{
+		method:   http.MethodPut,
+		path:     "/devices/{id}/state",
+		summary:  "Set the desired state of a device",
+		write:    true,
+		body:     "State",
+		response: "Status",
+		handle:   (*Server).setState,
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/devices/{id}/history",
+		summary: "Get the energy history of a device",
+		query: []parameter{
+			{"period", "History period: day, week, month or year, defaults to day"},
+			{"date", "Date (YYYY-MM-DD) the period ends on, defaults to today"},
+		},
+		response: "History",
+		handle:   (*Server).getHistory,
+	},
+}
+
+// match matches a path against a route path, returning the device id.
+func match(pattern, path string) (string, bool) {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	if len(patternParts) != len(pathParts) {
+		return "", false
+	}
+	id := ""
+	for i, part := range patternParts {
+		if part == "{id}" && pathParts[i] != "" {
+			id = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return "", false
+		}
+	}
+	return id, true
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Writing response failed: %v", err)
+	}
+}
+
+// writeError writes an error response. Errors from the cloud are
+// reported as a bad gateway.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusBadGateway
+	var he *httpError
+	if errors.As(err, &he) {
+		code = he.code
+	}
+	writeJSON(w, code, Error{Error: err.Error()})
+}
+
+// ServeHTTP routes and authenticates API requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	path := strings.TrimPrefix(r.URL.Path, Prefix)
+	if path == "/openapi.json" {
+		writeJSON(w, http.StatusOK, Spec())
+		return
+	}
+
+	for i := range routes {
+		rt := &routes[i]
+		id, ok := match(rt.path, path)
+		if !ok {
+			continue
+		}
+		if r.Method != rt.method {
+			writeError(w, withStatus(http.StatusMethodNotAllowed, fmt.Errorf("error: method %s not allowed", r.Method)))
+			return
+		}
+		g, err := s.authenticate(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if rt.write && !g.Write {
+			writeError(w, withStatus(http.StatusForbidden, fmt.Errorf("error: API key %q is read only", g.Name)))
+			return
+		}
+		req := &request{Request: r, grant: g}
+		if id != "" {
+			if req.device, err = s.device(g, id); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		log.Debugf("API %s %s by %s", r.Method, r.URL.Path, g.Name)
+		body, err := rt.handle(s, req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, body)
+		return
+	}
+
+	writeError(w, withStatus(http.StatusNotFound, fmt.Errorf("error: %s not found", r.URL.Path)))
+}
+
+// device looks up a device by GUID or name and checks the key may
+// access it. Devices the key has no access to are reported as not
+// found.
+func (s *Server) device(g *grant, id string) (string, error) {
+	devices := s.Cache.Devices()
+	if len(devices) == 0 {
+		if err := s.Cache.Poll(); err != nil {
+			return "", err
+		}
+		devices = s.Cache.Devices()
+	}
+	for _, d := range devices {
+		if (d.DeviceGUID == id || strings.EqualFold(d.DeviceName, id)) && g.allows(d.DeviceGUID) {
+			return d.DeviceGUID, nil
+		}
+	}
+	return "", withStatus(http.StatusNotFound, fmt.Errorf("error: device %s not found", id))
+}
+
+func (s *Server) listDevices(r *request) (interface{}, error) {
+	if len(s.Cache.Devices()) == 0 {
+		if err := s.Cache.Poll(); err != nil {
+			return nil, err
+		}
+	}
+	devices := []Device{}
+	for _, d := range s.Cache.Devices() {
+		if r.grant.allows(d.DeviceGUID) {
+			devices = append(devices, Device{
+				DeviceGUID:  d.DeviceGUID,
+				DeviceName:  d.DeviceName,
+				GroupName:   d.GroupName,
+				Model:       d.DeviceModuleNumber,
+				SummerHouse: d.SummerHouse,
+			})
+		}
+	}
+	return devices, nil
+}
+
+func (s *Server) getStatus(r *request) (interface{}, error) {
+	return s.Cache.Status(r.device)
+}
+
+func (s *Server) setState(r *request) (interface{}, error) {
+	state := State{}
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		return nil, withStatus(http.StatusBadRequest, fmt.Errorf("error: invalid state: %w", err))
+	}
+	parameters, err := state.Parameters()
+	if err != nil {
+		return nil, withStatus(http.StatusBadRequest, err)
+	}
+	if err := s.checkTemperature(r.device, parameters); err != nil {
+		return nil, err
+	}
+
+	client := *s.Client
+	client.SetDevice(r.device)
+	if _, err := client.SetState(parameters); err != nil {
+		return nil, err
+	}
+	log.Infof("API key %s changed state of %s", r.grant.Name, r.device)
+
+	return s.Cache.Refresh(r.device)
+}
+
+// checkTemperature checks a setpoint against the range of the device in
+// the mode it is set to or is in.
+func (s *Server) checkTemperature(guid string, parameters pt.DeviceControlParameters) error {
+	if parameters.TemperatureSet == nil {
+		return nil
+	}
+	var device pt.Device
+	for _, d := range s.Cache.Devices() {
+		if d.DeviceGUID == guid {
+			device = d.Device
+		}
+	}
+	mode := 0
+	if parameters.OperationMode != nil {
+		mode = *parameters.OperationMode
+	} else {
+		status, err := s.Cache.Status(guid)
+		if err != nil {
+			return err
+		}
+		mode = status.Parameters.OperationMode
+	}
+	t := *parameters.TemperatureSet
+	if min, max := temperatureRange(device, mode); max > 0 && (t < min || t > max) {
+		return withStatus(http.StatusBadRequest, fmt.Errorf("error: temperature %v outside %v-%v for mode %s", t, min, max, pt.ModesReverse[mode]))
+	}
+	return nil
+}
+
+func (s *Server) getHistory(r *request) (interface{}, error) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "day"
+	}
+	mode, ok := pt.HistoryDataMode[period]
+	if !ok {
+		return nil, withStatus(http.StatusBadRequest, fmt.Errorf("error: invalid period %q", period))
+	}
+	date := time.Now()
+	if value := r.URL.Query().Get("date"); value != "" {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			return nil, withStatus(http.StatusBadRequest, fmt.Errorf("error: invalid date %q", value))
+		}
+	}
+
+	client := *s.Client
+	client.SetDevice(r.device)
+	return client.GetDeviceHistoryForDate(mode, date)
+}
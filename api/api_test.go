@@ -0,0 +1,166 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cache"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+var groupsBody = `{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[
+	{"deviceGuid":"device1","deviceName":"Living"},
+	{"deviceGuid":"device2","deviceName":"Bedroom","autoTempMin":17,"autoTempMax":27,"coolTempMin":18,"coolTempMax":30,"heatTempMin":16,"heatTempMax":30}]}]}`
+
+// cloud is a fake Panasonic cloud counting status requests.
+func cloud(commands *[]pt.Command, statusCalls *int) *httptest.Server {
+	var mu sync.Mutex
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(groupsBody))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		*statusCalls++
+		mu.Unlock()
+		guid := strings.TrimPrefix(r.URL.Path, pt.URLDeviceStatus)
+		_, _ = w.Write([]byte(`{"deviceGuid":"` + guid + `","parameters":{"online":true,"temperatureSet":20}}`))
+	})
+	handler.HandleFunc(pt.URLControl, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		command := pt.Command{}
+		_ = json.Unmarshal(body, &command)
+		mu.Lock()
+		*commands = append(*commands, command)
+		mu.Unlock()
+		_, _ = w.Write([]byte(pt.SuccessResponse))
+	})
+	handler.HandleFunc(pt.URLHistory, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"energyConsumption":1.5}`))
+	})
+	return httptest.NewServer(handler)
+}
+
+func TestServer(t *testing.T) {
+	commands := []pt.Command{}
+	statusCalls := 0
+	server := cloud(&commands, &statusCalls)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	statuses := cache.New(&client)
+	if err := statuses.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := api.New(&client, statuses, []api.Key{
+		{Name: "admin", Key: "secret", Devices: []string{"*"}, Write: true},
+		{Name: "dashboard", Key: "readonly", Devices: []string{"Living"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	do := func(method, path, key, body string) (int, string) {
+		req, _ := http.NewRequest(method, ts.URL+api.Prefix+path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	tests := []struct {
+		name, method, path, key, body string
+		want                          int
+	}{
+		{"no key", http.MethodGet, "/devices", "", "", http.StatusUnauthorized},
+		{"invalid key", http.MethodGet, "/devices", "wrong", "", http.StatusUnauthorized},
+		{"list", http.MethodGet, "/devices", "readonly", "", http.StatusOK},
+		{"status", http.MethodGet, "/devices/device1", "readonly", "", http.StatusOK},
+		{"status by name", http.MethodGet, "/devices/living", "readonly", "", http.StatusOK},
+		{"not allowed", http.MethodGet, "/devices/device2", "readonly", "", http.StatusNotFound},
+		{"read only", http.MethodPut, "/devices/device1/state", "readonly", `{"power":"on"}`, http.StatusForbidden},
+		{"invalid state", http.MethodPut, "/devices/device1/state", "secret", `{"mode":"turbo"}`, http.StatusBadRequest},
+		{"temperature out of range", http.MethodPut, "/devices/device2/state", "secret", `{"temperature":30}`, http.StatusBadRequest},
+		{"temperature out of mode range", http.MethodPut, "/devices/device2/state", "secret", `{"mode":"cool","temperature":16}`, http.StatusBadRequest},
+		{"temperature above cool range", http.MethodPut, "/devices/device2/state", "secret", `{"mode":"cool","temperature":31}`, http.StatusBadRequest},
+		{"temperature below heat range", http.MethodPut, "/devices/device2/state", "secret", `{"mode":"heat","temperature":15}`, http.StatusBadRequest},
+		{"temperature step", http.MethodPut, "/devices/device2/state", "secret", `{"temperature":21.3}`, http.StatusBadRequest},
+		{"body too large", http.MethodPut, "/devices/device2/state", "secret", `{"power":"on","fanSpeed":"` + strings.Repeat("x", 1<<20) + `"}`, http.StatusBadRequest},
+		{"set", http.MethodPut, "/devices/device2/state", "secret", `{"power":"on","temperature":21.5}`, http.StatusOK},
+		{"history", http.MethodGet, "/devices/device1/history?period=week", "readonly", "", http.StatusOK},
+		{"invalid period", http.MethodGet, "/devices/device1/history?period=decade", "readonly", "", http.StatusBadRequest},
+		{"wrong method", http.MethodPost, "/devices", "secret", "", http.StatusMethodNotAllowed},
+		{"openapi", http.MethodGet, "/openapi.json", "", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		if got, body := do(test.method, test.path, test.key, test.body); got != test.want {
+			t.Errorf("TestServer(%s) want status %d, got %d: %s", test.name, test.want, got, body)
+		}
+	}
+
+	_, body := do(http.MethodGet, "/devices", "readonly", "")
+	devices := []api.Device{}
+	if err := json.Unmarshal([]byte(body), &devices); err != nil {
+		t.Fatal(err)
+	}
+	want := []api.Device{{DeviceGUID: "device1", DeviceName: "Living", GroupName: "My House"}}
+	if diff := cmp.Diff(want, devices); diff != "" {
+		t.Errorf("TestServer() devices mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(commands) != 1 || commands[0].DeviceGUID != "device2" || *commands[0].Parameters.TemperatureSet != 21.5 {
+		t.Errorf("TestServer() unexpected commands %+v", commands)
+	}
+	// Two polled statuses and one refresh after the state change
+	if statusCalls != 3 {
+		t.Errorf("TestServer() want 3 status calls, got %d", statusCalls)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	spec := api.Spec()
+	paths := spec["paths"].(map[string]interface{})
+	for _, path := range []string{"/api/v1/devices", "/api/v1/devices/{id}", "/api/v1/devices/{id}/state", "/api/v1/devices/{id}/history"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("TestSpec() missing path %s", path)
+		}
+	}
+	if _, err := json.Marshal(spec); err != nil {
+		t.Errorf("TestSpec() spec is not valid JSON: %v", err)
+	}
+
+	// The Device schema documents every field of api.Device
+	schema := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})["Device"].(map[string]interface{})
+	properties := []string{}
+	for name := range schema["properties"].(map[string]interface{}) {
+		properties = append(properties, name)
+	}
+	fields := []string{}
+	device := reflect.TypeOf(api.Device{})
+	for i := 0; i < device.NumField(); i++ {
+		fields = append(fields, strings.Split(device.Field(i).Tag.Get("json"), ",")[0])
+	}
+	sort.Strings(properties)
+	sort.Strings(fields)
+	if diff := cmp.Diff(fields, properties); diff != "" {
+		t.Errorf("TestSpec() Device schema mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// object is a JSON object in the OpenAPI spec.
+type object = map[string]interface{}
+
+// ref returns a reference to a schema component.
+func ref(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+// keys returns the sorted keys of a map as enum values.
+func keys(m map[string]int) []string {
+	values := []string{}
+	for k := range m {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// schemas are the schema components of the spec.
+func schemas() object {
+	number := object{"type": "number"}
+	integer := object{"type": "integer"}
+	str := object{"type": "string"}
+	return object{
+		"Error": object{
+			"type":       "object",
+			"properties": object{"error": str},
+		},
+		"Device": object{
+			"type": "object",
+			"properties": object{
+				"deviceGuid":  str,
+				"deviceName":  str,
+				"groupName":   str,
+				"model":       str,
+				"summerHouse": integer,
+			},
+		},
+		"Devices": object{
+			"type":  "array",
+			"items": ref("Device"),
+		},
+		"Status": object{
+			"type":        "object",
+			"description": "Cached device status as returned by the Panasonic cloud",
+			"properties": object{
+				"deviceGuid": str,
+				"deviceName": str,
+				"updated":    object{"type": "string", "format": "date-time"},
+				"parameters": object{
+					"type": "object",
+					"properties": object{
+						"online":            object{"type": "boolean"},
+						"operate":           integer,
+						"operationMode":     integer,
+						"temperatureSet":    number,
+						"insideTemperature": number,
+						"outTemperature":    number,
+						"fanSpeed":          integer,
+					},
+				},
+			},
+		},
+		"State": object{
+			"type":        "object",
+			"description": "Desired device state, fields that are not set are left unchanged",
+			"properties": object{
+				"power":       object{"type": "string", "enum": []string{"on", "off"}},
+				"mode":        object{"type": "string", "enum": keys(pt.Modes)},
+				"temperature": number,
+				"fanSpeed":    object{"type": "string", "enum": keys(pt.FanSpeeds)},
+			},
+		},
+		"History": object{
+			"type": "object",
+			"properties": object{
+				"energyConsumption": number,
+				"historyDataList": object{
+					"type": "array",
+					"items": object{
+						"type": "object",
+						"properties": object{
+							"dataNumber":         integer,
+							"consumption":        number,
+							"averageSettingTemp": number,
+							"averageInsideTemp":  number,
+							"averageOutsideTemp": number,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// response returns an OpenAPI response using a schema.
+func response(description, schema string) object {
+	return object{
+		"description": description,
+		"content":     object{"application/json": object{"schema": ref(schema)}},
+	}
+}
+
+// Spec returns the OpenAPI 3 specification of the API, generated
+// from the routes it serves.
+func Spec() object {
+	paths := object{}
+	for _, rt := range routes {
+		operation := object{
+			"summary":  rt.summary,
+			"security": []object{{"bearer": []string{}}, {"apiKey": []string{}}},
+			"responses": object{
+				"200": response("OK", rt.response),
+				"401": response("Missing or invalid API key", "Error"),
+				"502": response("Panasonic cloud request failed", "Error"),
+			},
+		}
+		parameters := []object{}
+		if strings.Contains(rt.path, "{id}") {
+			parameters = append(parameters, object{
+				"name": "id", "in": "path", "required": true,
+				"description": "Device GUID or name",
+				"schema":      object{"type": "string"},
+			})
+			operation["responses"].(object)["404"] = response("Device not found or not allowed for the key", "Error")
+		}
+		for _, p := range rt.query {
+			parameters = append(parameters, object{
+				"name": p.name, "in": "query",
+				"description": p.description,
+				"schema":      object{"type": "string"},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if rt.body != "" {
+			operation["requestBody"] = object{
+				"required": true,
+				"content":  object{"application/json": object{"schema": ref(rt.body)}},
+			}
+			operation["responses"].(object)["400"] = response("Invalid request", "Error")
+		}
+		if rt.write {
+			operation["responses"].(object)["403"] = response("API key is read only", "Error")
+		}
+
+		path, ok := paths[Prefix+rt.path].(object)
+		if !ok {
+			path = object{}
+			paths[Prefix+rt.path] = path
+		}
+		path[strings.ToLower(rt.method)] = operation
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "go-panasonic API",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas(),
+			"securitySchemes": object{
+				"bearer": object{"type": "http", "scheme": "bearer"},
+				"apiKey": object{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
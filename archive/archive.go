@@ -0,0 +1,194 @@
+// Package archive stores Panasonic Comfort Cloud history data in a
+// local BoltDB file so it can be queried after it ages out of the cloud.
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	devicesBucket = []byte("devices")
+	hoursBucket   = []byte("hours")
+	daysBucket    = []byte("days")
+)
+
+// Record is a single hourly history measurement for a device.
+type Record struct {
+	Time               time.Time `json:"time"`
+	Consumption        float64   `json:"consumption"`
+	Cost               float64   `json:"cost"`
+	AverageSettingTemp float64   `json:"averageSettingTemp"`
+	AverageInsideTemp  float64   `json:"averageInsideTemp"`
+	AverageOutsideTemp float64   `json:"averageOutsideTemp"`
+}
+
+// Archive is a local history database.
+type Archive struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the archive database at path.
+func Open(path string) (*Archive, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(devicesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Archive{db: db}, nil
+}
+
+// Close closes the archive database.
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// timeKey encodes a timestamp as a sortable bucket key.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.Unix()))
+	return key
+}
+
+// dayKey returns the bucket key for the day containing t.
+func dayKey(t time.Time) []byte {
+	return []byte(t.Format("20060102"))
+}
+
+// deviceBucket returns the bucket for a device, creating it when writable.
+func deviceBucket(tx *bolt.Tx, guid string) (*bolt.Bucket, error) {
+	devices := tx.Bucket(devicesBucket)
+	if !tx.Writable() {
+		return devices.Bucket([]byte(guid)), nil
+	}
+	device, err := devices.CreateBucketIfNotExists([]byte(guid))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range [][]byte{hoursBucket, daysBucket} {
+		if _, err := device.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return device, nil
+}
+
+// Put stores records for a device. Records with an existing timestamp
+// are overwritten, so storing the same data twice is harmless.
+func (a *Archive) Put(guid string, records []Record) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		device, err := deviceBucket(tx, guid)
+		if err != nil {
+			return err
+		}
+		hours := device.Bucket(hoursBucket)
+		for _, r := range records {
+			value, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if err := hours.Put(timeKey(r.Time), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MarkComplete records that all history for the given day has been stored.
+func (a *Archive) MarkComplete(guid string, day time.Time) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		device, err := deviceBucket(tx, guid)
+		if err != nil {
+			return err
+		}
+		return device.Bucket(daysBucket).Put(dayKey(day), []byte{1})
+	})
+}
+
+// Complete reports whether the given day has been fully synced.
+func (a *Archive) Complete(guid string, day time.Time) (bool, error) {
+	complete := false
+	err := a.db.View(func(tx *bolt.Tx) error {
+		device, _ := deviceBucket(tx, guid)
+		if device == nil {
+			return nil
+		}
+		complete = device.Bucket(daysBucket).Get(dayKey(day)) != nil
+		return nil
+	})
+
+	return complete, err
+}
+
+// Devices lists the device GUIDs present in the archive.
+func (a *Archive) Devices() ([]string, error) {
+	devices := []string{}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(devicesBucket).ForEach(func(k, v []byte) error {
+			devices = append(devices, string(k))
+			return nil
+		})
+	})
+
+	return devices, err
+}
+
+// Records returns the stored records for a device in the half-open
+// interval [from, to), ordered by time.
+func (a *Archive) Records(guid string, from, to time.Time) ([]Record, error) {
+	records := []Record{}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		device, _ := deviceBucket(tx, guid)
+		if device == nil {
+			return nil
+		}
+		end := timeKey(to)
+		c := device.Bucket(hoursBucket).Cursor()
+		for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			r := Record{}
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("unmarshal error %v: %s", err, v)
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+
+	return records, err
+}
+
+// Latest returns the most recent stored record for a device.
+// The boolean is false when no records exist.
+func (a *Archive) Latest(guid string) (Record, bool, error) {
+	r := Record{}
+	found := false
+	err := a.db.View(func(tx *bolt.Tx) error {
+		device, _ := deviceBucket(tx, guid)
+		if device == nil {
+			return nil
+		}
+		_, v := device.Bucket(hoursBucket).Cursor().Last()
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &r)
+	})
+
+	return r, found, err
+}
@@ -0,0 +1,230 @@
+package archive_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/archive"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+var (
+	groupsBody   = `{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"device1"}]}]}`
+	completeBody = `{"energyConsumption":0.7,"historyDataList":[{"dataNumber":0,"consumption":0.5,"cost":0.1,"averageSettingTemp":19.0,"averageInsideTemp":18.0,"averageOutsideTemp":10.0},{"dataNumber":1,"consumption":0.2,"cost":0.1,"averageSettingTemp":21.0,"averageInsideTemp":20.0,"averageOutsideTemp":12.0}]}`
+	historyBody  = `{"energyConsumption":0.7,"historyDataList":[{"dataNumber":0,"consumption":0.5,"cost":0.1,"averageSettingTemp":19.0,"averageInsideTemp":18.0,"averageOutsideTemp":10.0},{"dataNumber":1,"consumption":0.2,"cost":0.1,"averageSettingTemp":21.0,"averageInsideTemp":20.0,"averageOutsideTemp":12.0},{"dataNumber":2,"consumption":-255,"cost":-255,"averageSettingTemp":-255,"averageInsideTemp":-255,"averageOutsideTemp":-255}]}`
+)
+
+func setup(t *testing.T, body string) (*archive.Syncer, *int) {
+	requests := 0
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(groupsBody))
+	})
+	handler.HandleFunc(pt.URLHistory, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(body))
+	})
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	a, err := archive.Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+
+	client := cloudcontrol.NewClient(server.URL)
+	syncer := archive.NewSyncer(&client, a)
+	syncer.Backfill = 3
+	syncer.Now = func() time.Time {
+		return time.Date(2021, 1, 10, 15, 0, 0, 0, time.UTC)
+	}
+
+	return syncer, &requests
+}
+
+func TestSync(t *testing.T) {
+	syncer, requests := setup(t, completeBody)
+
+	got, err := syncer.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(8, got); diff != "" {
+		t.Errorf("TestSync() stored mismatch (-want +got):\n%s", diff)
+	}
+
+	// Past days are complete, only today is fetched again.
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(5, *requests); diff != "" {
+		t.Errorf("TestSync() requests mismatch (-want +got):\n%s", diff)
+	}
+
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	records, err := syncer.Archive.Records("device1", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(8, len(records)); diff != "" {
+		t.Errorf("TestSync() records mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSyncGaps(t *testing.T) {
+	syncer, requests := setup(t, historyBody)
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Days with gaps are fetched again within the grace period, only
+	// the oldest day is complete.
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(7, *requests); diff != "" {
+		t.Errorf("TestSyncGaps() requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSyncDeviceError(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"device1"},{"deviceGuid":"device2"}]}]}`))
+	})
+	handler.HandleFunc(pt.URLHistory, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "device1") {
+			http.Error(w, "offline", http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(completeBody))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	a, err := archive.Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	client := cloudcontrol.NewClient(server.URL)
+	syncer := archive.NewSyncer(&client, a)
+	syncer.Backfill = 1
+	syncer.Now = func() time.Time { return time.Date(2021, 1, 10, 15, 0, 0, 0, time.UTC) }
+
+	// The failing first device does not stop the second one
+	n, err := syncer.Sync()
+	var syncErr *archive.SyncError
+	if !errors.As(err, &syncErr) || len(syncErr.Errors) != 1 || syncErr.Errors["device1"] == nil {
+		t.Errorf("TestSyncDeviceError() got error %v, want a SyncError for device1", err)
+	}
+	if n != 4 {
+		t.Errorf("TestSyncDeviceError() stored %d records, want 4 for device2", n)
+	}
+}
+
+func TestSyncDaylightSaving(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip(err)
+	}
+	entries := []string{}
+	for hour := 0; hour < 24; hour++ {
+		entries = append(entries, fmt.Sprintf(`{"dataNumber":%d,"consumption":%d}`, hour, hour+1))
+	}
+	syncer, _ := setup(t, `{"historyDataList":[`+strings.Join(entries, ",")+`]}`)
+	syncer.Backfill = 0
+	syncer.Now = func() time.Time {
+		return time.Date(2021, 3, 28, 15, 0, 0, 0, amsterdam)
+	}
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The clock skips from 02:00 to 03:00, hour 2 is added to hour 3
+	day := time.Date(2021, 3, 28, 0, 0, 0, 0, amsterdam)
+	records, err := syncer.Archive.Records("device1", day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	total := 0.0
+	for _, r := range records {
+		got[r.Time.In(amsterdam).Format("15:04")] = r.Consumption
+		total += r.Consumption
+	}
+	want := map[string]float64{"01:00": 2, "03:00": 7, "04:00": 5, "23:00": 24}
+	for hour, consumption := range want {
+		if got[hour] != consumption {
+			t.Errorf("TestSyncDaylightSaving() %s consumption %v, want %v", hour, got[hour], consumption)
+		}
+	}
+	if len(records) != 23 || total != 300 {
+		t.Errorf("TestSyncDaylightSaving() got %d records with %v kWh, want 23 with 300", len(records), total)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	syncer, _ := setup(t, historyBody)
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	got, err := syncer.Archive.Query("device1", from, to, archive.Day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := archive.Point{
+		Start:              time.Date(2021, 1, 7, 0, 0, 0, 0, time.UTC),
+		Samples:            2,
+		Consumption:        0.7,
+		Cost:               0.2,
+		AverageSettingTemp: 20,
+		AverageInsideTemp:  19,
+		AverageOutsideTemp: 11,
+	}
+	if diff := cmp.Diff(4, len(got)); diff != "" {
+		t.Fatalf("TestQuery() length mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want, got[0]); diff != "" {
+		t.Errorf("TestQuery() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryHalfHourZone(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip(err)
+	}
+	syncer, _ := setup(t, historyBody)
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records at 00:00 and 01:00 UTC are 05:30 and 06:30 in Kolkata
+	from := time.Date(2021, 1, 7, 0, 0, 0, 0, kolkata)
+	got, err := syncer.Archive.Query("device1", from, from.AddDate(0, 0, 1), archive.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	starts := []string{}
+	for _, p := range got {
+		starts = append(starts, p.Start.Format("15:04"))
+	}
+	if diff := cmp.Diff([]string{"05:00", "06:00"}, starts); diff != "" {
+		t.Errorf("TestQueryHalfHourZone() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"fmt"
+	"time"
+)
+
+// Aggregation defines the bucket size used when querying the archive.
+type Aggregation int
+
+// Available aggregations
+const (
+	Hour Aggregation = iota
+	Day
+	Month
+)
+
+// Aggregations maps aggregation names to their values.
+var Aggregations = map[string]Aggregation{
+	"hour":  Hour,
+	"day":   Day,
+	"month": Month,
+}
+
+// Point is an aggregated set of records. Consumption and Cost are summed,
+// temperatures are averaged over the records in the bucket.
+type Point struct {
+	Start              time.Time
+	Samples            int
+	Consumption        float64
+	Cost               float64
+	AverageSettingTemp float64
+	AverageInsideTemp  float64
+	AverageOutsideTemp float64
+}
+
+// truncate returns the start of the aggregation bucket containing t.
+func (agg Aggregation) truncate(t time.Time) time.Time {
+	switch agg {
+	case Day:
+		return startOfDay(t)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	}
+}
+
+// Query returns the records for a device in [from, to) aggregated
+// into buckets.
+func (a *Archive) Query(guid string, from, to time.Time, agg Aggregation) ([]Point, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("error: invalid range %s - %s", from, to)
+	}
+	recs, err := a.Records(guid, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []Point{}
+	for _, r := range recs {
+		start := agg.truncate(r.Time.In(from.Location()))
+		if len(points) == 0 || !points[len(points)-1].Start.Equal(start) {
+			points = append(points, Point{Start: start})
+		}
+		p := &points[len(points)-1]
+		p.Samples++
+		p.Consumption += r.Consumption
+		p.Cost += r.Cost
+		p.AverageSettingTemp += r.AverageSettingTemp
+		p.AverageInsideTemp += r.AverageInsideTemp
+		p.AverageOutsideTemp += r.AverageOutsideTemp
+	}
+	for i := range points {
+		n := float64(points[i].Samples)
+		points[i].AverageSettingTemp /= n
+		points[i].AverageInsideTemp /= n
+		points[i].AverageOutsideTemp /= n
+	}
+
+	return points, nil
+}
@@ -0,0 +1,207 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultBackfill is the number of days fetched for a device that
+// has no history in the archive yet.
+const DefaultBackfill = 30
+
+// DefaultGrace is the number of days after which a day that still has
+// hours without data is marked complete anyway.
+const DefaultGrace = 2
+
+// Syncer copies history from Panasonic Comfort Cloud into an Archive.
+type Syncer struct {
+	Client   *cloudcontrol.Client
+	Archive  *Archive
+	Backfill int              // Days to look back for missing data
+	Grace    int              // Days to keep fetching days with gaps
+	Now      func() time.Time // Current time, defaults to time.Now
+}
+
+// NewSyncer creates a new Syncer for the given client and archive.
+func NewSyncer(client *cloudcontrol.Client, archive *Archive) *Syncer {
+	return &Syncer{
+		Client:   client,
+		Archive:  archive,
+		Backfill: DefaultBackfill,
+		Grace:    DefaultGrace,
+		Now:      time.Now,
+	}
+}
+
+// startOfDay truncates t to midnight in its location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// records converts hourly day-mode history entries to archive records.
+// Entries without data are skipped. The skipped hour on the day daylight
+// saving time starts shares its record with the next hour, so its
+// consumption and cost are added to it.
+func records(day time.Time, history pt.History) []Record {
+	result := []Record{}
+	index := map[int64]int{}
+	for _, e := range history.HistoryEntries {
+		if e.Consumption == pt.HistoryNoData {
+			continue
+		}
+		t := cloudcontrol.HistoryHour(day, e.DataNumber)
+		if i, found := index[t.Unix()]; found {
+			result[i].Consumption += e.Consumption
+			result[i].Cost += e.Cost
+			continue
+		}
+		index[t.Unix()] = len(result)
+		result = append(result, Record{
+			Time:               t,
+			Consumption:        e.Consumption,
+			Cost:               e.Cost,
+			AverageSettingTemp: e.AverageSettingTemp,
+			AverageInsideTemp:  e.AverageInsideTemp,
+			AverageOutsideTemp: e.AverageOutsideTemp,
+		})
+	}
+
+	return result
+}
+
+// gaps reports whether any hour of the history has no data yet.
+func gaps(history pt.History) bool {
+	if len(history.HistoryEntries) == 0 {
+		return true
+	}
+	for _, e := range history.HistoryEntries {
+		if e.Consumption == pt.HistoryNoData {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncDevice fetches all days within the backfill window that have not
+// been completely stored yet. It returns the number of records stored.
+func (s *Syncer) SyncDevice(guid string) (int, error) {
+	client := *s.Client
+	client.SetDevice(guid)
+
+	today := startOfDay(s.Now())
+	stored := 0
+	for day := today.AddDate(0, 0, -s.Backfill); !day.After(today); day = day.AddDate(0, 0, 1) {
+		complete, err := s.Archive.Complete(guid, day)
+		if err != nil {
+			return stored, err
+		}
+		if complete {
+			continue
+		}
+
+		log.Debugf("Fetching history for %s on %s", guid, day.Format("2006-01-02"))
+		history, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], day)
+		if err != nil {
+			return stored, err
+		}
+		recs := records(day, history)
+		if err := s.Archive.Put(guid, recs); err != nil {
+			return stored, err
+		}
+		stored += len(recs)
+
+		// Only days that have ended can no longer receive new data. The
+		// cloud fills in the last hours of a day late, so days with gaps
+		// are fetched again until they are older than the grace period.
+		if day.Before(today) && (!gaps(history) || day.Before(today.AddDate(0, 0, -s.Grace))) {
+			if err := s.Archive.MarkComplete(guid, day); err != nil {
+				return stored, err
+			}
+		}
+	}
+
+	return stored, nil
+}
+
+// SyncError is returned by Sync when the history of some devices could
+// not be synced.
+type SyncError struct {
+	Errors map[string]error // Errors by device GUID
+}
+
+func (e *SyncError) Error() string {
+	guids := []string{}
+	for guid := range e.Errors {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+	failed := []string{}
+	for _, guid := range guids {
+		failed = append(failed, fmt.Sprintf("%s: %v", guid, e.Errors[guid]))
+	}
+	return "error: history sync failed for " + strings.Join(failed, ", ")
+}
+
+// Unwrap returns the errors of the devices.
+func (e *SyncError) Unwrap() []error {
+	errs := []error{}
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Sync fetches missing history for every device on the account. A device
+// that fails does not stop the others, the failures are returned
+// together as a SyncError.
+func (s *Syncer) Sync() (int, error) {
+	devices, err := s.Client.ListDevices()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	failed := &SyncError{Errors: map[string]error{}}
+	for _, guid := range devices {
+		n, err := s.SyncDevice(guid)
+		total += n
+		if err != nil {
+			failed.Errors[guid] = err
+			continue
+		}
+		log.Debugf("Stored %d history records for %s", n, guid)
+	}
+	if len(failed.Errors) > 0 {
+		return total, failed
+	}
+
+	return total, nil
+}
+
+// Run syncs periodically until the context is cancelled. Errors are
+// logged and retried on the next interval.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.Sync(); err != nil {
+			log.Errorf("History sync failed: %v", err)
+		} else {
+			log.Infof("History sync stored %d records", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package automation runs conditional automations for Panasonic Comfort
+// Cloud devices. Rules have a condition over the device status and the
+// time, eg `outside < 5 and power == "off" and time >= "06:00" and
+// time < "07:00"`, and set the state of the device when the condition
+// becomes true.
+package automation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is the time between two evaluations in Run.
+const DefaultInterval = time.Minute
+
+// Variables are the variables conditions can use with their meaning.
+var Variables = map[string]string{
+	"inside":   "inside temperature in °C",
+	"outside":  "outside temperature in °C",
+	"setpoint": "set temperature in °C",
+	"power":    `"on" or "off"`,
+	"mode":     `"auto", "dry", "cool", "heat" or "fan"`,
+	"fan":      `fan speed, "auto", "low", "lowMid", "mid", "highMid" or "high"`,
+	"online":   "true when the device is connected",
+	"error":    "true when the device reports an error",
+	"code":     `error code reported by the device, "" without error`,
+	"device":   "device name",
+	"time":     `local time as "15:04"`,
+	"hour":     "hour of the day, 0-23",
+	"minute":   "minute of the hour, 0-59",
+	"weekday":  `day of the week, "mon" to "sun"`,
+	"date":     `date as "2006-01-02"`,
+}
+
+// Env returns the variables of a device status at a time.
+func Env(d pt.Device, now time.Time) map[string]interface{} {
+	p := d.Parameters
+	power := "off"
+	if p.Operate == 1 {
+		power = "on"
+	}
+	code := ""
+	if p.ErrorStatusFlg {
+		code = p.ErrorCodeStr
+		if code == "" {
+			code = fmt.Sprint(p.ErrorCode)
+		}
+	}
+	return map[string]interface{}{
+		"inside":   p.InsideTemperature,
+		"outside":  p.OutsideTemperature,
+		"setpoint": p.TemperatureSet,
+		"power":    power,
+		"mode":     pt.ModesReverse[p.OperationMode],
+		"fan":      pt.FanSpeedsReverse[p.FanSpeed],
+		"online":   p.Online,
+		"error":    p.ErrorStatusFlg,
+		"code":     code,
+		"device":   d.DeviceName,
+		"time":     now.Format("15:04"),
+		"hour":     float64(now.Hour()),
+		"minute":   float64(now.Minute()),
+		"weekday":  strings.ToLower(now.Weekday().String()[:3]),
+		"date":     now.Format("2006-01-02"),
+	}
+}
+
+// Rule sets the state of devices when its condition becomes true.
+type Rule struct {
+	Name string `json:"name"`
+	// Devices are GUIDs, names, aliases or group names
+	Devices []string `json:"devices"`
+	// When is the condition, see Expr
+	When  string    `json:"when"`
+	State api.State `json:"state"`
+}
+
+// Config is the automation configuration.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// rule is a Rule with its condition compiled and devices resolved.
+type rule struct {
+	Rule
+	when       *Expr
+	devices    []string
+	parameters pt.DeviceControlParameters
+}
+
+// Result is the outcome of a rule for a device.
+type Result struct {
+	Rule       string
+	DeviceGUID string
+	DeviceName string
+	// Matched is true when the condition is true, Triggered when it
+	// became true and the state is set
+	Matched   bool
+	Triggered bool
+	Err       error
+}
+
+// Engine evaluates rules against the device status.
+type Engine struct {
+	Now func() time.Time
+	// DryRun evaluates the rules without changing devices
+	DryRun bool
+
+	client  *cloudcontrol.Client
+	rules   []*rule
+	devices []string
+	mu      sync.Mutex
+	matched map[string]bool // rule and device whose condition is true
+}
+
+// New creates an Engine from the config. Device names, aliases and
+// groups of the rules are resolved to GUIDs once.
+func New(client *cloudcontrol.Client, config Config) (*Engine, error) {
+	e := &Engine{Now: time.Now, client: client, matched: map[string]bool{}}
+	seen := map[string]bool{}
+	for i, r := range config.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled := &rule{Rule: r}
+		var err error
+		if compiled.when, err = Compile(r.When); err != nil {
+			return nil, fmt.Errorf("error: automation %q: %w", r.Name, err)
+		}
+		if compiled.parameters, err = r.State.Parameters(); err != nil {
+			return nil, fmt.Errorf("error: automation %q: %w", r.Name, err)
+		}
+		if len(r.Devices) == 0 {
+			return nil, fmt.Errorf("error: automation %q has no devices", r.Name)
+		}
+		for _, device := range r.Devices {
+			guids, err := client.ResolveDevices(device)
+			if err != nil {
+				return nil, fmt.Errorf("error: automation %q: %w", r.Name, err)
+			}
+			compiled.devices = append(compiled.devices, guids...)
+			for _, guid := range guids {
+				if !seen[guid] {
+					seen[guid] = true
+					e.devices = append(e.devices, guid)
+				}
+			}
+		}
+		e.rules = append(e.rules, compiled)
+	}
+
+	return e, nil
+}
+
+// Evaluate gets the status of all devices of the rules and sets the
+// state of a rule on a device when its condition becomes true. A
+// condition that stays true does not trigger again until it was false.
+func (e *Engine) Evaluate() []Result {
+	now := e.Now()
+	statuses := map[string]cloudcontrol.StatusResult{}
+	for _, s := range e.client.EachStatus(e.devices, cloudcontrol.DefaultParallelism) {
+		statuses[s.DeviceGUID] = s
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	results := []Result{}
+	for _, r := range e.rules {
+		for _, guid := range r.devices {
+			status := statuses[guid]
+			result := Result{Rule: r.Name, DeviceGUID: guid, DeviceName: status.Status.DeviceName, Err: status.Err}
+			if result.Err == nil {
+				result.Matched, result.Err = r.when.Eval(Env(status.Status, now))
+			}
+			if result.Err != nil {
+				results = append(results, result)
+				continue
+			}
+
+			key := r.Name + "/" + guid
+			result.Triggered = result.Matched && !e.matched[key]
+			if result.Triggered && !e.DryRun {
+				client := *e.client
+				client.SetDevice(guid)
+				if _, err := client.SetState(r.parameters); err != nil {
+					result.Err = err
+				}
+			}
+			// A failed action is tried again at the next evaluation
+			e.matched[key] = result.Matched && result.Err == nil
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// Run evaluates the rules every interval until the context is done and
+// logs the triggered rules and errors.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, r := range e.Evaluate() {
+			switch {
+			case r.Err != nil:
+				log.Errorf("%s: %s: %v", r.Rule, r.DeviceGUID, r.Err)
+			case r.Triggered && e.DryRun:
+				log.Infof("%s: would change %s", r.Rule, r.DeviceName)
+			case r.Triggered:
+				log.Infof("%s: changed %s", r.Rule, r.DeviceName)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package automation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/automation"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+func TestExpr(t *testing.T) {
+	d := cloudtest.Device(living, "Living", "CS-Z25XKEW")
+	d.Parameters.OutsideTemperature = 3
+	env := automation.Env(d, time.Date(2021, 1, 4, 6, 30, 0, 0, time.Local))
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`outside < 5 and power == "off" and time >= "06:00" and time < "07:00"`, true},
+		{`outside < 5 && power == "on"`, false},
+		{`outside >= 5 or mode == "heat"`, true},
+		{`not online`, false},
+		{`!(inside > setpoint - 2)`, false},
+		{`inside <= setpoint - 1`, true},
+		{`-outside * 2 == -6`, true},
+		{`(outside + inside) / 2 > 11`, true},
+		{`weekday == "mon" and hour == 6 and minute == 30`, true},
+		{`date == "2021-01-04"`, true},
+		{`error or code != ""`, false},
+		{`device == 'Living'`, true},
+		{`fan == "auto"`, true},
+	}
+	for _, tc := range tests {
+		e, err := automation.Compile(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := e.Eval(env)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{`outside <`, `(outside < 5`, `temperature < 5`, `outside < 5 5`, `"open`, `outside # 5`} {
+		if _, err := automation.Compile(expr); err == nil {
+			t.Errorf("Compile(%q) succeeded, want error", expr)
+		}
+	}
+	for _, expr := range []string{`outside`, `outside < "5"`, `power + 1 > 0`, `outside / 0 > 1`} {
+		e, err := automation.Compile(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := e.Eval(env); err == nil {
+			t.Errorf("Eval(%q) = %v, want error", expr, got)
+		}
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestEngine(t *testing.T) {
+	c := cloudtest.Default()
+	if err := c.Update(living, func(d *pt.Device) { d.Parameters.OutsideTemperature = 3 }); err != nil {
+		t.Fatal(err)
+	}
+	client := testclient.New(t, c)
+
+	temperature := 21.0
+	e, err := automation.New(client, automation.Config{Rules: []automation.Rule{{
+		Name:    "morning",
+		Devices: []string{"My House"},
+		When:    `outside < 5 and power == "off" and time >= "06:00" and time < "07:00"`,
+		State:   api.State{Power: ptr("on"), Mode: ptr("heat"), Temperature: &temperature},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 4, 5, 59, 0, 0, time.Local)
+	e.Now = func() time.Time { return now }
+
+	triggered := func() []string {
+		names := []string{}
+		for _, r := range e.Evaluate() {
+			if r.Err != nil {
+				t.Errorf("%s: %v", r.DeviceName, r.Err)
+			}
+			if r.Triggered {
+				names = append(names, r.DeviceName)
+			}
+		}
+		return names
+	}
+
+	// Dry runs do not change devices but remember matches
+	e.DryRun = true
+	now = now.Add(time.Minute)
+	if diff := cmp.Diff([]string{"Living"}, triggered()); diff != "" {
+		t.Errorf("dry run mismatch (-want +got):\n%s", diff)
+	}
+	if got := len(c.Commands()); got != 0 {
+		t.Errorf("dry run sent %d commands, want 0", got)
+	}
+	if diff := cmp.Diff([]string{}, triggered()); diff != "" {
+		t.Errorf("second dry run mismatch (-want +got):\n%s", diff)
+	}
+
+	// The bedroom is too warm outside, the living room triggers again
+	// after the condition was false
+	e.DryRun = false
+	now = now.Add(time.Hour)
+	if diff := cmp.Diff([]string{}, triggered()); diff != "" {
+		t.Errorf("after 07:00 mismatch (-want +got):\n%s", diff)
+	}
+	now = now.Add(23 * time.Hour)
+	if diff := cmp.Diff([]string{"Living"}, triggered()); diff != "" {
+		t.Errorf("next morning mismatch (-want +got):\n%s", diff)
+	}
+	commands := c.Commands()
+	if len(commands) != 1 || commands[0].DeviceGUID != living || *commands[0].Parameters.TemperatureSet != temperature {
+		t.Errorf("got commands %+v, want living room set to %v", commands, temperature)
+	}
+	// The device is on now, so the condition is false
+	now = now.Add(time.Minute)
+	if diff := cmp.Diff([]string{}, triggered()); diff != "" {
+		t.Errorf("after turning on mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+
+	on := api.State{Power: ptr("on")}
+	for _, r := range []automation.Rule{
+		{Devices: []string{"Living"}, When: `outside <`, State: on},
+		{Devices: []string{"Living"}, When: `outside < 5`},
+		{When: `outside < 5`, State: on},
+		{Devices: []string{"Attic"}, When: `outside < 5`, State: on},
+	} {
+		if _, err := automation.New(client, automation.Config{Rules: []automation.Rule{r}}); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", r)
+		}
+	}
+}
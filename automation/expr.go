@@ -0,0 +1,406 @@
+package automation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled condition. Conditions compare variables, numbers
+// and "strings" with < <= > >= == !=, combine them with and, or and not
+// (or &&, || and !) and may use + - * / on numbers and parentheses.
+type Expr struct {
+	source string
+	root   node
+}
+
+// node is a node of the syntax tree.
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literal struct{ value interface{} }
+
+type variable struct{ name string }
+
+type unary struct {
+	op      string
+	operand node
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+// token is a lexical token, kind is number, string, ident or op.
+type token struct {
+	kind  string
+	text  string
+	value interface{}
+}
+
+// operators are the operators, longest first.
+var operators = []string{"<=", ">=", "==", "!=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "(", ")"}
+
+func lex(src string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexRune(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{kind: "string", text: src[i : i+end+2], value: src[i+1 : i+end+1]})
+			i += end + 2
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[i:j])
+			}
+			tokens = append(tokens, token{kind: "number", text: src[i:j], value: v})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{kind: "ident", text: src[i:j]})
+			i = j
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: "op", text: op})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over the tokens.
+type parser struct {
+	tokens    []token
+	pos       int
+	variables map[string]string
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{}
+}
+
+// accept consumes the next token if it is one of the operators or
+// keywords and returns it.
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != "op" && t.kind != "ident" {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: "or", left: left, right: right}
+	}
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: "and", left: left, right: right}
+	}
+}
+
+func (p *parser) not() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "not", operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("<", "<=", ">", ">=", "==", "!="); ok {
+		right, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return &binary{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) sum() (node, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) product() (node, error) {
+	left, err := p.negation()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.negation()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) negation() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.negation()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "-", operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	p.pos++
+	switch {
+	case t.kind == "number" || t.kind == "string":
+		return &literal{value: t.value}, nil
+	case t.kind == "ident" && (t.text == "true" || t.text == "false"):
+		return &literal{value: t.text == "true"}, nil
+	case t.kind == "ident":
+		if _, found := p.variables[t.text]; !found {
+			return nil, fmt.Errorf("unknown variable %q", t.text)
+		}
+		return &variable{name: t.text}, nil
+	case t.kind == "op" && t.text == "(":
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing )")
+		}
+		return n, nil
+	case t.kind == "":
+		return nil, fmt.Errorf("unexpected end")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// Compile compiles a condition over the Variables.
+func Compile(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("error: condition %q: %w", src, err)
+	}
+	p := &parser{tokens: tokens, variables: Variables}
+	root, err := p.or()
+	if err == nil && p.pos < len(tokens) {
+		err = fmt.Errorf("unexpected %q", tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error: condition %q: %w", src, err)
+	}
+	return &Expr{source: src, root: root}, nil
+}
+
+// String returns the source of the condition.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Eval evaluates the condition, it must result in true or false.
+func (e *Expr) Eval(env map[string]interface{}) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, fmt.Errorf("error: condition %q: %w", e.source, err)
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("error: condition %q is %v, not true or false", e.source, v)
+	}
+	return b, nil
+}
+
+func (n *literal) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *variable) eval(env map[string]interface{}) (interface{}, error) {
+	v, found := env[n.name]
+	if !found {
+		return nil, fmt.Errorf("%s is not known", n.name)
+	}
+	return v, nil
+}
+
+func (n *unary) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case bool:
+		if n.op == "not" {
+			return !v, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -v, nil
+		}
+	}
+	return nil, fmt.Errorf("can't apply %s to %v", n.op, v)
+}
+
+func (n *binary) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// and and or short circuit
+	if b, ok := left.(bool); ok && (n.op == "and" && !b || n.op == "or" && b) {
+		return b, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := left.(type) {
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			break
+		}
+		switch n.op {
+		case "and", "or":
+			return r, nil
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			break
+		}
+		switch n.op {
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/":
+			if r == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return l / r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			break
+		}
+		switch n.op {
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+	}
+	return nil, fmt.Errorf("can't apply %s to %v and %v", n.op, left, right)
+}
@@ -0,0 +1,399 @@
+// Package bridge connects Panasonic Comfort Cloud devices to MQTT.
+// Device state is published to state topics, commands received on
+// command topics are sent to the cloud and Home Assistant discovery
+// configs are published so devices show up as climate entities.
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultPrefix          = "panasonic"
+	DefaultDiscoveryPrefix = "homeassistant"
+	DefaultInterval        = time.Minute
+)
+
+// Home Assistant modes, off turns the device off
+var (
+	HAModes    = []string{"off", "auto", "cool", "heat", "dry", "fan_only"}
+	FanModes   = []string{"auto", "low", "lowMid", "mid", "highMid", "high"}
+	SwingModes = []string{"auto", "up", "upMid", "mid", "downMid", "down"}
+)
+
+// Broker is the part of an MQTT client used by the bridge.
+type Broker interface {
+	Publish(topic string, retain bool, payload []byte) error
+	Subscribe(topic string, handler func(topic string, payload []byte)) error
+}
+
+// State is the payload published on the state topic of a device. It
+// holds the device parameters together with their Home Assistant names.
+type State struct {
+	pt.DeviceParameters
+	Mode  string `json:"mode"`
+	Fan   string `json:"fan"`
+	Swing string `json:"swing"`
+}
+
+// NewState converts device parameters to a State.
+func NewState(p pt.DeviceParameters) State {
+	state := State{
+		DeviceParameters: p,
+		Mode:             "off",
+		Fan:              pt.FanSpeedsReverse[p.FanSpeed],
+		Swing:            "auto",
+	}
+	if p.Operate == 1 {
+		state.Mode = pt.ModesReverse[p.OperationMode]
+		if state.Mode == "fan" {
+			state.Mode = "fan_only"
+		}
+	}
+	if p.FanAutoMode != pt.FanAutoMode["both"] && p.FanAutoMode != pt.FanAutoMode["ud"] {
+		state.Swing = pt.AirSwingUDReverse[p.AirSwingUD]
+	}
+
+	return state
+}
+
+// DiscoveryDevice describes the device in a discovery config.
+type DiscoveryDevice struct {
+	Identifiers   []string `json:"identifiers"`
+	Name          string   `json:"name"`
+	Manufacturer  string   `json:"manufacturer"`
+	Model         string   `json:"model,omitempty"`
+	SuggestedArea string   `json:"suggested_area,omitempty"`
+}
+
+// Discovery is a Home Assistant MQTT climate discovery config.
+type Discovery struct {
+	Name                       string          `json:"name"`
+	UniqueID                   string          `json:"unique_id"`
+	Device                     DiscoveryDevice `json:"device"`
+	AvailabilityTopic          string          `json:"availability_topic"`
+	PowerCommandTopic          string          `json:"power_command_topic"`
+	ModeCommandTopic           string          `json:"mode_command_topic"`
+	ModeStateTopic             string          `json:"mode_state_topic"`
+	ModeStateTemplate          string          `json:"mode_state_template"`
+	Modes                      []string        `json:"modes"`
+	TemperatureCommandTopic    string          `json:"temperature_command_topic"`
+	TemperatureStateTopic      string          `json:"temperature_state_topic"`
+	TemperatureStateTemplate   string          `json:"temperature_state_template"`
+	CurrentTemperatureTopic    string          `json:"current_temperature_topic"`
+	CurrentTemperatureTemplate string          `json:"current_temperature_template"`
+	FanModeCommandTopic        string          `json:"fan_mode_command_topic"`
+	FanModeStateTopic          string          `json:"fan_mode_state_topic"`
+	FanModeStateTemplate       string          `json:"fan_mode_state_template"`
+	FanModes                   []string        `json:"fan_modes"`
+	SwingModeCommandTopic      string          `json:"swing_mode_command_topic"`
+	SwingModeStateTopic        string          `json:"swing_mode_state_topic"`
+	SwingModeStateTemplate     string          `json:"swing_mode_state_template"`
+	SwingModes                 []string        `json:"swing_modes"`
+	MinTemp                    float64         `json:"min_temp"`
+	MaxTemp                    float64         `json:"max_temp"`
+	TempStep                   float64         `json:"temp_step"`
+	TemperatureUnit            string          `json:"temperature_unit"`
+}
+
+// nonAlphanumeric matches characters not allowed in topic ids.
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// ID returns the topic id of a device. Device GUIDs contain characters
+// like '+' that are wildcards in MQTT topics.
+func ID(deviceGUID string) string {
+	return nonAlphanumeric.ReplaceAllString(deviceGUID, "_")
+}
+
+// Bridge publishes device state to MQTT and forwards commands.
+type Bridge struct {
+	Client          *cloudcontrol.Client
+	Broker          Broker
+	Prefix          string
+	DiscoveryPrefix string
+	Interval        time.Duration
+	Parallel        int
+
+	mu      sync.Mutex
+	devices map[string]cloudcontrol.GroupDevice // by topic id
+}
+
+// New creates a Bridge with the default topics and interval.
+func New(client *cloudcontrol.Client, broker Broker) *Bridge {
+	return &Bridge{
+		Client:          client,
+		Broker:          broker,
+		Prefix:          DefaultPrefix,
+		DiscoveryPrefix: DefaultDiscoveryPrefix,
+		Interval:        DefaultInterval,
+		Parallel:        cloudcontrol.DefaultParallelism,
+		devices:         map[string]cloudcontrol.GroupDevice{},
+	}
+}
+
+// topic returns the topic of a device below the bridge prefix.
+func (b *Bridge) topic(id string, parts ...string) string {
+	return strings.Join(append([]string{b.Prefix, id}, parts...), "/")
+}
+
+// discovery builds the discovery config of a device.
+func (b *Bridge) discovery(d cloudcontrol.GroupDevice) Discovery {
+	id := ID(d.DeviceGUID)
+	name := d.DeviceName
+	if name == "" {
+		name = d.DeviceGUID
+	}
+	state := b.topic(id, "state")
+	return Discovery{
+		Name:     name,
+		UniqueID: "panasonic_" + id,
+		Device: DiscoveryDevice{
+			Identifiers:   []string{"panasonic_" + id},
+			Name:          name,
+			Manufacturer:  "Panasonic",
+			Model:         d.DeviceModuleNumber,
+			SuggestedArea: d.GroupName,
+		},
+		AvailabilityTopic:          b.topic(id, "availability"),
+		PowerCommandTopic:          b.topic(id, "power", "set"),
+		ModeCommandTopic:           b.topic(id, "mode", "set"),
+		ModeStateTopic:             state,
+		ModeStateTemplate:          "{{ value_json.mode }}",
+		Modes:                      HAModes,
+		TemperatureCommandTopic:    b.topic(id, "temperature", "set"),
+		TemperatureStateTopic:      state,
+		TemperatureStateTemplate:   "{{ value_json.temperatureSet }}",
+		CurrentTemperatureTopic:    state,
+		CurrentTemperatureTemplate: "{{ value_json.insideTemperature }}",
+		FanModeCommandTopic:        b.topic(id, "fan", "set"),
+		FanModeStateTopic:          state,
+		FanModeStateTemplate:       "{{ value_json.fan }}",
+		FanModes:                   FanModes,
+		SwingModeCommandTopic:      b.topic(id, "swing", "set"),
+		SwingModeStateTopic:        state,
+		SwingModeStateTemplate:     "{{ value_json.swing }}",
+		SwingModes:                 SwingModes,
+		MinTemp:                    16,
+		MaxTemp:                    30,
+		TempStep:                   0.5,
+		TemperatureUnit:            "C",
+	}
+}
+
+// publishJSON marshals v and publishes it to topic.
+func (b *Bridge) publishJSON(topic string, retain bool, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Broker.Publish(topic, retain, payload)
+}
+
+// Discover lists all devices and publishes their retained discovery
+// configs.
+func (b *Bridge) Discover() error {
+	devices, err := b.Client.ListGroupDevices()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	for _, d := range devices {
+		b.devices[ID(d.DeviceGUID)] = d
+	}
+	b.mu.Unlock()
+
+	for _, d := range devices {
+		topic := fmt.Sprintf("%s/climate/%s/config", b.DiscoveryPrefix, ID(d.DeviceGUID))
+		if err := b.publishJSON(topic, true, b.discovery(d)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// guids returns the GUIDs of all discovered devices.
+func (b *Bridge) guids() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	guids := []string{}
+	for _, d := range b.devices {
+		guids = append(guids, d.DeviceGUID)
+	}
+	sort.Strings(guids)
+	return guids
+}
+
+// Publish polls the given devices, or all discovered devices when
+// none are given, and publishes their state and availability.
+func (b *Bridge) Publish(devices ...string) error {
+	if len(devices) == 0 {
+		devices = b.guids()
+	}
+
+	var firstErr error
+	for _, r := range b.Client.EachStatus(devices, b.Parallel) {
+		id := ID(r.DeviceGUID)
+		availability := "online"
+		if r.Err != nil || !r.Status.Parameters.Online {
+			availability = "offline"
+		}
+		if err := b.Broker.Publish(b.topic(id, "availability"), true, []byte(availability)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if r.Err != nil {
+			if firstErr == nil {
+				firstErr = r.Err
+			}
+			continue
+		}
+		if err := b.publishJSON(b.topic(id, "state"), true, NewState(r.Status.Parameters)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Parameters translates a command and its payload into control
+// parameters.
+func Parameters(command, payload string) (pt.DeviceControlParameters, error) {
+	parameters := pt.DeviceControlParameters{}
+	value := strings.TrimSpace(payload)
+	on, off := 1, 0
+
+	switch command {
+	case "power":
+		switch strings.ToLower(value) {
+		case "on", "1", "true":
+			parameters.Operate = &on
+		case "off", "0", "false":
+			parameters.Operate = &off
+		default:
+			return parameters, fmt.Errorf("error: invalid power %q", value)
+		}
+	case "mode":
+		if value == "off" {
+			parameters.Operate = &off
+			break
+		}
+		if value == "fan_only" {
+			value = "fan"
+		}
+		mode, ok := pt.Modes[value]
+		if !ok {
+			return parameters, fmt.Errorf("error: invalid mode %q", value)
+		}
+		parameters.Operate = &on
+		parameters.OperationMode = &mode
+	case "temperature":
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return parameters, fmt.Errorf("error: invalid temperature %q", value)
+		}
+		parameters.TemperatureSet = &temperature
+	case "fan":
+		speed, ok := pt.FanSpeeds[value]
+		if !ok {
+			return parameters, fmt.Errorf("error: invalid fan speed %q", value)
+		}
+		parameters.FanSpeed = &speed
+	case "swing":
+		if value == "auto" {
+			both := pt.FanAutoMode["both"]
+			parameters.FanAutoMode = &both
+			break
+		}
+		position, ok := pt.AirSwingUD[value]
+		if !ok {
+			return parameters, fmt.Errorf("error: invalid swing mode %q", value)
+		}
+		disabled := pt.FanAutoMode["disabled"]
+		parameters.FanAutoMode = &disabled
+		parameters.AirSwingUD = &position
+	default:
+		return parameters, fmt.Errorf("error: unknown command %q", command)
+	}
+
+	return parameters, nil
+}
+
+// Command sends a command received for the device with the given
+// topic id and publishes the new state.
+func (b *Bridge) Command(id, command, payload string) error {
+	b.mu.Lock()
+	d, ok := b.devices[id]
+	b.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("error: unknown device %s", id)
+	}
+
+	parameters, err := Parameters(command, payload)
+	if err != nil {
+		return err
+	}
+	client := *b.Client
+	client.SetDevice(d.DeviceGUID)
+	if _, err := client.SetState(parameters); err != nil {
+		return err
+	}
+
+	return b.Publish(d.DeviceGUID)
+}
+
+// handle processes a message on a command topic.
+func (b *Bridge) handle(topic string, payload []byte) {
+	parts := strings.Split(strings.TrimPrefix(topic, b.Prefix+"/"), "/")
+	if len(parts) != 3 || parts[2] != "set" {
+		log.Debugf("Ignoring message on %s", topic)
+		return
+	}
+	log.Debugf("Command %s %s for %s", parts[1], payload, parts[0])
+	if err := b.Command(parts[0], parts[1], string(payload)); err != nil {
+		log.Errorf("Command on %s failed: %v", topic, err)
+	}
+}
+
+// Start publishes the discovery configs and subscribes to the
+// command topics.
+func (b *Bridge) Start() error {
+	if err := b.Discover(); err != nil {
+		return err
+	}
+	return b.Broker.Subscribe(b.Prefix+"/+/+/set", b.handle)
+}
+
+// Run starts the bridge and publishes device state at its interval
+// until the context is cancelled.
+func (b *Bridge) Run(ctx context.Context) error {
+	if err := b.Start(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+	for {
+		if err := b.Publish(); err != nil {
+			log.Errorf("Publishing state failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package bridge_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// broker is an in-memory MQTT broker keeping the last message per topic.
+type broker struct {
+	mu       sync.Mutex
+	messages map[string]string
+	handlers map[string]func(string, []byte)
+}
+
+func (b *broker) Publish(topic string, retain bool, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages[topic] = string(payload)
+	return nil
+}
+
+func (b *broker) Subscribe(topic string, handler func(string, []byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = handler
+	return nil
+}
+
+// send delivers a message to the handler subscribed to the
+// panasonic/+/+/set wildcard.
+func (b *broker) send(topic, payload string) {
+	b.mu.Lock()
+	handler := b.handlers["panasonic/+/+/set"]
+	b.mu.Unlock()
+	handler(topic, []byte(payload))
+}
+
+func (b *broker) message(topic string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.messages[topic]
+}
+
+func TestParameters(t *testing.T) {
+	on, off, heat, high, disabled, down := 1, 0, 3, 5, 1, 1
+	temperature := 21.5
+	tests := []struct {
+		command, payload string
+		want             pt.DeviceControlParameters
+	}{
+		{"power", "ON", pt.DeviceControlParameters{Operate: &on}},
+		{"power", "OFF", pt.DeviceControlParameters{Operate: &off}},
+		{"mode", "off", pt.DeviceControlParameters{Operate: &off}},
+		{"mode", "heat", pt.DeviceControlParameters{Operate: &on, OperationMode: &heat}},
+		{"temperature", "21.5", pt.DeviceControlParameters{TemperatureSet: &temperature}},
+		{"fan", "high", pt.DeviceControlParameters{FanSpeed: &high}},
+		{"swing", "down", pt.DeviceControlParameters{FanAutoMode: &disabled, AirSwingUD: &down}},
+	}
+	for _, test := range tests {
+		got, err := bridge.Parameters(test.command, test.payload)
+		if err != nil {
+			t.Errorf("TestParameters(%s %s) error: %v", test.command, test.payload, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("TestParameters(%s %s) mismatch (-want +got):\n%s", test.command, test.payload, diff)
+		}
+	}
+
+	if _, err := bridge.Parameters("mode", "turbo"); err == nil {
+		t.Errorf("TestParameters() want error for invalid mode")
+	}
+}
+
+func TestBridge(t *testing.T) {
+	var mu sync.Mutex
+	commands := []pt.Command{}
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"CS-Z25+123","deviceName":"Living"}]}]}`))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"deviceGuid":"CS-Z25+123","parameters":{"online":true,"operate":1,"operationMode":2,"fanSpeed":3,"fanAutoMode":0,"temperatureSet":22}}`))
+	})
+	handler.HandleFunc(pt.URLControl, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		command := pt.Command{}
+		_ = json.Unmarshal(body, &command)
+		mu.Lock()
+		commands = append(commands, command)
+		mu.Unlock()
+		_, _ = w.Write([]byte(pt.SuccessResponse))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	b := &broker{messages: map[string]string{}, handlers: map[string]func(string, []byte){}}
+	br := bridge.New(&client, b)
+	if err := br.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := br.Publish(); err != nil {
+		t.Fatal(err)
+	}
+
+	discovery := bridge.Discovery{}
+	if err := json.Unmarshal([]byte(b.message("homeassistant/climate/CS_Z25_123/config")), &discovery); err != nil {
+		t.Fatal(err)
+	}
+	if discovery.ModeCommandTopic != "panasonic/CS_Z25_123/mode/set" || discovery.Device.SuggestedArea != "My House" {
+		t.Errorf("TestBridge() unexpected discovery config %+v", discovery)
+	}
+
+	state := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(b.message("panasonic/CS_Z25_123/state")), &state); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"mode": "cool", "fan": "mid", "swing": "auto", "temperatureSet": 22.0}
+	for key, value := range want {
+		if diff := cmp.Diff(value, state[key]); diff != "" {
+			t.Errorf("TestBridge() state %s mismatch (-want +got):\n%s", key, diff)
+		}
+	}
+	if got := b.message("panasonic/CS_Z25_123/availability"); got != "online" {
+		t.Errorf("TestBridge() want availability online, got %s", got)
+	}
+
+	b.send("panasonic/CS_Z25_123/mode/set", "heat")
+	mu.Lock()
+	defer mu.Unlock()
+	if len(commands) != 1 {
+		t.Fatalf("TestBridge() want 1 command, got %d", len(commands))
+	}
+	if commands[0].DeviceGUID != "CS-Z25+123" || *commands[0].Parameters.OperationMode != pt.Modes["heat"] {
+		t.Errorf("TestBridge() unexpected command %+v", commands[0])
+	}
+	if !strings.Contains(b.message("panasonic/CS_Z25_123/state"), `"mode"`) {
+		t.Errorf("TestBridge() state not republished after command")
+	}
+}
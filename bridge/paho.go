@@ -0,0 +1,85 @@
+package bridge
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+)
+
+// timeout is the time to wait for the broker to acknowledge a request
+const timeout = 10 * time.Second
+
+// Paho is a Broker using the Eclipse Paho MQTT client.
+type Paho struct {
+	client mqtt.Client
+
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler
+}
+
+// Connect connects to the MQTT broker at url, for example
+// tcp://localhost:1883. Username and password may be empty.
+func Connect(url, clientID, username, password string) (*Paho, error) {
+	p := &Paho{subscriptions: map[string]mqtt.MessageHandler{}}
+	options := mqtt.NewClientOptions().
+		AddBroker(url).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetAutoReconnect(true).
+		// Commands publish state from the message handler
+		SetOrderMatters(false).
+		SetCleanSession(false).
+		// The broker may have dropped the session, so the command topics
+		// are subscribed again after reconnecting
+		SetOnConnectHandler(p.resubscribe)
+	p.client = mqtt.NewClient(options)
+	if err := wait(p.client.Connect()); err != nil {
+		return nil, fmt.Errorf("error: connecting to %s: %w", url, err)
+	}
+
+	return p, nil
+}
+
+// resubscribe subscribes to all topics subscribed to before.
+func (p *Paho) resubscribe(client mqtt.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for topic, handler := range p.subscriptions {
+		if err := wait(client.Subscribe(topic, 1, handler)); err != nil {
+			log.Errorf("Subscribing to %s after reconnecting failed: %v", topic, err)
+		}
+	}
+}
+
+// wait waits for a token to complete.
+func wait(token mqtt.Token) error {
+	if !token.WaitTimeout(timeout) {
+		return fmt.Errorf("error: timeout waiting for MQTT broker")
+	}
+	return token.Error()
+}
+
+// Publish publishes a message with QoS 1.
+func (p *Paho) Publish(topic string, retain bool, payload []byte) error {
+	return wait(p.client.Publish(topic, 1, retain, payload))
+}
+
+// Subscribe subscribes to topic with QoS 1.
+func (p *Paho) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	callback := func(_ mqtt.Client, m mqtt.Message) {
+		handler(m.Topic(), m.Payload())
+	}
+	p.mu.Lock()
+	p.subscriptions[topic] = callback
+	p.mu.Unlock()
+	return wait(p.client.Subscribe(topic, 1, callback))
+}
+
+// Close disconnects from the broker.
+func (p *Paho) Close() {
+	p.client.Disconnect(250)
+}
@@ -0,0 +1,144 @@
+package bridge_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+)
+
+// tcpBroker is a minimal MQTT broker forgetting the session of a client
+// when it reconnects. It reports the topics subscribed to on every
+// connection.
+type tcpBroker struct {
+	listener   net.Listener
+	subscribed chan string
+	conns      chan *brokerConn
+}
+
+// brokerConn is a connection to a client, writes from the test and the
+// broker are serialized.
+type brokerConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *brokerConn) write(p packets.ControlPacket) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return p.Write(c.Conn)
+}
+
+func newTCPBroker(t *testing.T) *tcpBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &tcpBroker{listener: listener, subscribed: make(chan string, 10), conns: make(chan *brokerConn, 10)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c := &brokerConn{Conn: conn}
+			b.conns <- c
+			go b.serve(c)
+		}
+	}()
+	return b
+}
+
+func (b *tcpBroker) serve(conn *brokerConn) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		var reply packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			reply = packets.NewControlPacket(packets.Connack)
+		case *packets.SubscribePacket:
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = []byte{1}
+			reply = suback
+			for _, topic := range p.Topics {
+				b.subscribed <- topic
+			}
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+		if reply != nil {
+			if err := conn.write(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// publish sends a message to the client.
+func (c *brokerConn) publish(topic, payload string) error {
+	p := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	p.TopicName = topic
+	p.Payload = []byte(payload)
+	return c.write(p)
+}
+
+func TestPahoResubscribe(t *testing.T) {
+	broker := newTCPBroker(t)
+	p, err := bridge.Connect("tcp://"+broker.listener.Addr().String(), "test", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	first := <-broker.conns
+
+	received := make(chan string, 1)
+	if err := p.Subscribe("panasonic/+/+/set", func(topic string, payload []byte) {
+		received <- topic + " " + string(payload)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-broker.subscribed; got != "panasonic/+/+/set" {
+		t.Fatalf("got subscription to %q, want panasonic/+/+/set", got)
+	}
+
+	// The broker drops the connection and the session
+	first.Close()
+	var second *brokerConn
+	select {
+	case second = <-broker.conns:
+	case <-time.After(10 * time.Second):
+		t.Fatal("client did not reconnect")
+	}
+	select {
+	case got := <-broker.subscribed:
+		if got != "panasonic/+/+/set" {
+			t.Fatalf("got subscription to %q after reconnecting, want panasonic/+/+/set", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("client did not subscribe again after reconnecting")
+	}
+
+	if err := second.publish("panasonic/living/power/set", "on"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if got != "panasonic/living/power/set on" {
+			t.Errorf("got message %q, want panasonic/living/power/set on", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("command after reconnecting was not delivered")
+	}
+}
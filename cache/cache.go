@@ -0,0 +1,153 @@
+// Package cache polls the status of all devices, so the REST API and the
+// metrics exporter are served without contacting the cloud for every
+// request.
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is the default poll interval of the Cache
+const DefaultInterval = time.Minute
+
+// Status is a cached device status.
+type Status struct {
+	pt.Device
+	Updated time.Time `json:"updated"`
+}
+
+// Cache polls the status of all devices and serves it from memory.
+type Cache struct {
+	Client   *cloudcontrol.Client
+	Interval time.Duration
+	Parallel int
+
+	mu       sync.RWMutex
+	devices  []cloudcontrol.GroupDevice
+	statuses map[string]Status
+	errors   int
+}
+
+// New creates a Cache with the default interval.
+func New(client *cloudcontrol.Client) *Cache {
+	return &Cache{
+		Client:   client,
+		Interval: DefaultInterval,
+		Parallel: cloudcontrol.DefaultParallelism,
+		statuses: map[string]Status{},
+	}
+}
+
+// Poll refreshes the device list and the status of all devices.
+func (c *Cache) Poll() error {
+	devices, err := c.Client.ListGroupDevices()
+	if err != nil {
+		c.failed()
+		return err
+	}
+	c.mu.Lock()
+	c.devices = devices
+	c.mu.Unlock()
+
+	guids := []string{}
+	for _, d := range devices {
+		guids = append(guids, d.DeviceGUID)
+	}
+	var firstErr error
+	for _, r := range c.Client.EachStatus(guids, c.Parallel) {
+		if r.Err != nil {
+			c.failed()
+			if firstErr == nil {
+				firstErr = r.Err
+			}
+			continue
+		}
+		c.store(r.DeviceGUID, r.Status)
+	}
+
+	return firstErr
+}
+
+// failed counts a failed request.
+func (c *Cache) failed() {
+	c.mu.Lock()
+	c.errors++
+	c.mu.Unlock()
+}
+
+// Errors returns the number of failed requests to the cloud.
+func (c *Cache) Errors() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.errors
+}
+
+// store adds a status to the cache.
+func (c *Cache) store(guid string, status pt.Device) {
+	if status.DeviceGUID == "" {
+		status.DeviceGUID = guid
+	}
+	c.mu.Lock()
+	c.statuses[guid] = Status{Device: status, Updated: time.Now()}
+	c.mu.Unlock()
+}
+
+// Refresh fetches the status of a single device and updates the cache.
+func (c *Cache) Refresh(guid string) (Status, error) {
+	client := *c.Client
+	client.SetDevice(guid)
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		c.failed()
+		return Status{}, err
+	}
+	c.store(guid, status)
+
+	return c.Status(guid)
+}
+
+// Devices returns the devices found by the last poll.
+func (c *Cache) Devices() []cloudcontrol.GroupDevice {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]cloudcontrol.GroupDevice{}, c.devices...)
+}
+
+// Cached returns the cached status of a device without fetching it.
+func (c *Cache) Cached(guid string) (Status, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status, ok := c.statuses[guid]
+	return status, ok
+}
+
+// Status returns the cached status of a device, fetching it when the
+// device was not polled yet.
+func (c *Cache) Status(guid string) (Status, error) {
+	if status, ok := c.Cached(guid); ok {
+		return status, nil
+	}
+	return c.Refresh(guid)
+}
+
+// Run polls at the cache interval until the context is cancelled.
+func (c *Cache) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Poll(); err != nil {
+			log.Errorf("Polling devices failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
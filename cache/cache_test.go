@@ -0,0 +1,49 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cache"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+func TestCache(t *testing.T) {
+	cloud := cloudtest.Default()
+	c := cache.New(testclient.New(t, cloud))
+	if err := c.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.Devices()); got != 2 {
+		t.Errorf("TestCache() got %d devices, want 2", got)
+	}
+
+	// Status is served from the cache until it is refreshed
+	if err := cloud.Update(living, func(d *pt.Device) { d.Parameters.TemperatureSet = 23 }); err != nil {
+		t.Fatal(err)
+	}
+	status, err := c.Status(living)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Parameters.TemperatureSet == 23 {
+		t.Error("TestCache() Status() fetched the device, want the cached status")
+	}
+	status, err = c.Refresh(living)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Parameters.TemperatureSet != 23 {
+		t.Errorf("TestCache() Refresh() got setpoint %v, want 23", status.Parameters.TemperatureSet)
+	}
+
+	if _, err := c.Refresh("unknown"); err == nil {
+		t.Error("TestCache() Refresh() of an unknown device succeeded, want error")
+	}
+	if got := c.Errors(); got != 1 {
+		t.Errorf("TestCache() got %d errors, want 1", got)
+	}
+}
@@ -5,6 +5,7 @@ package cloudcontrol
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -15,11 +16,46 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// DefaultTimeout is the timeout of a request to Panasonic Comfort Cloud
+// when no HTTPClient is set.
+const DefaultTimeout = 30 * time.Second
+
+// defaultHTTPClient is used by clients without an HTTPClient.
+var defaultHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
 // Client is a Panasonic Comfort Cloud client.
 type Client struct {
 	Utoken     string
 	DeviceGUID string
 	Server     string
+	Aliases    map[string]string
+	// HTTPClient sends the requests, a default client is used when nil
+	HTTPClient *http.Client
+}
+
+// ErrDeviceOffline is returned when a device is not
+// connected to Panasonic Comfort Cloud.
+var ErrDeviceOffline = errors.New("error: device is offline")
+
+// HTTPError is returned when Panasonic Comfort Cloud
+// responds with an HTTP error status.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP Error: %s", e.Status)
+}
+
+// IsAuthError reports whether err was caused by a rejected
+// session token or login.
+func IsAuthError(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden
+	}
+	return false
 }
 
 // intPtr is a helper function that returns a pointer to an int.
@@ -32,6 +68,14 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+// httpClient returns the HTTP client sending the requests.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
 // SetDevice sets the device GUID on the client.
 func (c *Client) SetDevice(deviceGUID string) {
 	c.DeviceGUID = deviceGUID
@@ -60,8 +104,7 @@ func (c *Client) doPostRequest(url string, postbody []byte) ([]byte, error) {
 	log.Debugf("POST request URL: %#v\n", req.URL)
 	log.Debugf("POST request body: %#v\n", string(postbody))
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +115,7 @@ func (c *Client) doPostRequest(url string, postbody []byte) ([]byte, error) {
 	log.Debugf("POST response body: %s", string(body))
 
 	if resp.StatusCode > 200 {
-		return body, fmt.Errorf("HTTP Error: %s", resp.Status)
+		return body, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	return body, nil
@@ -85,8 +128,7 @@ func (c *Client) doGetRequest(url string) ([]byte, error) {
 
 	log.Debugf("GET request URL: %#v\n", req.URL)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +139,7 @@ func (c *Client) doGetRequest(url string) ([]byte, error) {
 	log.Debugf("GET response body: %s", string(body))
 
 	if resp.StatusCode > 200 {
-		return body, fmt.Errorf("HTTP Error: %s", resp.Status)
+		return body, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	return body, nil
@@ -122,7 +164,7 @@ func (c *Client) ValidateSession(token string) ([]byte, error) {
 	c.Utoken = token
 	body, err := c.doGetRequest(pt.URLValidate1)
 	if err != nil {
-		return body, fmt.Errorf("error: %v %s", err, body)
+		return body, fmt.Errorf("error: %w %s", err, body)
 	}
 
 	return body, nil
@@ -138,13 +180,13 @@ func (c *Client) CreateSession(username string, password string) ([]byte, error)
 
 	body, err := c.doPostRequest(pt.URLLogin, postBody)
 	if err != nil {
-		return nil, fmt.Errorf("error: %v %s", err, body)
+		return nil, fmt.Errorf("error: %w %s", err, body)
 	}
 
 	session := pt.Session{}
 	err = json.Unmarshal([]byte(body), &session)
 	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+		return nil, fmt.Errorf("error: %v %s", err, body)
 	}
 
 	c.Utoken = session.Utoken
@@ -156,12 +198,12 @@ func (c *Client) CreateSession(username string, password string) ([]byte, error)
 func (c *Client) GetGroups() (pt.Groups, error) {
 	body, err := c.doGetRequest(pt.URLGroups)
 	if err != nil {
-		return pt.Groups{}, fmt.Errorf("error: %v %s", err, body)
+		return pt.Groups{}, fmt.Errorf("error: %w %s", err, body)
 	}
 	groups := pt.Groups{}
 	err = json.Unmarshal([]byte(body), &groups)
 	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+		return pt.Groups{}, fmt.Errorf("error: %v %s", err, body)
 	}
 
 	return groups, nil
@@ -187,13 +229,13 @@ func (c *Client) ListDevices() ([]string, error) {
 func (c *Client) GetDeviceStatus() (pt.Device, error) {
 	body, err := c.doGetRequest(pt.URLDeviceStatus + url.QueryEscape(c.DeviceGUID))
 	if err != nil {
-		return pt.Device{}, fmt.Errorf("error: %v %s", err, body)
+		return pt.Device{}, fmt.Errorf("error: %w %s", err, body)
 	}
 
 	device := pt.Device{}
 	err = json.Unmarshal([]byte(body), &device)
 	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+		return pt.Device{}, fmt.Errorf("error: %v %s", err, body)
 	}
 
 	return device, nil
@@ -201,27 +243,40 @@ func (c *Client) GetDeviceStatus() (pt.Device, error) {
 
 // GetDeviceHistory will fetch historical device data from Panasonic.
 func (c *Client) GetDeviceHistory(timeFrame int) (pt.History, error) {
+	return c.GetDeviceHistoryForDate(timeFrame, time.Now())
+}
+
+// GetDeviceHistoryForDate will fetch historical device data from Panasonic
+// for the time frame containing the given date.
+func (c *Client) GetDeviceHistoryForDate(timeFrame int, date time.Time) (pt.History, error) {
 	postBody, _ := json.Marshal(map[string]string{
 		"dataMode":   fmt.Sprint(timeFrame),
-		"date":       time.Now().Format("20060102"),
+		"date":       date.Format("20060102"),
 		"deviceGuid": c.DeviceGUID,
 		"osTimezone": "+01:00",
 	})
 
 	body, err := c.doPostRequest(pt.URLHistory, postBody)
 	if err != nil {
-		return pt.History{}, fmt.Errorf("error: %v %s", err, body)
+		return pt.History{}, fmt.Errorf("error: %w %s", err, body)
 	}
 
 	history := pt.History{}
 	err = json.Unmarshal([]byte(body), &history)
 	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+		return pt.History{}, fmt.Errorf("error: %v %s", err, body)
 	}
 
 	return history, nil
 }
 
+// HistoryHour returns the start of the hour of an entry in the day-mode
+// history of day. Entries are numbered by the wall clock hour, on the day
+// daylight saving time starts the skipped hour starts with the next hour.
+func HistoryHour(day time.Time, dataNumber int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), dataNumber, 0, 0, 0, day.Location())
+}
+
 // control sends commands to the Panasonic cloud to control a device.
 func (c *Client) control(command pt.Command) ([]byte, error) {
 	postBody, _ := json.Marshal(command)
@@ -230,7 +285,7 @@ func (c *Client) control(command pt.Command) ([]byte, error) {
 
 	body, err := c.doPostRequest(pt.URLControl, postBody)
 	if err != nil {
-		return nil, fmt.Errorf("error: %v %s", err, body)
+		return nil, fmt.Errorf("error: %w %s", err, body)
 	}
 	if string(body) != pt.SuccessResponse {
 		return body, fmt.Errorf("error body: %v %s", err, body)
@@ -286,3 +341,14 @@ func (c *Client) SetMode(mode int) ([]byte, error) {
 
 	return c.control(command)
 }
+
+// SetState will send all given control parameters to the device
+// in a single command.
+func (c *Client) SetState(parameters pt.DeviceControlParameters) ([]byte, error) {
+	command := pt.Command{
+		DeviceGUID: c.DeviceGUID,
+		Parameters: parameters,
+	}
+
+	return c.control(command)
+}
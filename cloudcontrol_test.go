@@ -9,6 +9,8 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	cloudcontrol "github.com/hacktobeer/go-panasonic"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
 	pt "github.com/hacktobeer/go-panasonic/types"
 )
 
@@ -164,3 +166,218 @@ func TestCreateSession(t *testing.T) {
 		t.Errorf("TestCreateSession() token mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestResolveDevices(t *testing.T) {
+	client.CreateSession("", "")
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{
+			input: "My House",
+			want:  []string{"CZ-CAPWFC1+B8B7F1B3E326"},
+		},
+		{
+			input: "Alaior-home",
+			want:  []string{"CZ-CAPWFC1+B8B7F1B3E326"},
+		},
+		{
+			input: "CZ-CAPWFC1+B8B7F1B3E326",
+			want:  []string{"CZ-CAPWFC1+B8B7F1B3E326"},
+		},
+	}
+	for _, c := range cases {
+		got, err := client.ResolveDevices(c.input)
+		if err != nil {
+			t.Errorf("TestResolveDevices(%s) returned an error: %v", c.input, err)
+		}
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("TestResolveDevices(%s) mismatch (-want +got):\n%s", c.input, diff)
+		}
+	}
+
+	if _, err := client.ResolveDevices("Unknown"); err == nil {
+		t.Error("TestResolveDevices(Unknown) did not return an error")
+	}
+}
+
+func TestEach(t *testing.T) {
+	client.CreateSession("", "")
+	devices := []string{"device1", "device2", "device3"}
+	results := client.Each(devices, 2, (*cloudcontrol.Client).TurnOff)
+
+	got := []string{}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("TestEach() returned an error for %s: %v", r.DeviceGUID, r.Err)
+		}
+		got = append(got, r.DeviceGUID)
+	}
+	if diff := cmp.Diff(devices, got); diff != "" {
+		t.Errorf("TestEach() mismatch (-want +got):\n%s", diff)
+	}
+	if client.DeviceGUID != "" {
+		t.Errorf("TestEach() changed the client device to %s", client.DeviceGUID)
+	}
+}
+
+func TestFindDevice(t *testing.T) {
+	client.CreateSession("", "")
+	client.SetAliases(map[string]string{"Living": "Alaior-home"})
+	defer client.SetAliases(nil)
+
+	for _, input := range []string{"CZ-CAPWFC1+B8B7F1B3E326", "alaior-home", "S-125PU2E5B", "alaior", "living"} {
+		device, err := client.FindDevice(input)
+		if err != nil {
+			t.Errorf("TestFindDevice(%s) returned an error: %v", input, err)
+		}
+		want := "CZ-CAPWFC1+B8B7F1B3E326"
+		got := device.DeviceGUID
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("TestFindDevice(%s) mismatch (-want +got):\n%s", input, diff)
+		}
+	}
+
+	if _, err := client.FindDevice("bedroom"); err == nil {
+		t.Error("TestFindDevice(bedroom) did not return an error")
+	}
+}
+
+func TestFindDeviceAmbiguous(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupName":"My House","deviceList":[{"deviceGuid":"guid1","deviceName":"Bedroom 1"},{"deviceGuid":"guid2","deviceName":"Bedroom 2"}]}]}`))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	_, err := client.FindDevice("bedroom")
+	ambiguous, ok := err.(*cloudcontrol.AmbiguousDeviceError)
+	if !ok {
+		t.Fatalf("TestFindDeviceAmbiguous() error type mismatch, got %v", err)
+	}
+	if diff := cmp.Diff(2, len(ambiguous.Candidates)); diff != "" {
+		t.Errorf("TestFindDeviceAmbiguous() candidates mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestListGroupDevices(t *testing.T) {
+	client.CreateSession("", "")
+	devices, err := client.ListGroupDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("TestListGroupDevices() mismatch Devices, want 1, got %d", len(devices))
+	}
+
+	want := []interface{}{"My House", "Alaior-home", "S-125PU2E5B", 19.5}
+	got := []interface{}{devices[0].GroupName, devices[0].DeviceName, devices[0].DeviceModuleNumber, devices[0].Parameters.TemperatureSet}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TestListGroupDevices() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Temperature ranges use the keys of the cloud
+	wantRanges := []int{17, 27, 18, 30, 18, 30, 16, 30}
+	d := devices[0]
+	gotRanges := []int{d.AutoTempMin, d.AutoTempMax, d.DryTempMin, d.DryTempMax, d.CoolTempMin, d.CoolTempMax, d.HeatTempMin, d.HeatTempMax}
+	if diff := cmp.Diff(wantRanges, gotRanges); diff != "" {
+		t.Errorf("TestListGroupDevices() ranges mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetState(t *testing.T) {
+	client.CreateSession("", "")
+	temperature := 0.0
+	body, err := client.SetState(pt.DeviceControlParameters{TemperatureSet: &temperature})
+	if err != nil {
+		t.Errorf("TestSetState() returned an error: %v", err)
+	}
+	want := string(pt.SuccessResponse)
+	got := string(body)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TestSetState() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	_, err := client.GetGroups()
+	if !cloudcontrol.IsAuthError(err) {
+		t.Errorf("TestIsAuthError() want auth error, got %v", err)
+	}
+	if cloudcontrol.IsAuthError(fmt.Errorf("error")) {
+		t.Error("TestIsAuthError() want no auth error for plain error")
+	}
+}
+
+func TestMalformedBody(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>maintenance</html>"))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	client.SetDevice("CS-Z25XKEW+4321")
+	if _, err := client.CreateSession("username", "password"); err == nil {
+		t.Error("TestMalformedBody() CreateSession want error, got nil")
+	}
+	if _, err := client.GetGroups(); err == nil {
+		t.Error("TestMalformedBody() GetGroups want error, got nil")
+	}
+	if _, err := client.GetDeviceStatus(); err == nil {
+		t.Error("TestMalformedBody() GetDeviceStatus want error, got nil")
+	}
+	if _, err := client.GetDeviceHistory(pt.HistoryDataMode["day"]); err == nil {
+		t.Error("TestMalformedBody() GetDeviceHistory want error, got nil")
+	}
+}
+
+func TestGetDeviceStatus(t *testing.T) {
+	cloud := cloudtest.Default()
+	server := testclient.NewServer(cloud)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	client.Utoken = cloud.Token(cloudtest.DefaultUser)
+	client.SetDevice("CS-Z25XKEW+4321")
+	if _, err := client.SetTemperature(22.5); err != nil {
+		t.Fatalf("TestGetDeviceStatus() SetTemperature returned an error: %v", err)
+	}
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		t.Fatalf("TestGetDeviceStatus() returned an error: %v", err)
+	}
+	want, _ := cloud.Status("CS-Z25XKEW+4321")
+	if diff := cmp.Diff(want.Parameters, status.Parameters); diff != "" {
+		t.Errorf("TestGetDeviceStatus() mismatch (-want +got):\n%s", diff)
+	}
+	if status.Parameters.TemperatureSet != 22.5 {
+		t.Errorf("TestGetDeviceStatus() temperatureSet = %v, want 22.5", status.Parameters.TemperatureSet)
+	}
+}
+
+func TestValidateSession(t *testing.T) {
+	cloud := cloudtest.Default()
+	server := testclient.NewServer(cloud)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	if _, err := client.ValidateSession(cloud.Token(cloudtest.DefaultUser)); err != nil {
+		t.Errorf("TestValidateSession() returned an error: %v", err)
+	}
+	cloud.ExpireTokens()
+	if _, err := client.ValidateSession(client.Utoken); !cloudcontrol.IsAuthError(err) {
+		t.Errorf("TestValidateSession() want auth error for expired token, got %v", err)
+	}
+}
@@ -0,0 +1,648 @@
+// Package cloudtest provides a fake Panasonic Comfort Cloud for tests.
+// The fake keeps accounts, session tokens, groups and devices in memory,
+// applies control commands to the device state and generates history.
+// Token expiry, rate limits and faults can be used to test error
+// handling.
+package cloudtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// Defaults used by New
+const (
+	DefaultTokenTTL   = 24 * time.Hour
+	DefaultRateWindow = time.Minute
+)
+
+// Credentials of the account created by Default
+const (
+	DefaultUser     = "demo"
+	DefaultPassword = "demo"
+)
+
+// Codes in the body of failed requests
+const (
+	CodeTokenExpired   = 4100
+	CodeLoginFailed    = 4101
+	CodeDeviceNotFound = 4300
+	CodeRateLimited    = 4900
+)
+
+// Fault makes matching requests fail.
+type Fault struct {
+	// Path is the prefix of the request paths to fail, all requests
+	// when empty
+	Path string
+	// Status is the HTTP status to respond with, the request is handled
+	// normally when it is 0 and Drop is false
+	Status int
+	Body   string
+	// Delay is waited before responding
+	Delay time.Duration
+	// Drop closes the connection without a response, or responds with
+	// a 502 when the connection cannot be taken over
+	Drop bool
+	// Count is the number of requests to fail, all requests when 0
+	Count int
+}
+
+// Model simulates the devices of a Cloud.
+type Model interface {
+	// Update brings the parameters of a device up to date with the
+	// time now.
+	Update(d *pt.Device, now time.Time)
+	// History returns the consumption and average temperatures of a
+	// device between from and to, ok is false when there is no data.
+	History(deviceGUID string, from, to time.Time) (entry pt.HistoryEntry, ok bool)
+}
+
+type token struct {
+	loginID string
+	expires time.Time
+}
+
+type group struct {
+	id      int
+	name    string
+	devices []string
+}
+
+// Cloud is a fake Panasonic Comfort Cloud, it implements http.Handler.
+type Cloud struct {
+	TokenTTL time.Duration
+	// RateLimit is the number of requests per token within RateWindow,
+	// unlimited when 0
+	RateLimit  int
+	RateWindow time.Duration
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+	// Model, when set, updates the device state before every request
+	// and provides the history. History is generated from the current
+	// state otherwise.
+	Model Model
+
+	mu       sync.Mutex
+	accounts map[string]string
+	tokens   map[string]token
+	groups   []*group
+	devices  map[string]*pt.Device
+	commands []pt.Command
+	faults   []*Fault
+	requests map[string][]time.Time
+	issued   int
+}
+
+// New creates an empty Cloud.
+func New() *Cloud {
+	return &Cloud{
+		TokenTTL:   DefaultTokenTTL,
+		RateWindow: DefaultRateWindow,
+		accounts:   map[string]string{},
+		tokens:     map[string]token{},
+		devices:    map[string]*pt.Device{},
+		requests:   map[string][]time.Time{},
+	}
+}
+
+// Default creates a Cloud with the DefaultUser account and a group
+// with two devices.
+func Default() *Cloud {
+	c := New()
+	c.AddAccount(DefaultUser, DefaultPassword)
+	c.AddDevice("My House", Device("CS-Z25XKEW+4321", "Living", "CS-Z25XKEW"))
+	bedroom := Device("CS-TZ20WKEW+8765", "Bedroom", "CS-TZ20WKEW")
+	bedroom.HeatMode = false
+	bedroom.Parameters.OperationMode = pt.Modes["cool"]
+	bedroom.Parameters.TemperatureSet = 24
+	c.AddDevice("My House", bedroom)
+	return c
+}
+
+// Device returns an online device that is switched off, with all
+// modes and the usual temperature ranges.
+func Device(guid, name, model string) pt.Device {
+	return pt.Device{
+		DeviceGUID:         guid,
+		DeviceName:         name,
+		DeviceModuleNumber: model,
+		DeviceType:         "3",
+		AutoMode:           true,
+		CoolMode:           true,
+		DryMode:            true,
+		FanMode:            true,
+		HeatMode:           true,
+		AirSwingLR:         true,
+		AutoTempMin:        17,
+		AutoTempMax:        27,
+		CoolTempMin:        18,
+		CoolTempMax:        30,
+		DryTempMin:         18,
+		DryTempMax:         30,
+		HeatTempMin:        16,
+		HeatTempMax:        30,
+		FanSpeedMode:       5,
+		Parameters: pt.DeviceParameters{
+			Online:             true,
+			OperationMode:      pt.Modes["heat"],
+			TemperatureSet:     21,
+			FanAutoMode:        pt.FanAutoMode["disabled"],
+			InsideTemperature:  20,
+			OutsideTemperature: 10,
+		},
+	}
+}
+
+func (c *Cloud) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// simulate updates a device with the model, c.mu must be held.
+func (c *Cloud) simulate(d *pt.Device) {
+	if c.Model != nil {
+		c.Model.Update(d, c.now())
+	}
+}
+
+// AddAccount adds an account that can log in.
+func (c *Cloud) AddAccount(loginID, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accounts[loginID] = password
+}
+
+// AddDevice adds a device to a group, the group is created when
+// it does not exist.
+func (c *Cloud) AddDevice(groupName string, d pt.Device) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var g *group
+	for _, existing := range c.groups {
+		if existing.name == groupName {
+			g = existing
+		}
+	}
+	if g == nil {
+		g = &group{id: len(c.groups) + 1, name: groupName}
+		c.groups = append(c.groups, g)
+	}
+	g.devices = append(g.devices, d.DeviceGUID)
+	c.devices[d.DeviceGUID] = &d
+}
+
+// Status returns the current state of a device.
+func (c *Cloud) Status(guid string) (pt.Device, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, found := c.devices[guid]
+	if !found {
+		return pt.Device{}, false
+	}
+	c.simulate(d)
+	return *d, true
+}
+
+// Update changes the state of a device, eg to take it offline or
+// change its inside temperature.
+func (c *Cloud) Update(guid string, update func(d *pt.Device)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, found := c.devices[guid]
+	if !found {
+		return fmt.Errorf("error: device %q not found", guid)
+	}
+	c.simulate(d)
+	update(d)
+	return nil
+}
+
+// Commands returns the control commands applied so far.
+func (c *Cloud) Commands() []pt.Command {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]pt.Command{}, c.commands...)
+}
+
+// Token returns a new session token of an account without logging in.
+func (c *Cloud) Token(loginID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.issue(loginID)
+}
+
+// issue creates a session token, c.mu must be held.
+func (c *Cloud) issue(loginID string) string {
+	c.issued++
+	t := fmt.Sprintf("token-%d", c.issued)
+	c.tokens[t] = token{loginID: loginID, expires: c.now().Add(c.TokenTTL)}
+	return t
+}
+
+// ExpireTokens invalidates all session tokens.
+func (c *Cloud) ExpireTokens() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = map[string]token{}
+}
+
+// AddFault makes requests fail, faults are checked in the order they
+// were added.
+func (c *Cloud) AddFault(f Fault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = append(c.faults, &f)
+}
+
+// ClearFaults removes all faults.
+func (c *Cloud) ClearFaults() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = nil
+}
+
+// fault returns the fault for a request, if any.
+func (c *Cloud) fault(path string) *Fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, f := range c.faults {
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				c.faults = append(c.faults[:i], c.faults[i+1:]...)
+			}
+		}
+		copied := *f
+		return &copied
+	}
+	return nil
+}
+
+// reply writes a JSON response.
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	switch b := body.(type) {
+	case string:
+		_, _ = w.Write([]byte(b))
+	default:
+		_ = json.NewEncoder(w).Encode(b)
+	}
+}
+
+// fail writes an error response.
+func fail(w http.ResponseWriter, status, code int, message string) {
+	reply(w, status, map[string]interface{}{"code": code, "message": message})
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Cloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f := c.fault(r.URL.Path); f != nil {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+		if f.Drop {
+			// Writers that cannot drop the connection, such as a
+			// ResponseRecorder, get a bad gateway instead
+			hj, ok := w.(http.Hijacker)
+			if !ok {
+				fail(w, http.StatusBadGateway, 0, "Connection dropped")
+				return
+			}
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+		if f.Status != 0 {
+			reply(w, f.Status, f.Body)
+			return
+		}
+	}
+
+	if r.Method == http.MethodPost && r.URL.Path == pt.URLLogin {
+		c.login(w, r)
+		return
+	}
+
+	c.mu.Lock()
+	t, found := c.tokens[r.Header.Get("X-User-Authorization")]
+	if !found || !c.now().Before(t.expires) {
+		c.mu.Unlock()
+		fail(w, http.StatusUnauthorized, CodeTokenExpired, "Token expires")
+		return
+	}
+	if !c.allow(r.Header.Get("X-User-Authorization")) {
+		c.mu.Unlock()
+		fail(w, http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+		return
+	}
+	c.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == pt.URLValidate1:
+		reply(w, http.StatusOK, pt.SuccessResponse)
+	case r.Method == http.MethodGet && r.URL.Path == pt.URLGroups:
+		c.listGroups(w)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, pt.URLDeviceStatus):
+		c.status(w, strings.TrimPrefix(r.URL.Path, pt.URLDeviceStatus))
+	case r.Method == http.MethodPost && r.URL.Path == pt.URLControl:
+		c.control(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == pt.URLHistory:
+		c.history(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// allow records a request of a token and reports whether it is within
+// the rate limit, c.mu must be held.
+func (c *Cloud) allow(t string) bool {
+	if c.RateLimit <= 0 {
+		return true
+	}
+	now := c.now()
+	recent := []time.Time{}
+	for _, at := range c.requests[t] {
+		if now.Sub(at) < c.RateWindow {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= c.RateLimit {
+		c.requests[t] = recent
+		return false
+	}
+	c.requests[t] = append(recent, now)
+	return true
+}
+
+func (c *Cloud) login(w http.ResponseWriter, r *http.Request) {
+	credentials := struct {
+		LoginID  string `json:"loginId"`
+		Password string `json:"password"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		fail(w, http.StatusBadRequest, CodeLoginFailed, "Invalid request")
+		return
+	}
+
+	c.mu.Lock()
+	password, found := c.accounts[credentials.LoginID]
+	if !found || password != credentials.Password {
+		c.mu.Unlock()
+		fail(w, http.StatusUnauthorized, CodeLoginFailed, "Login failed")
+		return
+	}
+	t := c.issue(credentials.LoginID)
+	c.mu.Unlock()
+
+	reply(w, http.StatusOK, pt.Session{Utoken: t})
+}
+
+func (c *Cloud) listGroups(w http.ResponseWriter) {
+	c.mu.Lock()
+	groups := pt.Groups{GroupCount: len(c.groups), Groups: []pt.Group{}}
+	for _, g := range c.groups {
+		group := pt.Group{GroupID: g.id, GroupName: g.name, Devices: []pt.Device{}}
+		for _, guid := range g.devices {
+			c.simulate(c.devices[guid])
+			group.Devices = append(group.Devices, *c.devices[guid])
+		}
+		groups.Groups = append(groups.Groups, group)
+	}
+	c.mu.Unlock()
+
+	reply(w, http.StatusOK, groups)
+}
+
+func (c *Cloud) status(w http.ResponseWriter, guid string) {
+	d, found := c.Status(guid)
+	if !found {
+		fail(w, http.StatusNotFound, CodeDeviceNotFound, "Device not found")
+		return
+	}
+	d.TimeStamp = int(c.now().Unix())
+	reply(w, http.StatusOK, d)
+}
+
+// modeAllowed reports whether a device supports a mode.
+func modeAllowed(d *pt.Device, mode int) bool {
+	switch mode {
+	case pt.Modes["auto"]:
+		return d.AutoMode
+	case pt.Modes["dry"]:
+		return d.DryMode
+	case pt.Modes["cool"]:
+		return d.CoolMode
+	case pt.Modes["heat"]:
+		return d.HeatMode
+	case pt.Modes["fan"]:
+		return d.FanMode
+	}
+	return false
+}
+
+// temperatureRange returns the setpoint range of a device in a mode,
+// zero when the device reports no range.
+func temperatureRange(d *pt.Device, mode int) (float64, float64) {
+	switch mode {
+	case pt.Modes["auto"]:
+		return float64(d.AutoTempMin), float64(d.AutoTempMax)
+	case pt.Modes["dry"]:
+		return float64(d.DryTempMin), float64(d.DryTempMax)
+	case pt.Modes["cool"]:
+		return float64(d.CoolTempMin), float64(d.CoolTempMax)
+	case pt.Modes["heat"]:
+		return float64(d.HeatTempMin), float64(d.HeatTempMax)
+	}
+	return 0, 0
+}
+
+// validate checks control parameters against the device capabilities.
+func validate(d *pt.Device, p pt.DeviceControlParameters) error {
+	mode := d.Parameters.OperationMode
+	if p.OperationMode != nil {
+		mode = *p.OperationMode
+		if !modeAllowed(d, mode) {
+			return fmt.Errorf("mode %d not supported", mode)
+		}
+	}
+	if p.TemperatureSet != nil {
+		t := *p.TemperatureSet
+		min, max := temperatureRange(d, mode)
+		if max > 0 && (t < min || t > max) {
+			return fmt.Errorf("temperature %v outside %v-%v", t, min, max)
+		}
+		if t*2 != math.Round(t*2) {
+			return fmt.Errorf("temperature %v not a multiple of 0.5", t)
+		}
+	}
+	if p.FanSpeed != nil && (*p.FanSpeed < 0 || *p.FanSpeed > 5) {
+		return fmt.Errorf("fan speed %d not supported", *p.FanSpeed)
+	}
+	if p.Operate != nil && *p.Operate != 0 && *p.Operate != 1 {
+		return fmt.Errorf("operate %d not supported", *p.Operate)
+	}
+	return nil
+}
+
+func (c *Cloud) control(w http.ResponseWriter, r *http.Request) {
+	command := pt.Command{}
+	if err := json.NewDecoder(r.Body).Decode(&command); err != nil {
+		reply(w, http.StatusBadRequest, pt.FailureResponse)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, found := c.devices[command.DeviceGUID]
+	if !found {
+		fail(w, http.StatusNotFound, CodeDeviceNotFound, "Device not found")
+		return
+	}
+	c.simulate(d)
+	if !d.Parameters.Online || validate(d, command.Parameters) != nil {
+		reply(w, http.StatusOK, pt.FailureResponse)
+		return
+	}
+
+	// Control parameters have the JSON names of the device parameters,
+	// so the set fields can be applied by decoding them over the state.
+	b, _ := json.Marshal(command.Parameters)
+	_ = json.Unmarshal(b, &d.Parameters)
+	c.commands = append(c.commands, command)
+
+	reply(w, http.StatusOK, pt.SuccessResponse)
+}
+
+// hourly returns the generated consumption of a device in the hour
+// starting at t, between 0 and 0.5 kWh.
+func hourly(guid string, t time.Time) float64 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", guid, t.Unix())
+	return float64(h.Sum32()%6) / 10
+}
+
+// slots returns the boundaries of the history entries of a data mode
+// for a date, entry i covers slots[i] up to slots[i+1].
+func slots(mode int, date time.Time) []time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	starts := []time.Time{}
+	switch mode {
+	case pt.HistoryDataMode["day"]:
+		for i := 0; i <= 24; i++ {
+			starts = append(starts, day.Add(time.Duration(i)*time.Hour))
+		}
+	case pt.HistoryDataMode["week"]:
+		monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		for i := 0; i <= 7; i++ {
+			starts = append(starts, monday.AddDate(0, 0, i))
+		}
+	case pt.HistoryDataMode["month"]:
+		first := day.AddDate(0, 0, 1-day.Day())
+		for t := first; !t.After(first.AddDate(0, 1, 0)); t = t.AddDate(0, 0, 1) {
+			starts = append(starts, t)
+		}
+	case pt.HistoryDataMode["year"]:
+		first := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, date.Location())
+		for i := 0; i <= 12; i++ {
+			starts = append(starts, first.AddDate(0, i, 0))
+		}
+	}
+	return starts
+}
+
+// entry returns the history of a device between from and to, ok is
+// false when from is not before to or the model has no data.
+func (c *Cloud) entry(d pt.Device, from, to time.Time) (pt.HistoryEntry, bool) {
+	if !from.Before(to) {
+		return pt.HistoryEntry{}, false
+	}
+	if c.Model != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.Model.History(d.DeviceGUID, from, to)
+	}
+
+	entry := pt.HistoryEntry{
+		AverageSettingTemp: d.Parameters.TemperatureSet,
+		AverageInsideTemp:  d.Parameters.InsideTemperature,
+		AverageOutsideTemp: d.Parameters.OutsideTemperature,
+	}
+	for t := from; t.Before(to); t = t.Add(time.Hour) {
+		entry.Consumption += hourly(d.DeviceGUID, t)
+	}
+	return entry, true
+}
+
+func (c *Cloud) history(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		DataMode   string `json:"dataMode"`
+		Date       string `json:"date"`
+		DeviceGUID string `json:"deviceGuid"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		reply(w, http.StatusBadRequest, pt.FailureResponse)
+		return
+	}
+	mode, err := strconv.Atoi(request.DataMode)
+	if err != nil {
+		reply(w, http.StatusBadRequest, pt.FailureResponse)
+		return
+	}
+	date, err := time.ParseInLocation("20060102", request.Date, time.Local)
+	if err != nil {
+		reply(w, http.StatusBadRequest, pt.FailureResponse)
+		return
+	}
+	d, found := c.Status(request.DeviceGUID)
+	if !found {
+		fail(w, http.StatusNotFound, CodeDeviceNotFound, "Device not found")
+		return
+	}
+
+	now := c.now()
+	history := pt.History{HistoryEntries: []pt.HistoryEntry{}}
+	starts := slots(mode, date)
+	for i := 0; i+1 < len(starts); i++ {
+		end := starts[i+1]
+		if end.After(now) {
+			end = now
+		}
+		entry, ok := c.entry(d, starts[i], end)
+		if !ok {
+			entry = pt.HistoryEntry{
+				Consumption:        pt.HistoryNoData,
+				Cost:               pt.HistoryNoData,
+				AverageSettingTemp: pt.HistoryNoData,
+				AverageInsideTemp:  pt.HistoryNoData,
+				AverageOutsideTemp: pt.HistoryNoData,
+			}
+		} else {
+			entry.Consumption = math.Round(entry.Consumption*10) / 10
+			history.EnergyConsumption += entry.Consumption
+		}
+		entry.DataNumber = i
+		history.HistoryEntries = append(history.HistoryEntries, entry)
+	}
+	history.EnergyConsumption = math.Round(history.EnergyConsumption*10) / 10
+
+	reply(w, http.StatusOK, history)
+}
@@ -0,0 +1,213 @@
+package cloudtest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+func TestLogin(t *testing.T) {
+	server := testclient.NewServer(cloudtest.Default())
+	defer server.Close()
+	client := cloudcontrol.NewClient(server.URL)
+
+	if _, err := client.CreateSession(cloudtest.DefaultUser, "wrong"); !cloudcontrol.IsAuthError(err) {
+		t.Errorf("CreateSession with wrong password: got %v, want auth error", err)
+	}
+	if _, err := client.GetGroups(); !cloudcontrol.IsAuthError(err) {
+		t.Errorf("GetGroups without session: got %v, want auth error", err)
+	}
+	if _, err := client.CreateSession(cloudtest.DefaultUser, cloudtest.DefaultPassword); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{living, "CS-TZ20WKEW+8765"}, devices); diff != "" {
+		t.Errorf("ListDevices mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.Local)
+	c := cloudtest.Default()
+	c.Now = func() time.Time { return now }
+	c.TokenTTL = time.Hour
+	client := testclient.New(t, c)
+
+	if _, err := client.ValidateSession(client.Utoken); err != nil {
+		t.Fatalf("ValidateSession: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := client.ValidateSession(client.Utoken); !cloudcontrol.IsAuthError(err) {
+		t.Errorf("ValidateSession after expiry: got %v, want auth error", err)
+	}
+
+	client.Utoken = c.Token(cloudtest.DefaultUser)
+	if _, err := client.GetGroups(); err != nil {
+		t.Errorf("GetGroups with new token: %v", err)
+	}
+	c.ExpireTokens()
+	if _, err := client.GetGroups(); !cloudcontrol.IsAuthError(err) {
+		t.Errorf("GetGroups after ExpireTokens: got %v, want auth error", err)
+	}
+}
+
+func TestControl(t *testing.T) {
+	c := cloudtest.Default()
+	client := testclient.New(t, c)
+	client.SetDevice(living)
+
+	on, cool, temperature, speed := 1, pt.Modes["cool"], 23.5, pt.FanSpeeds["high"]
+	if _, err := client.SetState(pt.DeviceControlParameters{Operate: &on, OperationMode: &cool, TemperatureSet: &temperature, FanSpeed: &speed}); err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cloudtest.Device(living, "Living", "CS-Z25XKEW").Parameters
+	want.Operate, want.OperationMode, want.TemperatureSet, want.FanSpeed = on, cool, temperature, speed
+	if diff := cmp.Diff(want, status.Parameters); diff != "" {
+		t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+	}
+
+	// Rejected commands do not change the state
+	invalid := []pt.DeviceControlParameters{}
+	for _, v := range []float64{31, 20.3} {
+		v := v
+		invalid = append(invalid, pt.DeviceControlParameters{TemperatureSet: &v})
+	}
+	for _, p := range invalid {
+		if _, err := client.SetState(p); err == nil {
+			t.Errorf("SetState(%v) succeeded, want error", *p.TemperatureSet)
+		}
+	}
+	bedroom, heat := "CS-TZ20WKEW+8765", pt.Modes["heat"]
+	client.SetDevice(bedroom)
+	if _, err := client.SetMode(heat); err == nil {
+		t.Error("SetMode(heat) on a cooling only device succeeded, want error")
+	}
+	if err := c.Update(bedroom, func(d *pt.Device) { d.Parameters.Online = false }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TurnOn(); err == nil {
+		t.Error("TurnOn of an offline device succeeded, want error")
+	}
+
+	if got := len(c.Commands()); got != 1 {
+		t.Errorf("got %d applied commands, want 1", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	c := cloudtest.Default()
+	now := time.Date(2021, 3, 10, 14, 30, 0, 0, time.Local)
+	c.Now = func() time.Time { return now }
+	client := testclient.New(t, c)
+	client.SetDevice(living)
+
+	tests := []struct {
+		period    string
+		entries   int
+		withData  int
+		dateShift int
+	}{
+		{"day", 24, 15, 0},
+		{"day", 24, 24, -1},
+		{"day", 24, 0, 1},
+		{"week", 7, 3, 0},
+		{"month", 31, 10, 0},
+		{"month", 28, 28, -15},
+		{"year", 12, 3, 0},
+	}
+	for _, tc := range tests {
+		history, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode[tc.period], now.AddDate(0, 0, tc.dateShift))
+		if err != nil {
+			t.Fatal(err)
+		}
+		withData := 0
+		total := 0.0
+		for _, e := range history.HistoryEntries {
+			if e.Consumption != pt.HistoryNoData {
+				withData++
+				total += e.Consumption
+			}
+		}
+		if len(history.HistoryEntries) != tc.entries || withData != tc.withData {
+			t.Errorf("%s %+d: got %d entries with %d with data, want %d with %d", tc.period, tc.dateShift, len(history.HistoryEntries), withData, tc.entries, tc.withData)
+		}
+		if diff := history.EnergyConsumption - total; diff > 0.01 || diff < -0.01 {
+			t.Errorf("%s %+d: energy consumption %v, want sum of entries %v", tc.period, tc.dateShift, history.EnergyConsumption, total)
+		}
+	}
+
+	// History is generated deterministically
+	first, _ := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], now)
+	second, _ := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], now)
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("history changed (-first +second):\n%s", diff)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.Local)
+	c := cloudtest.Default()
+	c.Now = func() time.Time { return now }
+	c.RateLimit = 2
+	client := testclient.New(t, c)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetGroups(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var httpErr *cloudcontrol.HTTPError
+	if _, err := client.GetGroups(); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("third request: got %v, want status 429", err)
+	}
+	now = now.Add(cloudtest.DefaultRateWindow)
+	if _, err := client.GetGroups(); err != nil {
+		t.Errorf("request after rate window: %v", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	c := cloudtest.Default()
+	client := testclient.New(t, c)
+	client.SetDevice(living)
+
+	c.AddFault(cloudtest.Fault{Path: pt.URLDeviceStatus, Status: http.StatusServiceUnavailable, Count: 1})
+	var httpErr *cloudcontrol.HTTPError
+	if _, err := client.GetDeviceStatus(); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GetDeviceStatus with fault: got %v, want status 503", err)
+	}
+	if _, err := client.GetDeviceStatus(); err != nil {
+		t.Errorf("GetDeviceStatus after fault: %v", err)
+	}
+
+	c.AddFault(cloudtest.Fault{Drop: true})
+	if _, err := client.GetGroups(); err == nil || errors.As(err, &httpErr) {
+		t.Errorf("GetGroups with dropped connection: got %v, want network error", err)
+	}
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pt.URLGroups, nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("dropped connection without hijacker: got status %d, want 502", rec.Code)
+	}
+	c.ClearFaults()
+	if _, err := client.GetGroups(); err != nil {
+		t.Errorf("GetGroups after ClearFaults: %v", err)
+	}
+}
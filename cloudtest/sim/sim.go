@@ -0,0 +1,275 @@
+// Package sim simulates the rooms conditioned by Panasonic devices.
+// A Simulator implements cloudtest.Model: the inside temperature of a
+// room follows the heat pump output and the losses to the outdoor
+// temperature, and the energy consumption and temperatures are kept per
+// hour for the history. Simulated time can run faster than real time.
+package sim
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// Step is the simulated time between two updates of a room.
+const Step = time.Minute
+
+// Clock is a simulated clock running a number of times faster than
+// real time. Advance jumps forward, a clock with speed 0 only moves
+// with Advance.
+type Clock struct {
+	mu     sync.Mutex
+	start  time.Time
+	real   time.Time
+	speed  float64
+	offset time.Duration
+}
+
+// NewClock creates a clock starting now at start.
+func NewClock(start time.Time, speed float64) *Clock {
+	return &Clock{start: start, real: time.Now(), speed: speed}
+}
+
+// Now returns the simulated time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elapsed := time.Duration(float64(time.Since(c.real)) * c.speed)
+	return c.start.Add(elapsed + c.offset)
+}
+
+// Advance moves the clock forward.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+}
+
+// Weather returns the outdoor temperature at a time.
+type Weather func(t time.Time) float64
+
+// Daily is a daily outdoor temperature curve around mean, coldest at
+// 5:00 and warmest at 17:00.
+func Daily(mean, amplitude float64) Weather {
+	return func(t time.Time) float64 {
+		hours := float64(t.Hour()) + float64(t.Minute())/60
+		return mean - amplitude*math.Cos(2*math.Pi*(hours-5)/24)
+	}
+}
+
+// Room describes a room and the heat pump conditioning it.
+type Room struct {
+	// Capacity is the heat in kWh that warms the room 1 °C
+	Capacity float64
+	// Loss is the heat loss in kW per °C difference with outdoors
+	Loss float64
+	// HeatingCapacity and CoolingCapacity are the maximum thermal
+	// outputs in kW at 7 °C outdoors
+	HeatingCapacity float64
+	CoolingCapacity float64
+	// COP and EER are the heating and cooling efficiencies at 7 °C
+	// and 35 °C outdoors
+	COP float64
+	EER float64
+	// Standby is the consumption in kW when switched off, Fan the
+	// consumption of the fan when on
+	Standby float64
+	Fan     float64
+	// Heating below DefrostBelow °C outdoors is interrupted every
+	// DefrostEvery for DefrostDuration to defrost the outdoor unit
+	DefrostBelow    float64
+	DefrostEvery    time.Duration
+	DefrostDuration time.Duration
+}
+
+// DefaultRoom is a 25 m² room with a 2.5 kW unit.
+func DefaultRoom() Room {
+	return Room{
+		Capacity:        1,
+		Loss:            0.08,
+		HeatingCapacity: 3.2,
+		CoolingCapacity: 2.5,
+		COP:             4,
+		EER:             3.5,
+		Standby:         0.002,
+		Fan:             0.02,
+		DefrostBelow:    3,
+		DefrostEvery:    time.Hour,
+		DefrostDuration: 5 * time.Minute,
+	}
+}
+
+// bucket sums the simulation steps of an hour.
+type bucket struct {
+	energy                    float64
+	setpoint, inside, outside float64
+	samples                   int
+}
+
+// room is the simulated state of a device.
+type room struct {
+	Room
+	inside       float64
+	reported     float64 // inside temperature set on the device
+	last         time.Time
+	heating      time.Duration // heating time since the last defrost
+	defrostUntil time.Time
+	hours        map[int64]*bucket
+}
+
+// Simulator simulates the rooms of all devices. Rooms are created with
+// DefaultRoom unless added before the first update of the device. An
+// inside temperature changed on the device, eg with cloudtest.Cloud.Update,
+// is taken over by the simulation.
+type Simulator struct {
+	Weather Weather
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+// New creates a Simulator with the given outdoor temperature.
+func New(weather Weather) *Simulator {
+	return &Simulator{Weather: weather, rooms: map[string]*room{}}
+}
+
+// AddRoom sets the room of a device.
+func (s *Simulator) AddRoom(deviceGUID string, r Room) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rooms[deviceGUID] = &room{Room: r}
+}
+
+// output returns the thermal output in kW and its electrical
+// consumption for the device parameters, positive when heating.
+func (r *room) output(p pt.DeviceParameters, outside float64, at time.Time) (float64, float64) {
+	if p.Operate == 0 {
+		return 0, r.Standby
+	}
+
+	mode := p.OperationMode
+	if mode == pt.Modes["auto"] {
+		mode = pt.Modes["cool"]
+		if r.inside < p.TemperatureSet {
+			mode = pt.Modes["heat"]
+		}
+	}
+
+	// Output modulates to full power at 1 °C from the setpoint
+	switch mode {
+	case pt.Modes["heat"]:
+		if at.Before(r.defrostUntil) {
+			return 0, r.Fan
+		}
+		max := r.HeatingCapacity * math.Max(0.4, 1+0.02*(outside-7))
+		heat := math.Max(0, math.Min(max, max*(p.TemperatureSet-r.inside)))
+		cop := math.Max(1.5, r.COP*(1+0.025*(outside-7)))
+		return heat, r.Fan + heat/cop
+	case pt.Modes["cool"], pt.Modes["dry"]:
+		max := r.CoolingCapacity * math.Max(0.5, 1-0.01*(outside-35))
+		if mode == pt.Modes["dry"] {
+			max *= 0.3
+		}
+		cool := math.Max(0, math.Min(max, max*(r.inside-p.TemperatureSet)))
+		eer := math.Max(1.5, r.EER*(1-0.03*(outside-35)))
+		return -cool, r.Fan + cool/eer
+	}
+
+	return 0, r.Fan
+}
+
+// step simulates the room for d starting at t.
+func (r *room) step(p pt.DeviceParameters, outside float64, t time.Time, d time.Duration) {
+	heat, power := r.output(p, outside, t)
+	hours := d.Hours()
+	r.inside += (heat - r.Loss*(r.inside-outside)) * hours / r.Capacity
+
+	if heat > 0 {
+		r.heating += d
+		if outside < r.DefrostBelow && r.DefrostEvery > 0 && r.heating >= r.DefrostEvery {
+			r.heating = 0
+			r.defrostUntil = t.Add(d + r.DefrostDuration)
+		}
+	}
+
+	hour := t.Truncate(time.Hour).Unix()
+	b, found := r.hours[hour]
+	if !found {
+		b = &bucket{}
+		r.hours[hour] = b
+	}
+	b.energy += power * hours
+	b.setpoint += p.TemperatureSet
+	b.inside += r.inside
+	b.outside += outside
+	b.samples++
+}
+
+// Update implements cloudtest.Model.
+func (s *Simulator) Update(d *pt.Device, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, found := s.rooms[d.DeviceGUID]
+	if !found {
+		r = &room{Room: DefaultRoom()}
+		s.rooms[d.DeviceGUID] = r
+	}
+	if r.hours == nil {
+		r.hours = map[int64]*bucket{}
+		r.inside = d.Parameters.InsideTemperature
+		r.last = now
+	} else if d.Parameters.InsideTemperature != r.reported {
+		r.inside = d.Parameters.InsideTemperature
+	}
+
+	for r.last.Before(now) {
+		step := Step
+		if now.Sub(r.last) < step {
+			step = now.Sub(r.last)
+		}
+		r.step(d.Parameters, s.Weather(r.last), r.last, step)
+		r.last = r.last.Add(step)
+	}
+
+	r.reported = math.Round(r.inside*2) / 2
+	d.Parameters.InsideTemperature = r.reported
+	d.Parameters.OutsideTemperature = math.Round(s.Weather(now))
+	d.Parameters.Defrosting = 0
+	if now.Before(r.defrostUntil) && d.Parameters.Operate == 1 {
+		d.Parameters.Defrosting = 1
+	}
+}
+
+// History implements cloudtest.Model.
+func (s *Simulator) History(deviceGUID string, from, to time.Time) (pt.HistoryEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, found := s.rooms[deviceGUID]
+	if !found {
+		return pt.HistoryEntry{}, false
+	}
+
+	total := bucket{}
+	for hour := from.Truncate(time.Hour); hour.Before(to); hour = hour.Add(time.Hour) {
+		if b, found := r.hours[hour.Unix()]; found {
+			total.energy += b.energy
+			total.setpoint += b.setpoint
+			total.inside += b.inside
+			total.outside += b.outside
+			total.samples += b.samples
+		}
+	}
+	if total.samples == 0 {
+		return pt.HistoryEntry{}, false
+	}
+
+	n := float64(total.samples)
+	return pt.HistoryEntry{
+		Consumption:        total.energy,
+		AverageSettingTemp: math.Round(total.setpoint/n*2) / 2,
+		AverageInsideTemp:  math.Round(total.inside/n*4) / 4,
+		AverageOutsideTemp: math.Round(total.outside/n*4) / 4,
+	}, true
+}
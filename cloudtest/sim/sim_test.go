@@ -0,0 +1,147 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/sim"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+var start = time.Date(2021, 1, 10, 6, 0, 0, 0, time.Local)
+
+// simulate starts the default cloud with a simulator and a manual clock
+// and returns a client logged in to it.
+func simulate(t *testing.T, weather sim.Weather) (*cloudtest.Cloud, *sim.Clock, cloudcontrol.Client) {
+	clock := sim.NewClock(start, 0)
+	cloud := cloudtest.Default()
+	cloud.Now = clock.Now
+	cloud.Model = sim.New(weather)
+	server := testclient.NewServer(cloud)
+	t.Cleanup(server.Close)
+
+	client := cloudcontrol.NewClient(server.URL)
+	client.Utoken = cloud.Token(cloudtest.DefaultUser)
+	client.SetDevice(living)
+	return cloud, clock, client
+}
+
+func inside(t *testing.T, client cloudcontrol.Client) float64 {
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status.Parameters.InsideTemperature
+}
+
+func TestHeating(t *testing.T) {
+	_, clock, client := simulate(t, sim.Daily(5, 0))
+	if got := inside(t, client); got != 20 {
+		t.Fatalf("initial inside temperature %v, want 20", got)
+	}
+
+	// Switched off the room cools down towards the outdoor temperature
+	clock.Advance(3 * time.Hour)
+	cooled := inside(t, client)
+	if cooled < 16 || cooled > 18 {
+		t.Errorf("inside temperature after 3 hours off %v, want about 17", cooled)
+	}
+
+	on, heat, temperature := 1, pt.Modes["heat"], 22.0
+	if _, err := client.SetState(pt.DeviceControlParameters{Operate: &on, OperationMode: &heat, TemperatureSet: &temperature}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	if got := inside(t, client); got <= cooled {
+		t.Errorf("inside temperature after 1 hour heating %v, want above %v", got, cooled)
+	}
+	clock.Advance(2 * time.Hour)
+	if got := inside(t, client); math.Abs(got-temperature) > 0.5 {
+		t.Errorf("inside temperature after 3 hours heating %v, want %v", got, temperature)
+	}
+
+	history, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hour, e := range history.HistoryEntries {
+		switch {
+		case hour < 6 || hour >= 12:
+			if e.Consumption != pt.HistoryNoData {
+				t.Errorf("hour %d: consumption %v outside the simulation, want no data", hour, e.Consumption)
+			}
+		case hour < 9:
+			if e.Consumption != 0 || e.AverageOutsideTemp != 5 {
+				t.Errorf("hour %d: switched off consumption %v and outside %v, want 0 and 5", hour, e.Consumption, e.AverageOutsideTemp)
+			}
+		case hour == 9:
+			if e.Consumption < 0.5 || e.AverageSettingTemp != temperature {
+				t.Errorf("hour %d: heating consumption %v and setpoint %v, want at least 0.5 and %v", hour, e.Consumption, e.AverageSettingTemp, temperature)
+			}
+		}
+	}
+	if history.EnergyConsumption <= 0 {
+		t.Errorf("energy consumption %v, want positive", history.EnergyConsumption)
+	}
+}
+
+func TestCooling(t *testing.T) {
+	cloud, clock, client := simulate(t, sim.Daily(30, 0))
+	if err := cloud.Update(living, func(d *pt.Device) { d.Parameters.InsideTemperature = 29 }); err != nil {
+		t.Fatal(err)
+	}
+	on, cool, temperature := 1, pt.Modes["cool"], 24.0
+	if _, err := client.SetState(pt.DeviceControlParameters{Operate: &on, OperationMode: &cool, TemperatureSet: &temperature}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(4 * time.Hour)
+	if got := inside(t, client); math.Abs(got-temperature) > 0.5 {
+		t.Errorf("inside temperature after 4 hours cooling %v, want %v", got, temperature)
+	}
+}
+
+func TestDefrost(t *testing.T) {
+	cloud, clock, client := simulate(t, sim.Daily(-5, 2))
+	on := 1
+	if _, err := client.SetState(pt.DeviceControlParameters{Operate: &on}); err != nil {
+		t.Fatal(err)
+	}
+
+	defrosting := 0
+	for i := 0; i < 180; i++ {
+		clock.Advance(time.Minute)
+		status, _ := cloud.Status(living)
+		defrosting += status.Parameters.Defrosting
+	}
+	// Two defrost cycles of 5 minutes in three hours of heating
+	if defrosting < 8 || defrosting > 12 {
+		t.Errorf("defrosting %d of 180 minutes, want about 10", defrosting)
+	}
+}
+
+func TestDailyWeather(t *testing.T) {
+	weather := sim.Daily(10, 5)
+	coldest := weather(time.Date(2021, 1, 1, 5, 0, 0, 0, time.Local))
+	warmest := weather(time.Date(2021, 1, 1, 17, 0, 0, 0, time.Local))
+	if coldest != 5 || warmest != 15 {
+		t.Errorf("coldest %v and warmest %v, want 5 and 15", coldest, warmest)
+	}
+}
+
+func TestClock(t *testing.T) {
+	clock := sim.NewClock(start, 3600)
+	time.Sleep(10 * time.Millisecond)
+	if elapsed := clock.Now().Sub(start); elapsed < 36*time.Second {
+		t.Errorf("simulated %v after 10ms at 3600 times real time, want at least 36s", elapsed)
+	}
+	clock.Advance(time.Hour)
+	if elapsed := clock.Now().Sub(start); elapsed < time.Hour {
+		t.Errorf("simulated %v after advancing an hour", elapsed)
+	}
+}
@@ -0,0 +1,29 @@
+// Package testclient serves a fake cloud and logs in to it for tests.
+// It imports the testing package, so only import it from tests.
+package testclient
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+)
+
+// NewServer starts an httptest.Server serving the cloud.
+func NewServer(c *cloudtest.Cloud) *httptest.Server {
+	return httptest.NewServer(c)
+}
+
+// New starts a server for the cloud, closed when the test ends, and
+// returns a client logged in as cloudtest.DefaultUser.
+func New(t testing.TB, c *cloudtest.Cloud) *cloudcontrol.Client {
+	t.Helper()
+	server := NewServer(c)
+	t.Cleanup(server.Close)
+	client := cloudcontrol.NewClient(server.URL)
+	if _, err := client.CreateSession(cloudtest.DefaultUser, cloudtest.DefaultPassword); err != nil {
+		t.Fatal(err)
+	}
+	return &client
+}
@@ -0,0 +1,264 @@
+// Package exporter exposes Panasonic Comfort Cloud device state as
+// Prometheus metrics. The cloud is polled on the exporter's own
+// schedule so scrapes are served from a cache, which can be shared
+// with the REST API.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cache"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultInterval        = time.Minute
+	DefaultHistoryInterval = 15 * time.Minute
+)
+
+// device is the cached state of a single device.
+type device struct {
+	group  string
+	name   string
+	status pt.Device
+	// energy holds the consumption in kWh of yesterday and today per
+	// day (YYYYMMDD), closed the total of the days before
+	energy map[string]float64
+	closed float64
+}
+
+// Exporter polls devices and serves their state as metrics.
+type Exporter struct {
+	Client          *cloudcontrol.Client
+	Interval        time.Duration
+	HistoryInterval time.Duration
+	// Cache holds the device status, the API can serve from it as well
+	Cache *cache.Cache
+
+	mu            sync.RWMutex
+	devices       map[string]*device
+	lastPoll      time.Time
+	historyErrors int
+}
+
+// New creates an Exporter with the default intervals.
+func New(client *cloudcontrol.Client) *Exporter {
+	return &Exporter{
+		Client:          client,
+		Interval:        DefaultInterval,
+		HistoryInterval: DefaultHistoryInterval,
+		Cache:           cache.New(client),
+		devices:         map[string]*device{},
+	}
+}
+
+// Poll refreshes the status of all devices in the cache. The time of the
+// last poll is only updated when all devices were polled.
+func (e *Exporter) Poll() error {
+	err := e.Cache.Poll()
+	groupDevices := e.Cache.Devices()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, gd := range groupDevices {
+		status, ok := e.Cache.Cached(gd.DeviceGUID)
+		if !ok {
+			continue
+		}
+		d, ok := e.devices[gd.DeviceGUID]
+		if !ok {
+			d = &device{energy: map[string]float64{}}
+			e.devices[gd.DeviceGUID] = d
+		}
+		d.group = gd.GroupName
+		d.name = gd.DeviceName
+		d.status = status.Device
+	}
+	if err == nil {
+		e.lastPoll = time.Now()
+	}
+
+	return err
+}
+
+// PollHistory refreshes the energy consumption of yesterday and
+// today for all known devices. Fetching yesterday as well makes
+// sure the last hours of a day are counted, older days are only
+// kept in a total.
+func (e *Exporter) PollHistory(now time.Time) error {
+	e.mu.RLock()
+	guids := []string{}
+	for guid := range e.devices {
+		guids = append(guids, guid)
+	}
+	e.mu.RUnlock()
+
+	var firstErr error
+	for _, guid := range guids {
+		client := *e.Client
+		client.SetDevice(guid)
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			history, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], day)
+			if err != nil {
+				e.mu.Lock()
+				e.historyErrors++
+				e.mu.Unlock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			total := 0.0
+			for _, entry := range history.HistoryEntries {
+				if entry.Consumption != pt.HistoryNoData {
+					total += entry.Consumption
+				}
+			}
+			e.mu.Lock()
+			d := e.devices[guid]
+			key := day.Format("20060102")
+			// Never let the counter go down on a partial response
+			if total > d.energy[key] {
+				d.energy[key] = total
+			}
+			e.mu.Unlock()
+		}
+
+		// Days before yesterday are no longer fetched, so they are
+		// moved into the closed total
+		oldest := now.AddDate(0, 0, -1).Format("20060102")
+		e.mu.Lock()
+		d := e.devices[guid]
+		for key, v := range d.energy {
+			if key < oldest {
+				d.closed += v
+				delete(d.energy, key)
+			}
+		}
+		e.mu.Unlock()
+	}
+
+	return firstErr
+}
+
+// Run polls status and history at their intervals until the
+// context is cancelled.
+func (e *Exporter) Run(ctx context.Context) error {
+	status := time.NewTicker(e.Interval)
+	defer status.Stop()
+	history := time.NewTicker(e.HistoryInterval)
+	defer history.Stop()
+
+	if err := e.Poll(); err != nil {
+		log.Errorf("Polling devices failed: %v", err)
+	}
+	if err := e.PollHistory(time.Now()); err != nil {
+		log.Errorf("Polling history failed: %v", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-status.C:
+			if err := e.Poll(); err != nil {
+				log.Errorf("Polling devices failed: %v", err)
+			}
+		case <-history.C:
+			if err := e.PollHistory(time.Now()); err != nil {
+				log.Errorf("Polling history failed: %v", err)
+			}
+		}
+	}
+}
+
+// metric describes a per device metric.
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func(d *device) float64
+}
+
+// boolValue converts a bool to a metric value.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var metrics = []metric{
+	{"panasonic_inside_temperature_celsius", "Inside temperature measured by the device.", "gauge", func(d *device) float64 { return d.status.Parameters.InsideTemperature }},
+	{"panasonic_outside_temperature_celsius", "Outside temperature measured by the device.", "gauge", func(d *device) float64 { return d.status.Parameters.OutsideTemperature }},
+	{"panasonic_temperature_setpoint_celsius", "Temperature set on the device.", "gauge", func(d *device) float64 { return d.status.Parameters.TemperatureSet }},
+	{"panasonic_power", "Device power state, 1 is on.", "gauge", func(d *device) float64 { return float64(d.status.Parameters.Operate) }},
+	{"panasonic_mode", "Device operation mode: 0 auto, 1 dry, 2 cool, 3 heat, 4 fan.", "gauge", func(d *device) float64 { return float64(d.status.Parameters.OperationMode) }},
+	{"panasonic_fan_speed", "Device fan speed, 0 is auto.", "gauge", func(d *device) float64 { return float64(d.status.Parameters.FanSpeed) }},
+	{"panasonic_online", "Device connected to the cloud, 1 is online.", "gauge", func(d *device) float64 { return boolValue(d.status.Parameters.Online) }},
+	{"panasonic_error", "Device reports an error, 1 is error.", "gauge", func(d *device) float64 { return boolValue(d.status.Parameters.ErrorStatusFlg) }},
+	{"panasonic_error_code", "Error code reported by the device.", "gauge", func(d *device) float64 { return float64(d.status.Parameters.ErrorCode) }},
+	{"panasonic_energy_consumption_kwh_total", "Energy consumed since the start of the day before the exporter started, from device history.", "counter", func(d *device) float64 {
+		total := d.closed
+		for _, v := range d.energy {
+			total += v
+		}
+		return total
+	}},
+}
+
+// escape escapes a Prometheus label value.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// WriteMetrics writes all cached metrics in Prometheus text format.
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	guids := []string{}
+	for guid := range e.devices {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, guid := range guids {
+			d := e.devices[guid]
+			fmt.Fprintf(&b, "%s{device=\"%s\",name=\"%s\",group=\"%s\"} %v\n", m.name, escape(guid), escape(d.name), escape(d.group), m.value(d))
+		}
+	}
+	b.WriteString("# HELP panasonic_last_poll_timestamp_seconds Time of the last successful poll.\n")
+	b.WriteString("# TYPE panasonic_last_poll_timestamp_seconds gauge\n")
+	lastPoll := int64(0)
+	if !e.lastPoll.IsZero() {
+		lastPoll = e.lastPoll.Unix()
+	}
+	fmt.Fprintf(&b, "panasonic_last_poll_timestamp_seconds %d\n", lastPoll)
+	b.WriteString("# HELP panasonic_poll_errors_total Failed requests to the cloud.\n")
+	b.WriteString("# TYPE panasonic_poll_errors_total counter\n")
+	fmt.Fprintf(&b, "panasonic_poll_errors_total %d\n", e.Cache.Errors()+e.historyErrors)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the cached metrics.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := e.WriteMetrics(w); err != nil {
+		log.Errorf("Writing metrics failed: %v", err)
+	}
+}
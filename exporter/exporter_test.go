@@ -0,0 +1,164 @@
+package exporter_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/exporter"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+var (
+	groupsBody  = `{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"device1","deviceName":"Living \"room\""}]}]}`
+	statusBody  = `{"deviceGuid":"device1","parameters":{"online":true,"operate":1,"operationMode":3,"temperatureSet":21.5,"insideTemperature":20.0,"outTemperature":5.0}}`
+	historyBody = `{"historyDataList":[{"dataNumber":0,"consumption":0.5},{"dataNumber":1,"consumption":0.25},{"dataNumber":2,"consumption":-255}]}`
+)
+
+// cloud is a fake Panasonic cloud counting status requests.
+func cloud(t *testing.T) (*httptest.Server, *int32) {
+	statusCalls := new(int32)
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(groupsBody))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(statusCalls, 1)
+		_, _ = w.Write([]byte(statusBody))
+	})
+	handler.HandleFunc(pt.URLHistory, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(historyBody))
+	})
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, statusCalls
+}
+
+func TestExporter(t *testing.T) {
+	server, _ := cloud(t)
+	client := cloudcontrol.NewClient(server.URL)
+	e := exporter.New(&client)
+	if err := e.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.PollHistory(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scrapes are served from the cache
+	server.Close()
+	metrics := httptest.NewServer(e)
+	defer metrics.Close()
+	resp, err := http.Get(metrics.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	labels := `{device="device1",name="Living \"room\"",group="My House"}`
+	for _, want := range []string{
+		"# TYPE panasonic_inside_temperature_celsius gauge",
+		"panasonic_inside_temperature_celsius" + labels + " 20",
+		"panasonic_outside_temperature_celsius" + labels + " 5",
+		"panasonic_temperature_setpoint_celsius" + labels + " 21.5",
+		"panasonic_power" + labels + " 1",
+		"panasonic_mode" + labels + " 3",
+		"panasonic_online" + labels + " 1",
+		"# TYPE panasonic_energy_consumption_kwh_total counter",
+		"panasonic_energy_consumption_kwh_total" + labels + " 1.5",
+		"panasonic_poll_errors_total 0",
+	} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("TestExporter() missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestEnergyDays(t *testing.T) {
+	server, _ := cloud(t)
+	client := cloudcontrol.NewClient(server.URL)
+	e := exporter.New(&client)
+	if err := e.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every poll fetches yesterday and today, days before are kept in
+	// the total and the counter never goes down
+	start := time.Date(2021, 1, 4, 12, 0, 0, 0, time.Local)
+	for day := 0; day < 3; day++ {
+		if err := e.PollHistory(start.AddDate(0, 0, day)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var b strings.Builder
+	if err := e.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `panasonic_energy_consumption_kwh_total{device="device1",name="Living \"room\"",group="My House"} 3` + "\n"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("TestEnergyDays() missing %q in:\n%s", want, b.String())
+	}
+}
+
+func TestPollError(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(groupsBody))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "offline", http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := cloudcontrol.NewClient(server.URL)
+	e := exporter.New(&client)
+	if err := e.Poll(); err == nil {
+		t.Fatal("TestPollError() Poll succeeded, want error")
+	}
+
+	// A failed poll is not a successful poll
+	var b strings.Builder
+	if err := e.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"panasonic_last_poll_timestamp_seconds 0\n",
+		"panasonic_poll_errors_total 1\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("TestPollError() missing %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestSharedCache(t *testing.T) {
+	server, statusCalls := cloud(t)
+	client := cloudcontrol.NewClient(server.URL)
+	e := exporter.New(&client)
+	if err := e.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := api.New(&client, e.Cache, []api.Key{{Name: "dashboard", Key: "secret", Devices: []string{"*"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The API serves the status polled by the exporter
+	req := httptest.NewRequest(http.MethodGet, api.Prefix+"/devices/device1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("TestSharedCache() status %d: %s", rec.Code, rec.Body)
+	}
+	if got := atomic.LoadInt32(statusCalls); got != 1 {
+		t.Errorf("TestSharedCache() got %d status requests, want 1", got)
+	}
+}
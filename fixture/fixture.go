@@ -0,0 +1,197 @@
+// Package fixture records the HTTP traffic of a cloudcontrol.Client to
+// fixture files and replays it deterministically in tests. Credentials,
+// session tokens, device GUIDs and device hash GUIDs are redacted before
+// anything is written.
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// Redacted replaces credentials and tokens in fixtures.
+const Redacted = "REDACTED"
+
+// Request is a recorded request. Path includes the query.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Interaction is a request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Fixture is the recorded traffic in the order it was sent.
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Load reads a fixture file.
+func Load(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error: reading fixture: %w", err)
+	}
+	f := &Fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("error: parsing fixture %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Save writes the fixture to a file.
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error: writing fixture: %w", err)
+	}
+	return nil
+}
+
+// secretKeys are the JSON fields holding credentials and tokens.
+var secretKeys = map[string]bool{
+	"loginId":  true,
+	"password": true,
+	"uToken":   true,
+}
+
+// guidKeys are the JSON fields holding device GUIDs.
+var guidKeys = map[string]bool{
+	"deviceGuid": true,
+	"devGuid":    true,
+}
+
+// hashKey is the JSON field holding device hash GUIDs.
+const hashKey = "deviceHashGuid"
+
+// redactor replaces device GUIDs and hash GUIDs by stable placeholders,
+// device-1, hash-1 and so on, so that the requests of a fixture still
+// refer to the devices of its responses.
+type redactor struct {
+	placeholders map[string]string
+	guids        int
+	hashes       int
+}
+
+func newRedactor() *redactor {
+	return &redactor{placeholders: map[string]string{}}
+}
+
+// placeholder returns the placeholder of a GUID or hash GUID.
+func (r *redactor) placeholder(value string, hash bool) string {
+	if value == "" {
+		return value
+	}
+	if p, found := r.placeholders[value]; found {
+		return p
+	}
+	var p string
+	if hash {
+		r.hashes++
+		p = fmt.Sprintf("hash-%d", r.hashes)
+	} else {
+		r.guids++
+		p = fmt.Sprintf("device-%d", r.guids)
+	}
+	r.placeholders[value] = p
+	return p
+}
+
+// path redacts the device GUID of a device status path.
+func (r *redactor) path(u *url.URL) string {
+	path := u.EscapedPath()
+	if r != nil && strings.HasPrefix(u.Path, pt.URLDeviceStatus) {
+		guid := strings.TrimPrefix(u.Path, pt.URLDeviceStatus)
+		path = pt.URLDeviceStatus + url.QueryEscape(r.placeholder(guid, false))
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// body redacts a JSON body and returns it in a normalized form. With a
+// nil redactor only credentials and tokens are redacted. Other bodies
+// are returned unchanged.
+func (r *redactor) body(data []byte) string {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ""
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return string(data)
+	}
+	out, err := json.Marshal(r.scrub("", v))
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+// scrub redacts the value of a JSON field.
+func (r *redactor) scrub(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// Placeholders are numbered in a stable order
+		sort.Strings(keys)
+		for _, k := range keys {
+			v[k] = r.scrub(k, v[k])
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = r.scrub(key, v[i])
+		}
+		return v
+	case string:
+		switch {
+		case secretKeys[key] && v != "":
+			return Redacted
+		case r != nil && guidKeys[key]:
+			return r.placeholder(v, false)
+		case r != nil && key == hashKey:
+			return r.placeholder(v, true)
+		}
+	}
+	return v
+}
+
+// readBody reads and restores the body of a request or response.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	*body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, err
+}
@@ -0,0 +1,183 @@
+package fixture_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/fixture"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const (
+	living = "CS-Z25XKEW+4321"
+	hash   = "0f1e2d3c4b5a69788796a5b4c3d2e1f0"
+)
+
+var date = time.Date(2021, 3, 10, 0, 0, 0, 0, time.Local)
+
+// record records a session against the fake cloud and returns the
+// fixture file and the status of the living room device.
+func record(t *testing.T) (string, pt.Device) {
+	cloud := cloudtest.Default()
+	if err := cloud.Update(living, func(d *pt.Device) { d.DeviceHashGUID = hash }); err != nil {
+		t.Fatal(err)
+	}
+	server := testclient.NewServer(cloud)
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	client := cloudcontrol.NewClient(server.URL)
+	client.HTTPClient = &http.Client{Transport: fixture.NewRecorder(path, nil)}
+	if _, err := client.CreateSession(cloudtest.DefaultUser, cloudtest.DefaultPassword); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetGroups(); err != nil {
+		t.Fatal(err)
+	}
+	client.SetDevice(living)
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TurnOff(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], date); err != nil {
+		t.Fatal(err)
+	}
+	return path, status
+}
+
+// replay returns a client replaying a fixture.
+func replay(t *testing.T, path string, mode fixture.Mode) (cloudcontrol.Client, *fixture.Replayer) {
+	replayer, err := fixture.Open(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := cloudcontrol.NewClient("http://replay.invalid")
+	client.HTTPClient = &http.Client{Transport: replayer}
+	return client, replayer
+}
+
+func TestRedaction(t *testing.T) {
+	path, _ := record(t)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fixture.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{`"` + cloudtest.DefaultPassword + `"`, living, strings.Split(living, "+")[1], hash} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("fixture contains %q", secret)
+		}
+	}
+	var requests []string
+	for _, i := range f.Interactions {
+		requests = append(requests, i.Request.Method+" "+i.Request.Path)
+	}
+	want := []string{
+		"POST " + pt.URLLogin,
+		"GET " + pt.URLGroups,
+		"GET " + pt.URLDeviceStatus + "device-1",
+		"POST " + pt.URLControl,
+		"POST " + pt.URLHistory,
+	}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+	for _, s := range []string{`"loginId":"REDACTED"`, `"password":"REDACTED"`} {
+		if !strings.Contains(f.Interactions[0].Request.Body, s) {
+			t.Errorf("login request %s does not contain %s", f.Interactions[0].Request.Body, s)
+		}
+	}
+	if !strings.Contains(f.Interactions[0].Response.Body, `"uToken":"REDACTED"`) {
+		t.Errorf("login response %s does not contain a redacted token", f.Interactions[0].Response.Body)
+	}
+	if !strings.Contains(f.Interactions[1].Response.Body, `"deviceHashGuid":"hash-1"`) {
+		t.Errorf("groups response %s does not contain a redacted hash GUID", f.Interactions[1].Response.Body)
+	}
+}
+
+func TestStrictReplay(t *testing.T) {
+	path, recorded := record(t)
+	client, replayer := replay(t, path, fixture.Strict)
+
+	if _, err := client.CreateSession("someone", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"device-1", "device-2"}, devices); diff != "" {
+		t.Errorf("ListDevices mismatch (-want +got):\n%s", diff)
+	}
+	client.SetDevice("device-1")
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded.DeviceGUID, recorded.DeviceHashGUID = "device-1", "hash-1"
+	if diff := cmp.Diff(recorded, status); diff != "" {
+		t.Errorf("status mismatch (-want +got):\n%s", diff)
+	}
+
+	// Requests out of order are rejected
+	if _, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], date); err == nil {
+		t.Error("history before control succeeded, want error")
+	}
+	if _, err := client.TurnOn(); err == nil {
+		t.Error("TurnOn while TurnOff was recorded succeeded, want error")
+	}
+	if _, err := client.TurnOff(); err != nil {
+		t.Errorf("TurnOff: %v", err)
+	}
+	if _, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], date); err != nil {
+		t.Errorf("GetDeviceHistoryForDate: %v", err)
+	}
+	if got := replayer.Remaining(); got != 0 {
+		t.Errorf("%d interactions not replayed, want 0", got)
+	}
+	if _, err := client.GetGroups(); err == nil {
+		t.Error("request after the end of the fixture succeeded, want error")
+	}
+}
+
+func TestLenientReplay(t *testing.T) {
+	path, _ := record(t)
+	client, replayer := replay(t, path, fixture.Lenient)
+	client.SetDevice("device-1")
+
+	// Order, bodies and repetitions do not matter
+	if _, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["week"], time.Now()); err != nil {
+		t.Errorf("GetDeviceHistoryForDate: %v", err)
+	}
+	if _, err := client.TurnOn(); err != nil {
+		t.Errorf("TurnOn: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetGroups(); err != nil {
+			t.Errorf("GetGroups %d: %v", i, err)
+		}
+	}
+	if got := replayer.Remaining(); got != 2 {
+		t.Errorf("%d interactions not replayed, want 2", got)
+	}
+
+	client.SetDevice("device-2")
+	if _, err := client.GetDeviceStatus(); err == nil {
+		t.Error("status of a device that was not recorded succeeded, want error")
+	}
+}
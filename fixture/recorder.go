@@ -0,0 +1,72 @@
+package fixture
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Recorder is a http.RoundTripper recording all traffic to a fixture
+// file. The file is rewritten after every request, so it is complete
+// when the program is interrupted.
+type Recorder struct {
+	// Transport sends the requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+
+	path     string
+	mu       sync.Mutex
+	fixture  Fixture
+	redactor *redactor
+}
+
+// NewRecorder creates a Recorder writing to path.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport, path: path, redactor: newRedactor()}
+}
+
+// Fixture returns a copy of the recorded traffic.
+func (r *Recorder) Fixture() Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Fixture{Interactions: append([]Interaction{}, r.fixture.Interactions...)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Interactions = append(r.fixture.Interactions, Interaction{
+		Request: Request{
+			Method: req.Method,
+			Path:   r.redactor.path(req.URL),
+			Body:   r.redactor.body(reqBody),
+		},
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        r.redactor.body(respBody),
+		},
+	})
+	if err := r.fixture.Save(r.path); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+package fixture
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Request matching modes of a Replayer.
+const (
+	// Strict replays the interactions in the recorded order and
+	// requires the method, path and body of every request to match.
+	Strict Mode = iota
+	// Lenient replays the first unused interaction with the method and
+	// path of the request, ignoring the query and body. When all of them
+	// are used the last one is repeated, so polling loops keep working.
+	Lenient
+)
+
+// Mode is the request matching mode of a Replayer.
+type Mode int
+
+// Replayer is a http.RoundTripper serving the responses of a fixture.
+// Credentials and tokens in requests are redacted before matching, so
+// any username, password and token work. Devices must be referred to by
+// their placeholders, eg device-1.
+type Replayer struct {
+	Mode Mode
+
+	mu      sync.Mutex
+	fixture *Fixture
+	used    []bool
+	next    int
+	last    map[string]int
+}
+
+// NewReplayer creates a Replayer for a fixture.
+func NewReplayer(f *Fixture, mode Mode) *Replayer {
+	return &Replayer{Mode: mode, fixture: f, used: make([]bool, len(f.Interactions)), last: map[string]int{}}
+}
+
+// Open creates a Replayer for a fixture file.
+func Open(path string, mode Mode) (*Replayer, error) {
+	f, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(f, mode), nil
+}
+
+// Remaining returns the number of interactions not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var redactor *redactor
+	got := Request{Method: req.Method, Path: redactor.path(req.URL), Body: redactor.body(data)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.match(got)
+	if err != nil {
+		return nil, err
+	}
+	r.used[i] = true
+
+	recorded := r.fixture.Interactions[i].Response
+	resp := &http.Response{
+		StatusCode:    recorded.Status,
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+	if recorded.ContentType != "" {
+		resp.Header.Set("Content-Type", recorded.ContentType)
+	}
+	return resp, nil
+}
+
+// match returns the index of the interaction answering a request.
+func (r *Replayer) match(got Request) (int, error) {
+	if r.Mode == Strict {
+		if r.next >= len(r.fixture.Interactions) {
+			return 0, fmt.Errorf("error: unexpected request %s %s, all %d recorded requests were replayed", got.Method, got.Path, len(r.fixture.Interactions))
+		}
+		want := r.fixture.Interactions[r.next].Request
+		if got != want {
+			return 0, fmt.Errorf("error: request %d %s %s %s does not match recorded %s %s %s", r.next+1, got.Method, got.Path, got.Body, want.Method, want.Path, want.Body)
+		}
+		r.next++
+		return r.next - 1, nil
+	}
+
+	key := got.Method + " " + withoutQuery(got.Path)
+	for i, interaction := range r.fixture.Interactions {
+		if !r.used[i] && interaction.Request.Method+" "+withoutQuery(interaction.Request.Path) == key {
+			r.last[key] = i
+			return i, nil
+		}
+	}
+	if i, found := r.last[key]; found {
+		return i, nil
+	}
+	return 0, fmt.Errorf("error: no recorded request for %s", key)
+}
+
+func withoutQuery(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -7,18 +7,30 @@ replace github.com/hacktobeer/go-panasonic => ./
 replace github.com/hacktobeer/go-panasonic/types => ./types/
 
 require (
-	github.com/google/go-cmp v0.3.0
+	github.com/brutella/hap v0.0.17
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/google/go-cmp v0.5.9
 	github.com/hacktobeer/go-panasonic v1.0.0
 	github.com/hacktobeer/go-panasonic/types v0.0.0-00010101000000-000000000000
 	github.com/sirupsen/logrus v1.2.0
 	github.com/spf13/viper v1.7.1
+	github.com/tadglines/go-pkgs v0.0.0-20210623144937-b983b20f54f9
+	go.etcd.io/bbolt v1.3.7
+	google.golang.org/grpc v1.58.3
+	google.golang.org/protobuf v1.31.0
+	gopkg.in/yaml.v2 v2.2.4
 )
 
 require (
+	github.com/brutella/dnssd v1.2.3 // indirect
 	github.com/fsnotify/fsnotify v1.4.7 // indirect
+	github.com/go-chi/chi v1.5.4 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
 	github.com/magiconair/properties v1.8.1 // indirect
+	github.com/miekg/dns v1.1.50 // indirect
 	github.com/mitchellh/mapstructure v1.1.2 // indirect
 	github.com/pelletier/go-toml v1.2.0 // indirect
 	github.com/spf13/afero v1.1.2 // indirect
@@ -26,9 +38,15 @@ require (
 	github.com/spf13/jwalterweatherman v1.0.0 // indirect
 	github.com/spf13/pflag v1.0.3 // indirect
 	github.com/subosito/gotenv v1.2.0 // indirect
-	golang.org/x/crypto v0.0.0-20190605123033-f99c8df09eb5 // indirect
-	golang.org/x/sys v0.0.0-20220913175220-63ea55921009 // indirect
-	golang.org/x/text v0.3.2 // indirect
+	github.com/xiam/to v0.0.0-20200126224905-d60d31e03561 // indirect
+	golang.org/x/crypto v0.11.0 // indirect
+	golang.org/x/mod v0.8.0 // indirect
+	golang.org/x/net v0.12.0 // indirect
+	golang.org/x/sync v0.3.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/term v0.10.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
+	golang.org/x/tools v0.6.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230711160842-782d3b101e98 // indirect
 	gopkg.in/ini.v1 v1.51.0 // indirect
-	gopkg.in/yaml.v2 v2.2.4 // indirect
 )
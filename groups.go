@@ -0,0 +1,152 @@
+package cloudcontrol
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// DefaultParallelism is the default number of devices
+// contacted concurrently by group operations.
+const DefaultParallelism = 4
+
+// DeviceResult is the outcome of a command sent to a single device.
+type DeviceResult struct {
+	DeviceGUID string
+	Body       []byte
+	Err        error
+}
+
+// StatusResult is the status of a single device in a group operation.
+type StatusResult struct {
+	DeviceGUID string
+	Status     pt.Device
+	Err        error
+}
+
+// GroupDevice is a device together with the name of its group.
+type GroupDevice struct {
+	GroupName string
+	pt.Device
+}
+
+// ListGroupDevices lists all devices with their group. The device
+// parameters come from the groups response, so no status calls
+// are made per device.
+func (c *Client) ListGroupDevices() ([]GroupDevice, error) {
+	groups, err := c.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []GroupDevice{}
+	for _, group := range groups.Groups {
+		for _, device := range group.Devices {
+			devices = append(devices, GroupDevice{GroupName: group.GroupName, Device: device})
+		}
+	}
+
+	return devices, nil
+}
+
+// GetGroup gets the group with the given name.
+func (c *Client) GetGroup(name string) (pt.Group, error) {
+	groups, err := c.GetGroups()
+	if err != nil {
+		return pt.Group{}, err
+	}
+	for _, group := range groups.Groups {
+		if group.GroupName == name {
+			return group, nil
+		}
+	}
+
+	return pt.Group{}, fmt.Errorf("error: group %q not found", name)
+}
+
+// ResolveDevices returns the GUIDs of the devices matching name. The
+// name can be a group name or anything accepted by FindDevice. A group
+// name resolves to all devices in that group.
+func (c *Client) ResolveDevices(name string) ([]string, error) {
+	groups, err := c.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	all := []pt.Device{}
+	for _, group := range groups.Groups {
+		if group.GroupName == name {
+			devices := []string{}
+			for _, device := range group.Devices {
+				devices = append(devices, device.DeviceGUID)
+			}
+			return devices, nil
+		}
+		all = append(all, group.Devices...)
+	}
+
+	if target, ok := c.Aliases[strings.ToLower(name)]; ok {
+		name = target
+	}
+	device, err := matchDevice(all, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{device.DeviceGUID}, nil
+}
+
+// forDevice returns a copy of the client that targets the given device.
+func (c *Client) forDevice(deviceGUID string) *Client {
+	client := *c
+	client.SetDevice(deviceGUID)
+	return &client
+}
+
+// fanOut calls fn for every device with at most parallel calls running
+// at the same time. A parallel value below 1 uses DefaultParallelism.
+func fanOut(devices []string, parallel int, fn func(i int, device string)) {
+	if parallel < 1 {
+		parallel = DefaultParallelism
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for i, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, device string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i, device)
+		}(i, device)
+	}
+	wg.Wait()
+}
+
+// Each runs command against every device concurrently. The command
+// receives a copy of the client targeting a single device, eg
+// (*Client).TurnOff. Results are returned in the order of devices.
+func (c *Client) Each(devices []string, parallel int, command func(*Client) ([]byte, error)) []DeviceResult {
+	results := make([]DeviceResult, len(devices))
+	fanOut(devices, parallel, func(i int, device string) {
+		body, err := command(c.forDevice(device))
+		results[i] = DeviceResult{DeviceGUID: device, Body: body, Err: err}
+	})
+
+	return results
+}
+
+// EachStatus gets the status of every device concurrently.
+// Results are returned in the order of devices.
+func (c *Client) EachStatus(devices []string, parallel int) []StatusResult {
+	results := make([]StatusResult, len(devices))
+	fanOut(devices, parallel, func(i int, device string) {
+		status, err := c.forDevice(device).GetDeviceStatus()
+		results[i] = StatusResult{DeviceGUID: device, Status: status, Err: err}
+	})
+
+	return results
+}
@@ -0,0 +1,167 @@
+package homekit
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/brutella/hap/accessory"
+	"github.com/brutella/hap/characteristic"
+	"github.com/brutella/hap/service"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// Temperature range of the threshold characteristics
+const (
+	MinTemperature  = 16
+	MaxTemperature  = 30
+	TemperatureStep = 0.5
+)
+
+// RotationSpeedAuto is the rotation speed mapped to the auto fan speed.
+// Rotation speeds 1 to 5 map to the fixed fan speeds low to high, 0 is
+// sent by HomeKit when turning the device off and is ignored.
+const RotationSpeedAuto = 6
+
+// Accessory is a device exposed as a HomeKit HeaterCooler.
+type Accessory struct {
+	*accessory.A
+	HeaterCooler  *service.HeaterCooler
+	Heating       *characteristic.HeatingThresholdTemperature
+	Cooling       *characteristic.CoolingThresholdTemperature
+	RotationSpeed *characteristic.RotationSpeed
+	SwingMode     *characteristic.SwingMode
+
+	DeviceGUID string
+}
+
+// accessoryID returns a stable accessory id for a device, so HomeKit
+// keeps its rooms and automations when devices are added or removed.
+// Id 1 is used by the bridge itself.
+func accessoryID(deviceGUID string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(deviceGUID))
+	id := h.Sum64()
+	if id <= 1 {
+		id += 2
+	}
+	return id
+}
+
+// NewAccessory creates the accessory of a device. HomeKit writes are
+// translated to control parameters and passed to control.
+func NewAccessory(d cloudcontrol.GroupDevice, control func(pt.DeviceControlParameters) error) *Accessory {
+	name := d.DeviceName
+	if name == "" {
+		name = d.DeviceGUID
+	}
+	a := &Accessory{
+		A: accessory.New(accessory.Info{
+			Name:         name,
+			SerialNumber: d.DeviceGUID,
+			Manufacturer: "Panasonic",
+			Model:        d.DeviceModuleNumber,
+		}, accessory.TypeAirConditioner),
+		HeaterCooler:  service.NewHeaterCooler(),
+		Heating:       characteristic.NewHeatingThresholdTemperature(),
+		Cooling:       characteristic.NewCoolingThresholdTemperature(),
+		RotationSpeed: characteristic.NewRotationSpeed(),
+		SwingMode:     characteristic.NewSwingMode(),
+		DeviceGUID:    d.DeviceGUID,
+	}
+	a.Id = accessoryID(d.DeviceGUID)
+
+	for _, c := range []*characteristic.Float{a.Heating.Float, a.Cooling.Float} {
+		c.SetMinValue(MinTemperature)
+		c.SetMaxValue(MaxTemperature)
+		c.SetStepValue(TemperatureStep)
+	}
+	a.RotationSpeed.SetMaxValue(RotationSpeedAuto)
+	a.RotationSpeed.Unit = ""
+	a.HeaterCooler.AddC(a.Heating.C)
+	a.HeaterCooler.AddC(a.Cooling.C)
+	a.HeaterCooler.AddC(a.RotationSpeed.C)
+	a.HeaterCooler.AddC(a.SwingMode.C)
+	a.AddS(a.HeaterCooler.S)
+
+	a.HeaterCooler.Active.OnSetRemoteValue(func(v int) error {
+		return control(pt.DeviceControlParameters{Operate: &v})
+	})
+	a.HeaterCooler.TargetHeaterCoolerState.OnSetRemoteValue(func(v int) error {
+		mode := pt.Modes["auto"]
+		switch v {
+		case characteristic.TargetHeaterCoolerStateHeat:
+			mode = pt.Modes["heat"]
+		case characteristic.TargetHeaterCoolerStateCool:
+			mode = pt.Modes["cool"]
+		}
+		return control(pt.DeviceControlParameters{OperationMode: &mode})
+	})
+	setTemperature := func(v float64) error {
+		return control(pt.DeviceControlParameters{TemperatureSet: &v})
+	}
+	a.Heating.OnSetRemoteValue(setTemperature)
+	a.Cooling.OnSetRemoteValue(setTemperature)
+	a.RotationSpeed.OnSetRemoteValue(func(v float64) error {
+		speed := int(math.Round(v))
+		switch {
+		case speed <= 0:
+			return nil
+		case speed >= RotationSpeedAuto:
+			speed = pt.FanSpeeds["auto"]
+		}
+		return control(pt.DeviceControlParameters{FanSpeed: &speed})
+	})
+	a.SwingMode.OnSetRemoteValue(func(v int) error {
+		mode := pt.FanAutoMode["disabled"]
+		if v == characteristic.SwingModeSwingEnabled {
+			mode = pt.FanAutoMode["both"]
+		}
+		return control(pt.DeviceControlParameters{FanAutoMode: &mode})
+	})
+
+	return a
+}
+
+// Update sets the characteristics from the device parameters.
+func (a *Accessory) Update(p pt.DeviceParameters) {
+	hc := a.HeaterCooler
+	hc.Active.SetValue(p.Operate)
+	hc.CurrentTemperature.SetValue(p.InsideTemperature)
+	a.Heating.SetValue(p.TemperatureSet)
+	a.Cooling.SetValue(p.TemperatureSet)
+
+	target := characteristic.TargetHeaterCoolerStateAuto
+	current := characteristic.CurrentHeaterCoolerStateIdle
+	switch p.OperationMode {
+	case pt.Modes["heat"]:
+		target = characteristic.TargetHeaterCoolerStateHeat
+		current = characteristic.CurrentHeaterCoolerStateHeating
+	case pt.Modes["cool"]:
+		target = characteristic.TargetHeaterCoolerStateCool
+		current = characteristic.CurrentHeaterCoolerStateCooling
+	case pt.Modes["auto"]:
+		if p.InsideTemperature < p.TemperatureSet {
+			current = characteristic.CurrentHeaterCoolerStateHeating
+		} else if p.InsideTemperature > p.TemperatureSet {
+			current = characteristic.CurrentHeaterCoolerStateCooling
+		}
+	}
+	if p.Operate == 0 {
+		current = characteristic.CurrentHeaterCoolerStateInactive
+	}
+	hc.TargetHeaterCoolerState.SetValue(target)
+	hc.CurrentHeaterCoolerState.SetValue(current)
+
+	speed := float64(p.FanSpeed)
+	if p.FanSpeed == pt.FanSpeeds["auto"] {
+		speed = RotationSpeedAuto
+	}
+	a.RotationSpeed.SetValue(speed)
+
+	swing := characteristic.SwingModeSwingDisabled
+	if p.FanAutoMode == pt.FanAutoMode["both"] || p.FanAutoMode == pt.FanAutoMode["ud"] {
+		swing = characteristic.SwingModeSwingEnabled
+	}
+	a.SwingMode.SetValue(swing)
+}
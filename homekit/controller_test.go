@@ -0,0 +1,223 @@
+package homekit_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/brutella/hap/chacha20poly1305"
+	"github.com/brutella/hap/curve25519"
+	"github.com/brutella/hap/hkdf"
+	"github.com/brutella/hap/tlv8"
+	"github.com/tadglines/go-pkgs/crypto/srp"
+)
+
+// controller is a minimal HomeKit controller, it does what an Apple
+// device does to pair with and control an accessory.
+type controller struct {
+	id         string
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+
+	addr   string
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newController() *controller {
+	public, private, _ := ed25519.GenerateKey(nil)
+	return &controller{id: "test-controller", publicKey: public, privateKey: private}
+}
+
+// tlv is the union of the TLV8 fields used in pairing messages.
+type tlv struct {
+	Method        byte   `tlv8:"0"`
+	Identifier    string `tlv8:"1"`
+	Salt          []byte `tlv8:"2"`
+	PublicKey     []byte `tlv8:"3"`
+	Proof         []byte `tlv8:"4"`
+	EncryptedData []byte `tlv8:"5"`
+	State         byte   `tlv8:"6"`
+	Error         byte   `tlv8:"7"`
+	Signature     []byte `tlv8:"10"`
+}
+
+// connect opens a new connection to the accessory.
+func (c *controller) connect(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	c.addr, c.conn, c.reader = addr, conn, bufio.NewReader(conn)
+	return nil
+}
+
+func (c *controller) close() {
+	c.conn.Close()
+}
+
+// do sends a request on the connection and returns the response body.
+func (c *controller) do(method, path, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, "http://"+c.addr+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if err := req.Write(c.conn); err != nil {
+		return 0, nil, err
+	}
+	resp, err := http.ReadResponse(c.reader, req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, b, err
+}
+
+// exchange sends a pairing message and decodes the response.
+func (c *controller) exchange(path string, msg interface{}) (tlv, error) {
+	b, err := tlv8.Marshal(msg)
+	if err != nil {
+		return tlv{}, err
+	}
+	_, body, err := c.do(http.MethodPost, path, "application/pairing+tlv8", b)
+	if err != nil {
+		return tlv{}, err
+	}
+	resp := tlv{}
+	if err := tlv8.Unmarshal(body, &resp); err != nil {
+		return resp, err
+	}
+	if resp.Error != 0 {
+		return resp, fmt.Errorf("%s state %d: error %d", path, resp.State, resp.Error)
+	}
+	return resp, nil
+}
+
+// pair runs pair-setup with the given pin (XXX-XX-XXX).
+func (c *controller) pair(pin string) error {
+	m2, err := c.exchange("/pair-setup", struct {
+		Method byte `tlv8:"0"`
+		State  byte `tlv8:"6"`
+	}{0, 1})
+	if err != nil {
+		return err
+	}
+
+	s, err := srp.NewSRP("rfc5054.3072", sha512.New, func(salt, pin []byte) []byte {
+		h := sha512.New()
+		h.Write([]byte("Pair-Setup:"))
+		h.Write(pin)
+		t := h.Sum(nil)
+		h.Reset()
+		h.Write(salt)
+		h.Write(t)
+		return h.Sum(nil)
+	})
+	if err != nil {
+		return err
+	}
+	session := s.NewClientSession([]byte("Pair-Setup"), []byte(pin))
+	key, err := session.ComputeKey(m2.Salt, m2.PublicKey)
+	if err != nil {
+		return err
+	}
+	m4, err := c.exchange("/pair-setup", struct {
+		Method    byte   `tlv8:"0"`
+		PublicKey []byte `tlv8:"3"`
+		Proof     []byte `tlv8:"4"`
+		State     byte   `tlv8:"6"`
+	}{0, session.GetA(), session.ComputeAuthenticator(), 3})
+	if err != nil {
+		return err
+	}
+	if !session.VerifyServerAuthenticator(m4.Proof) {
+		return fmt.Errorf("invalid accessory proof")
+	}
+
+	sign, _ := hkdf.Sha512(key, []byte("Pair-Setup-Controller-Sign-Salt"), []byte("Pair-Setup-Controller-Sign-Info"))
+	data := append(append(sign[:], c.id...), c.publicKey...)
+	sub, err := tlv8.Marshal(struct {
+		Identifier string `tlv8:"1"`
+		PublicKey  []byte `tlv8:"3"`
+		Signature  []byte `tlv8:"10"`
+	}{c.id, c.publicKey, ed25519.Sign(c.privateKey, data)})
+	if err != nil {
+		return err
+	}
+	encKey, _ := hkdf.Sha512(key, []byte("Pair-Setup-Encrypt-Salt"), []byte("Pair-Setup-Encrypt-Info"))
+	encrypted, mac, _ := chacha20poly1305.EncryptAndSeal(encKey[:], []byte("PS-Msg05"), sub, nil)
+	_, err = c.exchange("/pair-setup", struct {
+		Method        byte   `tlv8:"0"`
+		EncryptedData []byte `tlv8:"5"`
+		State         byte   `tlv8:"6"`
+	}{0, append(encrypted, mac[:]...), 5})
+	return err
+}
+
+// verify runs pair-verify. The test server does not encrypt the
+// session, requests after it stay in plain text.
+func (c *controller) verify() error {
+	public, private := curve25519.GenerateKeyPair()
+	m2, err := c.exchange("/pair-verify", struct {
+		Method    byte   `tlv8:"0"`
+		PublicKey []byte `tlv8:"3"`
+		State     byte   `tlv8:"6"`
+	}{0, public[:], 1})
+	if err != nil {
+		return err
+	}
+
+	var accessoryKey [32]byte
+	copy(accessoryKey[:], m2.PublicKey)
+	shared := curve25519.SharedSecret(private, accessoryKey)
+	encKey, _ := hkdf.Sha512(shared[:], []byte("Pair-Verify-Encrypt-Salt"), []byte("Pair-Verify-Encrypt-Info"))
+
+	data := append(append(public[:], c.id...), accessoryKey[:]...)
+	sub, err := tlv8.Marshal(struct {
+		Identifier string `tlv8:"1"`
+		Signature  []byte `tlv8:"10"`
+	}{c.id, ed25519.Sign(c.privateKey, data)})
+	if err != nil {
+		return err
+	}
+	encrypted, mac, _ := chacha20poly1305.EncryptAndSeal(encKey[:], []byte("PV-Msg03"), sub, nil)
+	if _, err := c.exchange("/pair-verify", struct {
+		Method        byte   `tlv8:"0"`
+		EncryptedData []byte `tlv8:"5"`
+		State         byte   `tlv8:"6"`
+	}{0, append(encrypted, mac[:]...), 3}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getJSON gets a resource after pair-verify.
+func (c *controller) getJSON(path string, v interface{}) error {
+	code, body, err := c.do(http.MethodGet, path, "", nil)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d: %s", path, code, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// putJSON writes a resource after pair-verify.
+func (c *controller) putJSON(path string, v interface{}) (int, []byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, nil, err
+	}
+	return c.do(http.MethodPut, path, "application/hap+json", b)
+}
@@ -0,0 +1,207 @@
+// Package homekit exposes Panasonic Comfort Cloud devices as HomeKit
+// HeaterCooler accessories through a HomeKit Accessory Protocol bridge.
+package homekit
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brutella/hap"
+	"github.com/brutella/hap/accessory"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultName     = "Panasonic"
+	DefaultInterval = time.Minute
+)
+
+// pinKey is the store key of the generated pairing code
+const pinKey = "pin"
+
+// Bridge is a HomeKit bridge with an accessory for every device.
+type Bridge struct {
+	Client *cloudcontrol.Client
+	// Store persists the pairing data and keys of the bridge
+	Store    hap.Store
+	Name     string
+	Pin      string // Pairing code, generated and kept in the store when empty
+	Addr     string // Listen address, a random port when empty
+	Interval time.Duration
+	Parallel int
+
+	mu          sync.Mutex
+	accessories map[string]*Accessory
+}
+
+// New creates a Bridge with the default name and interval.
+func New(client *cloudcontrol.Client, store hap.Store) *Bridge {
+	return &Bridge{
+		Client:      client,
+		Store:       store,
+		Name:        DefaultName,
+		Interval:    DefaultInterval,
+		Parallel:    cloudcontrol.DefaultParallelism,
+		accessories: map[string]*Accessory{},
+	}
+}
+
+// ValidatePin checks that a pairing code has 8 digits and is not one
+// of the trivial codes HomeKit refuses.
+func ValidatePin(pin string) error {
+	if len(pin) != 8 || strings.Trim(pin, "0123456789") != "" {
+		return fmt.Errorf("error: pairing code must be 8 digits")
+	}
+	if hap.InvalidPins[pin] {
+		return fmt.Errorf("error: pairing code %s is too easy to guess", pin)
+	}
+	return nil
+}
+
+// FormatPin formats a pairing code as shown in the Home app, XXX-XX-XXX.
+func FormatPin(pin string) string {
+	return pin[:3] + "-" + pin[3:5] + "-" + pin[5:]
+}
+
+// StoredPin returns the pairing code kept in the store. A random code is
+// generated and saved when there is none, created reports that.
+func StoredPin(store hap.Store) (pin string, created bool, err error) {
+	if b, err := store.Get(pinKey); err == nil && ValidatePin(string(b)) == nil {
+		return string(b), false, nil
+	}
+	for pin == "" || hap.InvalidPins[pin] {
+		n, err := rand.Int(rand.Reader, big.NewInt(100000000))
+		if err != nil {
+			return "", false, fmt.Errorf("error: generating pairing code: %w", err)
+		}
+		pin = fmt.Sprintf("%08d", n)
+	}
+	if err := store.Set(pinKey, []byte(pin)); err != nil {
+		return "", false, fmt.Errorf("error: saving pairing code: %w", err)
+	}
+	return pin, true, nil
+}
+
+// control returns the function sending control parameters to a device.
+func (b *Bridge) control(deviceGUID string) func(pt.DeviceControlParameters) error {
+	return func(parameters pt.DeviceControlParameters) error {
+		client := *b.Client
+		client.SetDevice(deviceGUID)
+		if _, err := client.SetState(parameters); err != nil {
+			log.Errorf("HomeKit command for %s failed: %v", deviceGUID, err)
+			return err
+		}
+		log.Debugf("HomeKit command sent to %s", deviceGUID)
+		return nil
+	}
+}
+
+// Server creates the accessories of all devices and the HAP server
+// serving them. A generated pairing code is logged when it is created.
+func (b *Bridge) Server() (*hap.Server, error) {
+	pin := b.Pin
+	if pin == "" {
+		stored, created, err := StoredPin(b.Store)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			log.Infof("Generated HomeKit pairing code %s, it is kept in the store", FormatPin(stored))
+		}
+		pin = stored
+	}
+	if err := ValidatePin(pin); err != nil {
+		return nil, err
+	}
+
+	devices, err := b.Client.ListGroupDevices()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].DeviceGUID < devices[j].DeviceGUID })
+
+	bridge := accessory.NewBridge(accessory.Info{Name: b.Name, Manufacturer: "go-panasonic"})
+	accessories := []*accessory.A{}
+	b.mu.Lock()
+	for _, d := range devices {
+		a := NewAccessory(d, b.control(d.DeviceGUID))
+		b.accessories[d.DeviceGUID] = a
+		accessories = append(accessories, a.A)
+	}
+	b.mu.Unlock()
+
+	server, err := hap.NewServer(b.Store, bridge.A, accessories...)
+	if err != nil {
+		return nil, fmt.Errorf("error: creating HomeKit bridge: %w", err)
+	}
+	server.Pin = pin
+	server.Addr = b.Addr
+
+	return server, nil
+}
+
+// Poll refreshes the characteristics of all accessories.
+func (b *Bridge) Poll() error {
+	b.mu.Lock()
+	guids := []string{}
+	for guid := range b.accessories {
+		guids = append(guids, guid)
+	}
+	b.mu.Unlock()
+
+	var firstErr error
+	for _, r := range b.Client.EachStatus(guids, b.Parallel) {
+		if r.Err != nil {
+			if firstErr == nil {
+				firstErr = r.Err
+			}
+			continue
+		}
+		b.mu.Lock()
+		b.accessories[r.DeviceGUID].Update(r.Status.Parameters)
+		b.mu.Unlock()
+	}
+
+	return firstErr
+}
+
+// Run serves the bridge and polls the devices at the bridge interval
+// until the context is cancelled.
+func (b *Bridge) Run(ctx context.Context) error {
+	server, err := b.Server()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(b.Interval)
+		defer ticker.Stop()
+		for {
+			if err := b.Poll(); err != nil {
+				log.Errorf("Polling devices failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	if err := server.ListenAndServe(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package homekit_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/brutella/hap"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/homekit"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// cloud is a fake Panasonic cloud with a single cooling device.
+type cloud struct {
+	mu       sync.Mutex
+	commands []pt.Command
+}
+
+func (c *cloud) handler() http.Handler {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"device1","deviceName":"Living","deviceModuleNumber":"CS-Z25"}]}]}`))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"deviceGuid":"device1","deviceName":"Living","parameters":{"online":true,"operate":1,"operationMode":2,"fanSpeed":0,"fanAutoMode":1,"temperatureSet":22,"insideTemperature":24}}`))
+	})
+	handler.HandleFunc(pt.URLControl, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		command := pt.Command{}
+		_ = json.Unmarshal(body, &command)
+		c.mu.Lock()
+		c.commands = append(c.commands, command)
+		c.mu.Unlock()
+		_, _ = w.Write([]byte(pt.SuccessResponse))
+	})
+	return handler
+}
+
+// serve serves the HTTP handler of a bridge on a local test server and
+// returns its address. Unlike ListenAndServe, the bridge is not announced
+// over mDNS and sessions are not encrypted after pair-verify. The key
+// pair stands in for the one hap keeps in the store.
+func serve(t *testing.T, client *cloudcontrol.Client, store hap.Store, key hap.KeyPair) string {
+	b := homekit.New(client, store)
+	b.Pin = "12344321"
+	server, err := b.Server()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Key = key
+	if err := b.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(server.ServeMux().(http.Handler))
+	t.Cleanup(ts.Close)
+	return ts.Listener.Addr().String()
+}
+
+// keyPair generates a bridge key pair.
+func keyPair(t *testing.T) hap.KeyPair {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hap.KeyPair{Public: public, Private: private}
+}
+
+type accessories struct {
+	Accessories []struct {
+		ID       uint64 `json:"aid"`
+		Services []struct {
+			Type            string `json:"type"`
+			Characteristics []struct {
+				ID    uint64      `json:"iid"`
+				Type  string      `json:"type"`
+				Value interface{} `json:"value"`
+			} `json:"characteristics"`
+		} `json:"services"`
+	} `json:"accessories"`
+}
+
+// heaterCooler returns the accessory id and the characteristic values
+// and ids by type of the HeaterCooler service.
+func (a accessories) heaterCooler() (uint64, map[string]interface{}, map[string]uint64) {
+	for _, acc := range a.Accessories {
+		for _, s := range acc.Services {
+			if s.Type != "BC" {
+				continue
+			}
+			values, ids := map[string]interface{}{}, map[string]uint64{}
+			for _, c := range s.Characteristics {
+				values[c.Type] = c.Value
+				ids[c.Type] = c.ID
+			}
+			return acc.ID, values, ids
+		}
+	}
+	return 0, nil, nil
+}
+
+func TestBridge(t *testing.T) {
+	c := &cloud{}
+	ts := httptest.NewServer(c.handler())
+	defer ts.Close()
+	client := cloudcontrol.NewClient(ts.URL)
+	store := hap.NewFsStore(t.TempDir())
+	addr := serve(t, &client, store, keyPair(t))
+
+	ctrl := newController()
+	if err := ctrl.connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer ctrl.close()
+	if err := ctrl.pair("123-44-321"); err != nil {
+		t.Fatalf("pair-setup: %v", err)
+	}
+	if err := ctrl.verify(); err != nil {
+		t.Fatalf("pair-verify: %v", err)
+	}
+
+	got := accessories{}
+	if err := ctrl.getJSON("/accessories", &got); err != nil {
+		t.Fatal(err)
+	}
+	aid, values, ids := got.heaterCooler()
+	if values == nil {
+		t.Fatalf("no HeaterCooler service in %+v", got)
+	}
+	wantValues := map[string]interface{}{
+		"B0": 1.0,  // Active
+		"B1": 3.0,  // CurrentHeaterCoolerState cooling
+		"B2": 2.0,  // TargetHeaterCoolerState cool
+		"11": 24.0, // CurrentTemperature
+		"12": 22.0, // HeatingThresholdTemperature
+		"D":  22.0, // CoolingThresholdTemperature
+		"29": 6.0,  // RotationSpeed auto
+		"B6": 0.0,  // SwingMode
+	}
+	for typ, want := range wantValues {
+		if diff := cmp.Diff(want, values[typ]); diff != "" {
+			t.Errorf("characteristic %s mismatch (-want +got):\n%s", typ, diff)
+		}
+	}
+
+	write := map[string]interface{}{
+		"characteristics": []map[string]interface{}{
+			{"aid": aid, "iid": ids["B2"], "value": 1},
+			{"aid": aid, "iid": ids["12"], "value": 23.5},
+			{"aid": aid, "iid": ids["29"], "value": 2},
+			{"aid": aid, "iid": ids["B6"], "value": 1},
+		},
+	}
+	code, body, err := ctrl.putJSON("/characteristics", write)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusNoContent {
+		t.Fatalf("PUT /characteristics: status %d: %s", code, body)
+	}
+	heat, temperature, speed, swing := pt.Modes["heat"], 23.5, pt.FanSpeeds["lowMid"], pt.FanAutoMode["both"]
+	wantCommands := []pt.Command{
+		{DeviceGUID: "device1", Parameters: pt.DeviceControlParameters{OperationMode: &heat}},
+		{DeviceGUID: "device1", Parameters: pt.DeviceControlParameters{TemperatureSet: &temperature}},
+		{DeviceGUID: "device1", Parameters: pt.DeviceControlParameters{FanSpeed: &speed}},
+		{DeviceGUID: "device1", Parameters: pt.DeviceControlParameters{FanAutoMode: &swing}},
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if diff := cmp.Diff(wantCommands, c.commands); diff != "" {
+		t.Errorf("commands mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPairingPersists(t *testing.T) {
+	ts := httptest.NewServer((&cloud{}).handler())
+	defer ts.Close()
+	client := cloudcontrol.NewClient(ts.URL)
+	dir := t.TempDir()
+	key := keyPair(t)
+
+	ctrl := newController()
+	addr := serve(t, &client, hap.NewFsStore(dir), key)
+	if err := ctrl.connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.pair("123-44-321"); err != nil {
+		t.Fatalf("pair-setup: %v", err)
+	}
+	ctrl.close()
+
+	// A new bridge on the same store knows the controller and its own
+	// keys, and refuses to pair again.
+	addr = serve(t, &client, hap.NewFsStore(dir), key)
+	if err := ctrl.connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer ctrl.close()
+	if err := ctrl.verify(); err != nil {
+		t.Fatalf("pair-verify after restart: %v", err)
+	}
+	got := accessories{}
+	if err := ctrl.getJSON("/accessories", &got); err != nil {
+		t.Fatal(err)
+	}
+
+	other := newController()
+	if err := other.connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer other.close()
+	if err := other.pair("123-44-321"); err == nil {
+		t.Error("pair-setup of a paired bridge succeeded, want error")
+	}
+}
+
+func TestStoredPin(t *testing.T) {
+	store := hap.NewMemStore()
+	pin, created, err := homekit.StoredPin(store)
+	if err != nil || !created {
+		t.Fatalf("StoredPin() = %q, %v, %v, want a new code", pin, created, err)
+	}
+	if err := homekit.ValidatePin(pin); err != nil {
+		t.Errorf("StoredPin() generated %q: %v", pin, err)
+	}
+	again, created, err := homekit.StoredPin(store)
+	if err != nil || created || again != pin {
+		t.Errorf("StoredPin() = %q, %v, %v, want %q from the store", again, created, err, pin)
+	}
+
+	for _, pin := range []string{"", "1234", "1234567a", "00000000", "12345678", "87654321"} {
+		if err := homekit.ValidatePin(pin); err == nil {
+			t.Errorf("ValidatePin(%q) succeeded, want error", pin)
+		}
+	}
+}
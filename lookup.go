@@ -0,0 +1,94 @@
+package cloudcontrol
+
+import (
+	"fmt"
+	"strings"
+
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// AmbiguousDeviceError is returned when a device lookup
+// matches more than one device.
+type AmbiguousDeviceError struct {
+	Query      string
+	Candidates []pt.Device
+}
+
+func (e *AmbiguousDeviceError) Error() string {
+	names := []string{}
+	for _, d := range e.Candidates {
+		names = append(names, fmt.Sprintf("%s (%s)", d.DeviceName, d.DeviceGUID))
+	}
+	return fmt.Sprintf("error: %q matches multiple devices: %s", e.Query, strings.Join(names, ", "))
+}
+
+// SetAliases sets user-defined device aliases on the client. Aliases
+// map a name to a device GUID, device name or module number and are
+// matched case-insensitively.
+func (c *Client) SetAliases(aliases map[string]string) {
+	c.Aliases = map[string]string{}
+	for alias, target := range aliases {
+		c.Aliases[strings.ToLower(alias)] = target
+	}
+}
+
+// matchDevice finds a device by GUID, name, module number or partial
+// name, in that order of preference.
+func matchDevice(devices []pt.Device, query string) (pt.Device, error) {
+	lower := strings.ToLower(query)
+	matchers := []func(d pt.Device) bool{
+		func(d pt.Device) bool { return d.DeviceGUID == query },
+		func(d pt.Device) bool { return strings.ToLower(d.DeviceName) == lower },
+		func(d pt.Device) bool { return strings.EqualFold(d.DeviceModuleNumber, query) },
+		func(d pt.Device) bool { return strings.Contains(strings.ToLower(d.DeviceName), lower) },
+	}
+
+	for _, match := range matchers {
+		found := []pt.Device{}
+		for _, d := range devices {
+			if match(d) {
+				found = append(found, d)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], nil
+		}
+		if len(found) > 1 {
+			return pt.Device{}, &AmbiguousDeviceError{Query: query, Candidates: found}
+		}
+	}
+
+	return pt.Device{}, fmt.Errorf("error: device %q not found", query)
+}
+
+// FindDevice looks up a device by alias, GUID, exact name, module
+// number or partial name. An *AmbiguousDeviceError listing the
+// candidates is returned when the query matches several devices.
+func (c *Client) FindDevice(query string) (pt.Device, error) {
+	groups, err := c.GetGroups()
+	if err != nil {
+		return pt.Device{}, err
+	}
+	devices := []pt.Device{}
+	for _, group := range groups.Groups {
+		devices = append(devices, group.Devices...)
+	}
+
+	if target, ok := c.Aliases[strings.ToLower(query)]; ok {
+		query = target
+	}
+
+	return matchDevice(devices, query)
+}
+
+// SetDeviceByName looks up a device with FindDevice and sets it
+// as the device on the client.
+func (c *Client) SetDeviceByName(query string) error {
+	device, err := c.FindDevice(query)
+	if err != nil {
+		return err
+	}
+	c.SetDevice(device.DeviceGUID)
+
+	return nil
+}
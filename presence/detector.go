@@ -0,0 +1,268 @@
+package presence
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is whether somebody is home.
+type State string
+
+// States, Unknown leaves the devices as they are
+const (
+	Unknown State = ""
+	Home    State = "home"
+	Away    State = "away"
+)
+
+// Detector types
+const (
+	Webhook = "webhook"
+	Command = "command"
+	File    = "file"
+)
+
+// maxBodySize is the largest webhook body accepted
+const maxBodySize = 4 << 10
+
+// DefaultCommand pings a host once, {host} is replaced by the host.
+var DefaultCommand = []string{"ping", "-c", "1", "-W", "1", "{host}"}
+
+// DefaultTimeout is the time a command may run for a host.
+const DefaultTimeout = 10 * time.Second
+
+// Detector detects whether somebody is home.
+type Detector interface {
+	Detect(ctx context.Context) (State, error)
+}
+
+// DetectorConfig configures a Detector.
+type DetectorConfig struct {
+	Name string `json:"name"`
+	// Type is webhook, command or file. File detectors are overrides:
+	// home or away in the file wins over all other detectors.
+	Type string `json:"type"`
+	// Token is required from webhook callers, as bearer token or token
+	// query parameter
+	Token string `json:"token"`
+	// MaxAge is the age after which a webhook state is unknown, never
+	// when 0
+	MaxAge time.Duration `json:"maxAge"`
+	// Command is run for every host with {host} replaced, somebody is
+	// home when it succeeds for any host. It pings the hosts when empty.
+	Command []string `json:"command"`
+	Hosts   []string `json:"hosts"`
+	// Timeout is the time the command may run for a host, DefaultTimeout
+	// when 0
+	Timeout time.Duration `json:"timeout"`
+	// Path is the override file
+	Path string `json:"path"`
+}
+
+// Detector creates the Detector.
+func (dc DetectorConfig) Detector() (Detector, error) {
+	switch dc.Type {
+	case Webhook:
+		if dc.Token == "" {
+			return nil, fmt.Errorf("error: presence detector %q needs a token", dc.Name)
+		}
+		return &WebhookDetector{Token: dc.Token, MaxAge: dc.MaxAge}, nil
+	case Command:
+		command := dc.Command
+		if len(command) == 0 {
+			command = DefaultCommand
+		}
+		if len(dc.Hosts) == 0 && strings.Contains(strings.Join(command, " "), "{host}") {
+			return nil, fmt.Errorf("error: presence detector %q needs hosts", dc.Name)
+		}
+		timeout := dc.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		return &CommandDetector{Command: command, Hosts: dc.Hosts, Timeout: timeout}, nil
+	case File:
+		if dc.Path == "" {
+			return nil, fmt.Errorf("error: presence detector %q needs a path", dc.Name)
+		}
+		return &FileDetector{Path: dc.Path}, nil
+	}
+	return nil, fmt.Errorf("error: presence detector %q has unknown type %q", dc.Name, dc.Type)
+}
+
+// parseState parses home or away, anything else is unknown.
+func parseState(s string) State {
+	switch State(strings.ToLower(strings.TrimSpace(s))) {
+	case Home:
+		return Home
+	case Away:
+		return Away
+	}
+	return Unknown
+}
+
+// WebhookDetector keeps the state posted to it, eg by an automation on
+// a phone entering or leaving home.
+type WebhookDetector struct {
+	Token  string
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	state   State
+	updated time.Time
+}
+
+// Detect implements Detector.
+func (w *WebhookDetector) Detect(context.Context) (State, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.MaxAge > 0 && time.Since(w.updated) > w.MaxAge {
+		return Unknown, nil
+	}
+	return w.state, nil
+}
+
+// authorized reports whether the request has the token as bearer token
+// or token query parameter. Requests are refused without a token.
+func (w *WebhookDetector) authorized(r *http.Request) bool {
+	if w.Token == "" {
+		return false
+	}
+	token := []byte(w.Token)
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(bearer), token) == 1 ||
+		subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), token) == 1
+}
+
+// ServeHTTP sets the state from a POST with a state query parameter or
+// a JSON body {"state": "home"} or {"present": true}. GET returns the
+// state.
+func (w *WebhookDetector) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !w.authorized(r) {
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		state, _ := w.Detect(r.Context())
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]State{"state": state})
+	case http.MethodPost, http.MethodPut:
+		state := parseState(r.URL.Query().Get("state"))
+		if state == Unknown {
+			body := struct {
+				State   string `json:"state"`
+				Present *bool  `json:"present"`
+			}{}
+			data, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, maxBodySize))
+			if err != nil {
+				http.Error(rw, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if json.Unmarshal(data, &body) == nil {
+				state = parseState(body.State)
+				if body.Present != nil && *body.Present {
+					state = Home
+				} else if body.Present != nil {
+					state = Away
+				}
+			}
+		}
+		if state == Unknown {
+			http.Error(rw, "state must be home or away", http.StatusBadRequest)
+			return
+		}
+		w.mu.Lock()
+		w.state, w.updated = state, time.Now()
+		w.mu.Unlock()
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CommandDetector runs a command for known hosts on the LAN, eg a ping
+// or an ARP lookup of phones.
+type CommandDetector struct {
+	Command []string
+	Hosts   []string
+	Timeout time.Duration // Unlimited when 0
+}
+
+// Detect implements Detector. A host is present when the command exits
+// with 0 and absent when it exits with another code. When the command
+// times out for a host and no other host is present, the state is
+// unknown.
+func (c *CommandDetector) Detect(ctx context.Context) (State, error) {
+	hosts := c.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	var timedOut error
+	for _, host := range hosts {
+		args := make([]string, len(c.Command))
+		for i, arg := range c.Command {
+			args[i] = strings.ReplaceAll(arg, "{host}", host)
+		}
+		err := c.run(ctx, args)
+		if err == nil {
+			return Home, nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			if timedOut == nil {
+				timedOut = err
+			}
+			continue
+		}
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) || ctx.Err() != nil {
+			return Unknown, fmt.Errorf("error: running %s: %w", args[0], err)
+		}
+	}
+	if timedOut != nil {
+		return Unknown, timedOut
+	}
+	return Away, nil
+}
+
+// run runs the command within the timeout.
+func (c *CommandDetector) run(ctx context.Context, args []string) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	err := exec.CommandContext(ctx, args[0], args[1:]...).Run()
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("error: running %s: %w", args[0], ctx.Err())
+	}
+	return err
+}
+
+// FileDetector reads home or away from a file, any other content or a
+// missing file is unknown.
+type FileDetector struct {
+	Path string
+}
+
+// Detect implements Detector.
+func (f *FileDetector) Detect(context.Context) (State, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Unknown, nil
+	}
+	if err != nil {
+		return Unknown, fmt.Errorf("error: reading %s: %w", f.Path, err)
+	}
+	return parseState(string(data)), nil
+}
@@ -0,0 +1,261 @@
+// Package presence switches Panasonic devices between comfort and eco
+// states depending on whether somebody is home. Presence comes from
+// detectors such as webhooks called by phones, pings of known hosts on
+// the LAN and a manual override file.
+package presence
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultAwayAfter = 15 * time.Minute
+	DefaultInterval  = time.Minute
+)
+
+// Profile are the states of devices when somebody is home and away.
+type Profile struct {
+	Name string `json:"name"`
+	// Devices are GUIDs, names, aliases or group names
+	Devices []string  `json:"devices"`
+	Comfort api.State `json:"comfort"`
+	Eco     api.State `json:"eco"`
+	// AwayAfter and HomeAfter are the grace periods nobody or somebody
+	// must be home before the devices switch
+	AwayAfter time.Duration `json:"awayAfter"`
+	HomeAfter time.Duration `json:"homeAfter"`
+}
+
+// Config is the presence configuration.
+type Config struct {
+	Interval  time.Duration    `json:"interval"`
+	Detectors []DetectorConfig `json:"detectors"`
+	Profiles  []Profile        `json:"profiles"`
+}
+
+// Reading is the state of a detector.
+type Reading struct {
+	Detector string
+	State    State
+	Err      error
+}
+
+// Result is the outcome of switching a device.
+type Result struct {
+	Profile    string
+	DeviceGUID string
+	State      State
+	Err        error
+}
+
+// detector is a named Detector.
+type detector struct {
+	Detector
+	name     string
+	override bool
+}
+
+// profile is a Profile with its devices resolved and its state.
+type profile struct {
+	Profile
+	devices      []string
+	comfort, eco pt.DeviceControlParameters
+	applied      State
+	pending      State
+	pendingSince time.Time
+}
+
+// Presence switches profiles on the detected presence.
+type Presence struct {
+	Config
+	Now func() time.Time
+
+	client    *cloudcontrol.Client
+	detectors []detector
+	webhooks  map[string]*WebhookDetector
+	mu        sync.Mutex
+	profiles  []*profile
+}
+
+// New creates a Presence with the detectors of the config. Devices of
+// the profiles are resolved once.
+func New(client *cloudcontrol.Client, config Config) (*Presence, error) {
+	if config.Interval < 0 {
+		return nil, fmt.Errorf("error: presence interval must be positive")
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+	if len(config.Detectors) == 0 || len(config.Profiles) == 0 {
+		return nil, fmt.Errorf("error: presence needs detectors and profiles")
+	}
+
+	p := &Presence{Config: config, Now: time.Now, client: client, webhooks: map[string]*WebhookDetector{}}
+	seen := map[string]bool{}
+	for _, dc := range config.Detectors {
+		if dc.Name == "" || seen[dc.Name] {
+			return nil, fmt.Errorf("error: presence detector name %q is empty or used more than once", dc.Name)
+		}
+		seen[dc.Name] = true
+		d, err := dc.Detector()
+		if err != nil {
+			return nil, err
+		}
+		if w, ok := d.(*WebhookDetector); ok {
+			p.webhooks[dc.Name] = w
+		}
+		p.detectors = append(p.detectors, detector{Detector: d, name: dc.Name, override: dc.Type == File})
+	}
+
+	for i, pc := range config.Profiles {
+		if pc.Name == "" {
+			pc.Name = fmt.Sprintf("profile %d", i+1)
+		}
+		if pc.AwayAfter == 0 {
+			pc.AwayAfter = DefaultAwayAfter
+		}
+		if pc.AwayAfter < 0 || pc.HomeAfter < 0 {
+			return nil, fmt.Errorf("error: presence profile %q has a negative grace period", pc.Name)
+		}
+		compiled := &profile{Profile: pc}
+		var err error
+		if compiled.comfort, err = pc.Comfort.Parameters(); err != nil {
+			return nil, fmt.Errorf("error: presence profile %q comfort: %w", pc.Name, err)
+		}
+		if compiled.eco, err = pc.Eco.Parameters(); err != nil {
+			return nil, fmt.Errorf("error: presence profile %q eco: %w", pc.Name, err)
+		}
+		if len(pc.Devices) == 0 {
+			return nil, fmt.Errorf("error: presence profile %q has no devices", pc.Name)
+		}
+		for _, device := range pc.Devices {
+			guids, err := client.ResolveDevices(device)
+			if err != nil {
+				return nil, fmt.Errorf("error: presence profile %q: %w", pc.Name, err)
+			}
+			compiled.devices = append(compiled.devices, guids...)
+		}
+		p.profiles = append(p.profiles, compiled)
+	}
+
+	return p, nil
+}
+
+// Handler serves the webhook detectors on /presence/<name>.
+func (p *Presence) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for name, w := range p.webhooks {
+		mux.Handle("/presence/"+name, w)
+	}
+	return mux
+}
+
+// Webhooks reports whether any detector is a webhook.
+func (p *Presence) Webhooks() bool {
+	return len(p.webhooks) > 0
+}
+
+// Detect reads all detectors. Home or away from an override wins and
+// is forced. Otherwise somebody is home when any detector says so and
+// away when no detector says home and at least one says away.
+func (p *Presence) Detect(ctx context.Context) (State, bool, []Reading) {
+	readings := []Reading{}
+	state, override := Unknown, Unknown
+	for _, d := range p.detectors {
+		s, err := d.Detect(ctx)
+		readings = append(readings, Reading{Detector: d.name, State: s, Err: err})
+		switch {
+		case d.override && s != Unknown && override == Unknown:
+			override = s
+		case d.override:
+		case s == Home:
+			state = Home
+		case s == Away && state == Unknown:
+			state = Away
+		}
+	}
+	if override != Unknown {
+		return override, true, readings
+	}
+	return state, false, readings
+}
+
+// Step detects the presence and switches the profiles whose grace
+// period is over. It returns the readings and the switched devices.
+func (p *Presence) Step(ctx context.Context) ([]Reading, []Result) {
+	state, forced, readings := p.Detect(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.Now()
+	results := []Result{}
+	for _, pr := range p.profiles {
+		if state == Unknown || state == pr.applied {
+			pr.pending = Unknown
+			continue
+		}
+		if pr.pending != state {
+			pr.pending, pr.pendingSince = state, now
+		}
+		grace := pr.AwayAfter
+		if state == Home {
+			grace = pr.HomeAfter
+		}
+		if !forced && now.Sub(pr.pendingSince) < grace {
+			continue
+		}
+
+		parameters := pr.comfort
+		if state == Away {
+			parameters = pr.eco
+		}
+		failed := false
+		for _, r := range p.client.Each(pr.devices, cloudcontrol.DefaultParallelism, func(c *cloudcontrol.Client) ([]byte, error) {
+			return c.SetState(parameters)
+		}) {
+			results = append(results, Result{Profile: pr.Name, DeviceGUID: r.DeviceGUID, State: state, Err: r.Err})
+			failed = failed || r.Err != nil
+		}
+		// A failed switch is tried again at the next step
+		if !failed {
+			pr.applied, pr.pending = state, Unknown
+			log.Infof("%s: %s, switched %d device(s)", pr.Name, state, len(pr.devices))
+		}
+	}
+
+	return readings, results
+}
+
+// Run steps every Interval until the context is done.
+func (p *Presence) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		readings, results := p.Step(ctx)
+		for _, r := range readings {
+			if r.Err != nil {
+				log.Warnf("Presence detector %s: %v", r.Detector, r.Err)
+			}
+		}
+		for _, r := range results {
+			if r.Err != nil {
+				log.Warnf("%s: %s: %v", r.Profile, r.DeviceGUID, r.Err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
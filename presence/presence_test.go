@@ -0,0 +1,208 @@
+package presence_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/presence"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+func state(power, mode string, temperature float64) api.State {
+	return api.State{Power: &power, Mode: &mode, Temperature: &temperature}
+}
+
+func TestWebhook(t *testing.T) {
+	w := &presence.WebhookDetector{Token: "secret"}
+	server := httptest.NewServer(w)
+	defer server.Close()
+
+	post := func(query, body string) int {
+		t.Helper()
+		resp, err := http.Post(server.URL+query, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	tests := []struct {
+		query, body string
+		code        int
+		want        presence.State
+	}{
+		{"?state=home", "", http.StatusUnauthorized, presence.Unknown},
+		{"?token=secre&state=home", "", http.StatusUnauthorized, presence.Unknown},
+		{"?token=secret&state=home", "", http.StatusNoContent, presence.Home},
+		{"?token=secret", `{"present": false}`, http.StatusNoContent, presence.Away},
+		{"?token=secret", `{"state": "Home"}`, http.StatusNoContent, presence.Home},
+		{"?token=secret", `{"state": "gone"}`, http.StatusBadRequest, presence.Home},
+		{"?token=secret", `{"state": "away", "padding": "` + strings.Repeat("x", 1<<20) + `"}`, http.StatusBadRequest, presence.Home},
+	}
+	for _, tc := range tests {
+		if code := post(tc.query, tc.body); code != tc.code {
+			t.Errorf("POST %s %s: got status %d, want %d", tc.query, tc.body, code, tc.code)
+		}
+		if got, _ := w.Detect(context.Background()); got != tc.want {
+			t.Errorf("POST %s %s: got %q, want %q", tc.query, tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestCommandAndFile(t *testing.T) {
+	ctx := context.Background()
+	command := []string{"sh", "-c", "test {host} = phone"}
+	for _, tc := range []struct {
+		hosts []string
+		want  presence.State
+	}{
+		{[]string{"laptop", "phone"}, presence.Home},
+		{[]string{"laptop"}, presence.Away},
+	} {
+		if got, err := (&presence.CommandDetector{Command: command, Hosts: tc.hosts}).Detect(ctx); err != nil || got != tc.want {
+			t.Errorf("hosts %v: got %q, %v, want %q", tc.hosts, got, err, tc.want)
+		}
+	}
+	if _, err := (&presence.CommandDetector{Command: []string{"/nonexistent"}}).Detect(ctx); err == nil {
+		t.Error("missing command succeeded, want error")
+	}
+
+	// A hanging command does not block the other hosts
+	hanging := []string{"sh", "-c", "test {host} = phone || exec sleep 10"}
+	detector := &presence.CommandDetector{Command: hanging, Timeout: 50 * time.Millisecond}
+	for _, tc := range []struct {
+		hosts []string
+		want  presence.State
+	}{
+		{[]string{"laptop", "phone"}, presence.Home},
+		{[]string{"laptop"}, presence.Unknown},
+	} {
+		detector.Hosts = tc.hosts
+		start := time.Now()
+		got, err := detector.Detect(ctx)
+		if got != tc.want || (tc.want == presence.Unknown) != (err != nil) {
+			t.Errorf("hanging hosts %v: got %q, %v, want %q", tc.hosts, got, err, tc.want)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("hanging hosts %v: took %v, want the timeout", tc.hosts, elapsed)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "presence")
+	f := &presence.FileDetector{Path: path}
+	if got, err := f.Detect(ctx); err != nil || got != presence.Unknown {
+		t.Errorf("missing file: got %q, %v, want unknown", got, err)
+	}
+	if err := ioutil.WriteFile(path, []byte("away\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Detect(ctx); err != nil || got != presence.Away {
+		t.Errorf("got %q, %v, want away", got, err)
+	}
+}
+
+func TestPresence(t *testing.T) {
+	c := cloudtest.Default()
+	client := testclient.New(t, c)
+	override := filepath.Join(t.TempDir(), "override")
+	p, err := presence.New(client, presence.Config{
+		Detectors: []presence.DetectorConfig{
+			{Name: "phone", Type: presence.Webhook, Token: "secret"},
+			{Name: "override", Type: presence.File, Path: override},
+		},
+		Profiles: []presence.Profile{{
+			Name:      "living",
+			Devices:   []string{"Living"},
+			Comfort:   state("on", "heat", 21),
+			Eco:       state("on", "heat", 17),
+			AwayAfter: 30 * time.Minute,
+			HomeAfter: 5 * time.Minute,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 4, 8, 0, 0, 0, time.Local)
+	p.Now = func() time.Time { return now }
+	server := httptest.NewServer(p.Handler())
+	defer server.Close()
+
+	setpoints := []float64{}
+	step := func(minutes int, phone string) {
+		t.Helper()
+		now = now.Add(time.Duration(minutes) * time.Minute)
+		if phone != "" {
+			resp, err := http.Post(server.URL+"/presence/phone?token=secret&state="+phone, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+		_, results := p.Step(context.Background())
+		for _, r := range results {
+			if r.Err != nil {
+				t.Fatalf("%s: %v", r.DeviceGUID, r.Err)
+			}
+			d, _ := c.Status(living)
+			if d.Parameters.Operate != 1 || d.Parameters.OperationMode != pt.Modes["heat"] {
+				t.Errorf("got %+v, want heating", d.Parameters)
+			}
+			setpoints = append(setpoints, d.Parameters.TemperatureSet)
+		}
+	}
+
+	// Nothing is known until the phone calls
+	step(0, "")
+	// Home after the grace period, away only after 30 minutes away
+	step(0, "home")
+	step(5, "")
+	step(10, "away")
+	step(20, "")
+	step(10, "")
+	// Coming back shortly is ignored
+	step(10, "home")
+	step(2, "away")
+	step(5, "")
+	// The override switches without grace period
+	if err := ioutil.WriteFile(override, []byte("home"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	step(1, "")
+
+	if diff := cmp.Diff([]float64{21, 17, 21}, setpoints); diff != "" {
+		t.Errorf("setpoints mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	webhook := []presence.DetectorConfig{{Name: "phone", Type: presence.Webhook, Token: "secret"}}
+	profile := presence.Profile{Devices: []string{"Living"}, Comfort: state("on", "heat", 21), Eco: state("off", "heat", 21)}
+	for _, config := range []presence.Config{
+		{},
+		{Detectors: webhook},
+		{Detectors: []presence.DetectorConfig{{Name: "phone", Type: "bluetooth"}}, Profiles: []presence.Profile{profile}},
+		{Detectors: []presence.DetectorConfig{{Name: "ping", Type: presence.Command}}, Profiles: []presence.Profile{profile}},
+		{Detectors: []presence.DetectorConfig{{Type: presence.Webhook, Token: "secret"}}, Profiles: []presence.Profile{profile}},
+		{Detectors: []presence.DetectorConfig{{Name: "phone", Type: presence.Webhook}}, Profiles: []presence.Profile{profile}},
+		{Detectors: append(webhook, webhook...), Profiles: []presence.Profile{profile}},
+		{Detectors: webhook, Profiles: []presence.Profile{{Devices: []string{"Attic"}, Comfort: profile.Comfort, Eco: profile.Eco}}},
+		{Detectors: webhook, Profiles: []presence.Profile{{Devices: []string{"Living"}, Comfort: profile.Comfort}}},
+	} {
+		if _, err := presence.New(client, config); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", config)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: panasonic.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Panasonic_ListDevices_FullMethodName = "/panasonic.v1.Panasonic/ListDevices"
+	Panasonic_GetStatus_FullMethodName   = "/panasonic.v1.Panasonic/GetStatus"
+	Panasonic_SetState_FullMethodName    = "/panasonic.v1.Panasonic/SetState"
+	Panasonic_GetHistory_FullMethodName  = "/panasonic.v1.Panasonic/GetHistory"
+	Panasonic_WatchStatus_FullMethodName = "/panasonic.v1.Panasonic/WatchStatus"
+)
+
+// PanasonicClient is the client API for Panasonic service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PanasonicClient interface {
+	// ListDevices lists all devices with their group.
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// GetStatus gets the current status of a device.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error)
+	// SetState sends all given fields to the device in a single
+	// command and returns the new status.
+	SetState(ctx context.Context, in *SetStateRequest, opts ...grpc.CallOption) (*Status, error)
+	// GetHistory gets the energy history of a device.
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*History, error)
+	// WatchStatus polls devices and streams their state changes.
+	WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (Panasonic_WatchStatusClient, error)
+}
+
+type panasonicClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPanasonicClient(cc grpc.ClientConnInterface) PanasonicClient {
+	return &panasonicClient{cc}
+}
+
+func (c *panasonicClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, Panasonic_ListDevices_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *panasonicClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, Panasonic_GetStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *panasonicClient) SetState(ctx context.Context, in *SetStateRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, Panasonic_SetState_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *panasonicClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*History, error) {
+	out := new(History)
+	err := c.cc.Invoke(ctx, Panasonic_GetHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *panasonicClient) WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (Panasonic_WatchStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Panasonic_ServiceDesc.Streams[0], Panasonic_WatchStatus_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &panasonicWatchStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Panasonic_WatchStatusClient interface {
+	Recv() (*StatusChange, error)
+	grpc.ClientStream
+}
+
+type panasonicWatchStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *panasonicWatchStatusClient) Recv() (*StatusChange, error) {
+	m := new(StatusChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PanasonicServer is the server API for Panasonic service.
+// All implementations must embed UnimplementedPanasonicServer
+// for forward compatibility
+type PanasonicServer interface {
+	// ListDevices lists all devices with their group.
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// GetStatus gets the current status of a device.
+	GetStatus(context.Context, *GetStatusRequest) (*Status, error)
+	// SetState sends all given fields to the device in a single
+	// command and returns the new status.
+	SetState(context.Context, *SetStateRequest) (*Status, error)
+	// GetHistory gets the energy history of a device.
+	GetHistory(context.Context, *GetHistoryRequest) (*History, error)
+	// WatchStatus polls devices and streams their state changes.
+	WatchStatus(*WatchStatusRequest, Panasonic_WatchStatusServer) error
+	mustEmbedUnimplementedPanasonicServer()
+}
+
+// UnimplementedPanasonicServer must be embedded to have forward compatible implementations.
+type UnimplementedPanasonicServer struct {
+}
+
+func (UnimplementedPanasonicServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedPanasonicServer) GetStatus(context.Context, *GetStatusRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedPanasonicServer) SetState(context.Context, *SetStateRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetState not implemented")
+}
+func (UnimplementedPanasonicServer) GetHistory(context.Context, *GetHistoryRequest) (*History, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedPanasonicServer) WatchStatus(*WatchStatusRequest, Panasonic_WatchStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedPanasonicServer) mustEmbedUnimplementedPanasonicServer() {}
+
+// UnsafePanasonicServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PanasonicServer will
+// result in compilation errors.
+type UnsafePanasonicServer interface {
+	mustEmbedUnimplementedPanasonicServer()
+}
+
+func RegisterPanasonicServer(s grpc.ServiceRegistrar, srv PanasonicServer) {
+	s.RegisterService(&Panasonic_ServiceDesc, srv)
+}
+
+func _Panasonic_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PanasonicServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Panasonic_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PanasonicServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Panasonic_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PanasonicServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Panasonic_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PanasonicServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Panasonic_SetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PanasonicServer).SetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Panasonic_SetState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PanasonicServer).SetState(ctx, req.(*SetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Panasonic_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PanasonicServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Panasonic_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PanasonicServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Panasonic_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PanasonicServer).WatchStatus(m, &panasonicWatchStatusServer{stream})
+}
+
+type Panasonic_WatchStatusServer interface {
+	Send(*StatusChange) error
+	grpc.ServerStream
+}
+
+type panasonicWatchStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *panasonicWatchStatusServer) Send(m *StatusChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Panasonic_ServiceDesc is the grpc.ServiceDesc for Panasonic service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Panasonic_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "panasonic.v1.Panasonic",
+	HandlerType: (*PanasonicServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDevices",
+			Handler:    _Panasonic_ListDevices_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Panasonic_GetStatus_Handler,
+		},
+		{
+			MethodName: "SetState",
+			Handler:    _Panasonic_SetState_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _Panasonic_GetHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _Panasonic_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "panasonic.proto",
+}
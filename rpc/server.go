@@ -0,0 +1,270 @@
+// Package rpc serves Panasonic Comfort Cloud devices over gRPC. The
+// service is defined in panasonic.proto, the generated Go client and
+// server code live in the pb package.
+package rpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/rpc/pb"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultMinInterval is the shortest poll interval a WatchStatus call
+// may request.
+const DefaultMinInterval = 10 * time.Second
+
+// Server implements the Panasonic gRPC service with a Client.
+type Server struct {
+	pb.UnimplementedPanasonicServer
+
+	Client *cloudcontrol.Client
+	// Interval is the poll interval of WatchStatus calls that
+	// don't request one
+	Interval    time.Duration
+	MinInterval time.Duration
+}
+
+// NewServer creates a Server with the default intervals.
+func NewServer(client *cloudcontrol.Client) *Server {
+	return &Server{
+		Client:      client,
+		Interval:    watch.DefaultInterval,
+		MinInterval: DefaultMinInterval,
+	}
+}
+
+// cloudError converts an error from the cloud into a gRPC status.
+func cloudError(err error) error {
+	switch {
+	case errors.Is(err, cloudcontrol.ErrDeviceOffline):
+		return status.Error(codes.Unavailable, err.Error())
+	case cloudcontrol.IsAuthError(err):
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	var httpErr *cloudcontrol.HTTPError
+	if errors.As(err, &httpErr) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// device returns a client for the device matching query.
+func (s *Server) device(query string) (*cloudcontrol.Client, error) {
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "error: no device given")
+	}
+	client := *s.Client
+	if err := client.SetDeviceByName(query); err != nil {
+		var ambiguous *cloudcontrol.AmbiguousDeviceError
+		var httpErr *cloudcontrol.HTTPError
+		switch {
+		case errors.As(err, &ambiguous):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.As(err, &httpErr):
+			return nil, cloudError(err)
+		}
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &client, nil
+}
+
+// toStatus converts a device status to its message.
+func toStatus(d pt.Device) *pb.Status {
+	p := d.Parameters
+	errorCode := ""
+	if p.ErrorStatusFlg {
+		errorCode = p.ErrorCodeStr
+		if errorCode == "" {
+			errorCode = fmt.Sprint(p.ErrorCode)
+		}
+	}
+	return &pb.Status{
+		Guid:               d.DeviceGUID,
+		Name:               d.DeviceName,
+		Online:             p.Online,
+		Power:              p.Operate == 1,
+		Mode:               pb.Mode(p.OperationMode + 1),
+		TemperatureSet:     p.TemperatureSet,
+		InsideTemperature:  p.InsideTemperature,
+		OutsideTemperature: p.OutsideTemperature,
+		FanSpeed:           pb.FanSpeed(p.FanSpeed + 1),
+		Error:              p.ErrorStatusFlg,
+		ErrorCode:          errorCode,
+	}
+}
+
+// ListDevices lists all devices with their group.
+func (s *Server) ListDevices(ctx context.Context, req *pb.ListDevicesRequest) (*pb.ListDevicesResponse, error) {
+	devices, err := s.Client.ListGroupDevices()
+	if err != nil {
+		return nil, cloudError(err)
+	}
+	resp := &pb.ListDevicesResponse{}
+	for _, d := range devices {
+		resp.Devices = append(resp.Devices, &pb.Device{
+			Guid:  d.DeviceGUID,
+			Name:  d.DeviceName,
+			Group: d.GroupName,
+			Model: d.DeviceModuleNumber,
+		})
+	}
+	return resp, nil
+}
+
+// getStatus gets the status of the device on a client.
+func getStatus(client *cloudcontrol.Client) (*pb.Status, error) {
+	d, err := client.GetDeviceStatus()
+	if err != nil {
+		return nil, cloudError(err)
+	}
+	if d.DeviceGUID == "" {
+		d.DeviceGUID = client.DeviceGUID
+	}
+	return toStatus(d), nil
+}
+
+// GetStatus gets the current status of a device.
+func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.Status, error) {
+	client, err := s.device(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	return getStatus(client)
+}
+
+// SetState sends the requested state to a device in a single command.
+func (s *Server) SetState(ctx context.Context, req *pb.SetStateRequest) (*pb.Status, error) {
+	parameters := pt.DeviceControlParameters{}
+	if req.Power != nil {
+		operate := 0
+		if *req.Power {
+			operate = 1
+		}
+		parameters.Operate = &operate
+	}
+	if req.Mode != pb.Mode_MODE_UNSPECIFIED {
+		mode := int(req.Mode) - 1
+		if _, ok := pt.ModesReverse[mode]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "error: invalid mode %v", req.Mode)
+		}
+		parameters.OperationMode = &mode
+	}
+	if req.Temperature != nil {
+		parameters.TemperatureSet = req.Temperature
+	}
+	if req.FanSpeed != pb.FanSpeed_FAN_SPEED_UNSPECIFIED {
+		speed := int(req.FanSpeed) - 1
+		if _, ok := pt.FanSpeedsReverse[speed]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "error: invalid fan speed %v", req.FanSpeed)
+		}
+		parameters.FanSpeed = &speed
+	}
+	if parameters == (pt.DeviceControlParameters{}) {
+		return nil, status.Error(codes.InvalidArgument, "error: no state given")
+	}
+
+	client, err := s.device(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.SetState(parameters); err != nil {
+		return nil, cloudError(err)
+	}
+	return getStatus(client)
+}
+
+// GetHistory gets the energy history of a device.
+func (s *Server) GetHistory(ctx context.Context, req *pb.GetHistoryRequest) (*pb.History, error) {
+	valid := false
+	for _, mode := range pt.HistoryDataMode {
+		valid = valid || int(req.Period) == mode
+	}
+	if !valid {
+		return nil, status.Errorf(codes.InvalidArgument, "error: invalid period %v", req.Period)
+	}
+	client, err := s.device(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	date := time.Now()
+	if req.Date != nil {
+		date = req.Date.AsTime().In(time.Local)
+	}
+	history, err := client.GetDeviceHistoryForDate(int(req.Period), date)
+	if err != nil {
+		return nil, cloudError(err)
+	}
+
+	resp := &pb.History{EnergyConsumption: history.EnergyConsumption}
+	for _, e := range history.HistoryEntries {
+		entry := &pb.HistoryEntry{
+			DataNumber:                int32(e.DataNumber),
+			AverageSettingTemperature: e.AverageSettingTemp,
+			AverageInsideTemperature:  e.AverageInsideTemp,
+			AverageOutsideTemperature: e.AverageOutsideTemp,
+		}
+		if e.Consumption != pt.HistoryNoData {
+			consumption := e.Consumption
+			entry.Consumption = &consumption
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp, nil
+}
+
+// WatchStatus streams state changes of the requested devices until
+// the client cancels the call.
+func (s *Server) WatchStatus(req *pb.WatchStatusRequest, stream pb.Panasonic_WatchStatusServer) error {
+	interval := s.Interval
+	if req.Interval != nil {
+		interval = req.Interval.AsDuration()
+	}
+	if interval < s.MinInterval {
+		return status.Errorf(codes.InvalidArgument, "error: interval must be at least %v", s.MinInterval)
+	}
+
+	guids := []string{}
+	for _, query := range req.Devices {
+		client, err := s.device(query)
+		if err != nil {
+			return err
+		}
+		guids = append(guids, client.DeviceGUID)
+	}
+	if len(guids) == 0 {
+		devices, err := s.Client.ListDevices()
+		if err != nil {
+			return cloudError(err)
+		}
+		guids = devices
+	}
+
+	w := watch.New(s.Client, guids)
+	w.Interval = interval
+	for e := range w.Watch(stream.Context()) {
+		change := &pb.StatusChange{
+			Time:   timestamppb.New(e.Time),
+			Guid:   e.DeviceGUID,
+			Name:   e.DeviceName,
+			Type:   string(e.Type),
+			Old:    fmt.Sprint(e.Old),
+			New:    fmt.Sprint(e.New),
+			Status: toStatus(e.Status),
+		}
+		if err := stream.Send(change); err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
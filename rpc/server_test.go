@@ -0,0 +1,173 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/rpc"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/rpc/pb"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// cloud is a fake Panasonic cloud whose inside temperature rises on
+// every status request.
+type cloud struct {
+	mu          sync.Mutex
+	temperature float64
+	commands    []pt.Command
+}
+
+func (c *cloud) handler() http.Handler {
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"My House","deviceList":[{"deviceGuid":"device1","deviceName":"Living","deviceModuleNumber":"CS-Z25"}]}]}`))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		fmt.Fprintf(w, `{"deviceGuid":"device1","deviceName":"Living","parameters":{"online":true,"operate":1,"operationMode":3,"fanSpeed":0,"temperatureSet":21,"insideTemperature":%v}}`, c.temperature)
+		c.temperature++
+	})
+	handler.HandleFunc(pt.URLControl, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		command := pt.Command{}
+		_ = json.Unmarshal(body, &command)
+		c.mu.Lock()
+		c.commands = append(c.commands, command)
+		c.mu.Unlock()
+		_, _ = w.Write([]byte(pt.SuccessResponse))
+	})
+	handler.HandleFunc(pt.URLHistory, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"energyConsumption":0.5,"historyDataList":[{"dataNumber":0,"consumption":0.5},{"dataNumber":1,"consumption":-255}]}`))
+	})
+	return handler
+}
+
+// dial starts the gRPC server on an in-memory listener and returns a
+// client connected to it.
+func dial(t *testing.T, s *rpc.Server) pb.PanasonicClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterPanasonicServer(server, s)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewPanasonicClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	c := &cloud{temperature: 19}
+	ts := httptest.NewServer(c.handler())
+	defer ts.Close()
+	client := cloudcontrol.NewClient(ts.URL)
+	s := rpc.NewServer(&client)
+	s.MinInterval = time.Millisecond
+	conn := dial(t, s)
+	ctx := context.Background()
+
+	devices, err := conn.ListDevices(ctx, &pb.ListDevicesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDevices := &pb.ListDevicesResponse{Devices: []*pb.Device{{Guid: "device1", Name: "Living", Group: "My House", Model: "CS-Z25"}}}
+	if diff := cmp.Diff(wantDevices, devices, protocmp.Transform()); diff != "" {
+		t.Errorf("TestServer() ListDevices mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := conn.GetStatus(ctx, &pb.GetStatusRequest{Device: "living"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &pb.Status{Guid: "device1", Name: "Living", Online: true, Power: true, Mode: pb.Mode_MODE_HEAT, TemperatureSet: 21, InsideTemperature: 19, FanSpeed: pb.FanSpeed_FAN_SPEED_AUTO}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("TestServer() GetStatus mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := conn.GetStatus(ctx, &pb.GetStatusRequest{Device: "kitchen"}); status.Code(err) != codes.NotFound {
+		t.Errorf("TestServer() want NotFound for unknown device, got %v", err)
+	}
+
+	power, temperature := false, 22.5
+	if _, err := conn.SetState(ctx, &pb.SetStateRequest{Device: "device1", Power: &power, Mode: pb.Mode_MODE_COOL, Temperature: &temperature}); err != nil {
+		t.Fatal(err)
+	}
+	off, cool := 0, pt.Modes["cool"]
+	wantCommands := []pt.Command{{DeviceGUID: "device1", Parameters: pt.DeviceControlParameters{Operate: &off, OperationMode: &cool, TemperatureSet: &temperature}}}
+	if diff := cmp.Diff(wantCommands, c.commands); diff != "" {
+		t.Errorf("TestServer() SetState mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := conn.SetState(ctx, &pb.SetStateRequest{Device: "device1"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("TestServer() want InvalidArgument for empty state, got %v", err)
+	}
+
+	history, err := conn.GetHistory(ctx, &pb.GetHistoryRequest{Device: "device1", Period: pb.Period_PERIOD_DAY})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumption := 0.5
+	wantHistory := &pb.History{EnergyConsumption: 0.5, Entries: []*pb.HistoryEntry{{DataNumber: 0, Consumption: &consumption}, {DataNumber: 1}}}
+	if diff := cmp.Diff(wantHistory, history, protocmp.Transform()); diff != "" {
+		t.Errorf("TestServer() GetHistory mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := conn.GetHistory(ctx, &pb.GetHistoryRequest{Device: "device1", Period: pb.Period(7)}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("TestServer() want InvalidArgument for unknown period, got %v", err)
+	}
+}
+
+func TestWatchStatus(t *testing.T) {
+	c := &cloud{temperature: 19}
+	ts := httptest.NewServer(c.handler())
+	defer ts.Close()
+	client := cloudcontrol.NewClient(ts.URL)
+	s := rpc.NewServer(&client)
+	s.MinInterval = time.Millisecond
+	conn := dial(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := conn.WatchStatus(ctx, &pb.WatchStatusRequest{Interval: durationpb.New(5 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Guid != "device1" || change.Type != "insideTemperature" || change.Old != "19" || change.New != "20" {
+		t.Errorf("TestWatchStatus() unexpected change %v", change)
+	}
+
+	s.MinInterval = time.Minute
+	stream, err = conn.WatchStatus(ctx, &pb.WatchStatusRequest{Interval: durationpb.New(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("TestWatchStatus() want InvalidArgument for short interval, got %v", err)
+	}
+}
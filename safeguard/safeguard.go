@@ -0,0 +1,309 @@
+// Package safeguard keeps unattended devices, eg in holiday homes, from
+// letting rooms freeze. Devices whose inside temperature drops below a
+// threshold are forced to heat at a protective setpoint, and alerts are
+// sent when this happens and when a device is offline for too long.
+package safeguard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/alert"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultBelow        = 10
+	DefaultSetpoint     = 10
+	DefaultOfflineAfter = time.Hour
+	DefaultInterval     = 5 * time.Minute
+)
+
+// Event types of safeguard alerts
+const (
+	Frost   watch.EventType = "frost"
+	Offline watch.EventType = "offline"
+)
+
+// Guard protects devices.
+type Guard struct {
+	Name string `json:"name"`
+	// Devices are GUIDs, names, aliases or group names
+	Devices []string `json:"devices"`
+	// Below is the inside temperature under which a device is forced to
+	// heat at Setpoint. Setpoint is raised to the lowest heating
+	// setpoint of the device, units with summer house mode enabled
+	// usually heat from 8°C.
+	Below    float64 `json:"below"`
+	Setpoint float64 `json:"setpoint"`
+	// OfflineAfter is how long a device may be offline or unreachable
+	// before an alert is sent
+	OfflineAfter time.Duration `json:"offlineAfter"`
+	// Sinks are the names of the alert sinks to notify, all when empty
+	Sinks []string `json:"sinks"`
+}
+
+// Config is the safeguard configuration.
+type Config struct {
+	Interval time.Duration `json:"interval"`
+	Guards   []Guard       `json:"guards"`
+}
+
+// Result is the outcome of a check of a device.
+type Result struct {
+	Guard       string
+	DeviceGUID  string
+	DeviceName  string
+	Online      bool
+	Inside      float64
+	SummerHouse int
+	// Forced is true when the device was forced to heat in the check
+	Forced bool
+	// Offline is how long the device has been offline or unreachable
+	Offline time.Duration
+	Err     error
+}
+
+// device is a guarded device with its state.
+type device struct {
+	guard    *Guard
+	guid     string
+	setpoint float64 // protective setpoint in the device heating range
+	offline  time.Time
+	alerted  bool // offline alert sent
+	frost    bool // frost alert sent
+}
+
+// Safeguard checks guarded devices.
+type Safeguard struct {
+	Config
+	Now   func() time.Time
+	Sinks map[string]alert.Sink
+
+	client  *cloudcontrol.Client
+	mu      sync.Mutex
+	devices []*device
+}
+
+// New creates a Safeguard. Devices are resolved once and their heating
+// range is looked up for the protective setpoint.
+func New(client *cloudcontrol.Client, config Config, sinks map[string]alert.Sink) (*Safeguard, error) {
+	if config.Interval < 0 {
+		return nil, fmt.Errorf("error: safeguard interval must be positive")
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+	if len(config.Guards) == 0 {
+		return nil, fmt.Errorf("error: no safeguard guards")
+	}
+
+	s := &Safeguard{Config: config, Now: time.Now, Sinks: sinks, client: client}
+	for i := range config.Guards {
+		g := &config.Guards[i]
+		if g.Name == "" {
+			g.Name = fmt.Sprintf("guard %d", i+1)
+		}
+		if g.Below == 0 {
+			g.Below = DefaultBelow
+		}
+		if g.Setpoint == 0 {
+			g.Setpoint = DefaultSetpoint
+		}
+		if g.OfflineAfter == 0 {
+			g.OfflineAfter = DefaultOfflineAfter
+		}
+		if g.OfflineAfter < 0 {
+			return nil, fmt.Errorf("error: safeguard %q has a negative offline time", g.Name)
+		}
+		if len(g.Devices) == 0 {
+			return nil, fmt.Errorf("error: safeguard %q has no devices", g.Name)
+		}
+		for _, name := range g.Sinks {
+			if _, found := sinks[name]; !found {
+				return nil, fmt.Errorf("error: safeguard %q has unknown sink %q", g.Name, name)
+			}
+		}
+
+		for _, d := range g.Devices {
+			guids, err := client.ResolveDevices(d)
+			if err != nil {
+				return nil, fmt.Errorf("error: safeguard %q: %w", g.Name, err)
+			}
+			for _, guid := range guids {
+				found, err := client.FindDevice(guid)
+				if err != nil {
+					return nil, fmt.Errorf("error: safeguard %q: %w", g.Name, err)
+				}
+				if !found.HeatMode {
+					return nil, fmt.Errorf("error: safeguard %q: %s cannot heat", g.Name, found.DeviceName)
+				}
+				setpoint := g.Setpoint
+				if min := float64(found.HeatTempMin); min > setpoint {
+					log.Warnf("%s: %s heats from %v°C with summer house %d, using setpoint %v°C", g.Name, found.DeviceName, min, found.SummerHouse, min)
+					setpoint = min
+				}
+				if max := float64(found.HeatTempMax); max > 0 && setpoint > max {
+					setpoint = max
+				}
+				s.devices = append(s.devices, &device{guard: g, guid: guid, setpoint: math.Round(setpoint*2) / 2})
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Check gets the status of all guarded devices, forces devices that are
+// too cold to heat and sends alerts.
+func (s *Safeguard) Check(ctx context.Context) []Result {
+	guids := []string{}
+	for _, d := range s.devices {
+		guids = append(guids, d.guid)
+	}
+	statuses := map[string]cloudcontrol.StatusResult{}
+	for _, status := range s.client.EachStatus(guids, cloudcontrol.DefaultParallelism) {
+		statuses[status.DeviceGUID] = status
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	results := []Result{}
+	for _, d := range s.devices {
+		status := statuses[d.guid]
+		p := status.Status.Parameters
+		result := Result{
+			Guard:       d.guard.Name,
+			DeviceGUID:  d.guid,
+			DeviceName:  status.Status.DeviceName,
+			Online:      status.Err == nil && p.Online,
+			Inside:      p.InsideTemperature,
+			SummerHouse: status.Status.SummerHouse,
+			Err:         status.Err,
+		}
+		if result.DeviceName == "" {
+			result.DeviceName = d.guid
+		}
+
+		if !result.Online {
+			if d.offline.IsZero() {
+				d.offline = now
+			}
+			result.Offline = now.Sub(d.offline)
+			if result.Offline >= d.guard.OfflineAfter && !d.alerted {
+				d.alerted = true
+				s.notify(ctx, d, watch.Event{Time: now, DeviceGUID: d.guid, DeviceName: result.DeviceName, Type: Offline, Old: d.offline.Format(time.RFC3339), New: result.Offline.Round(time.Minute).String()},
+					fmt.Sprintf("%s has been offline for %v and cannot be protected", result.DeviceName, result.Offline.Round(time.Minute)))
+			}
+			results = append(results, result)
+			continue
+		}
+		if d.alerted {
+			s.notify(ctx, d, watch.Event{Time: now, DeviceGUID: d.guid, DeviceName: result.DeviceName, Type: Offline, Old: now.Sub(d.offline).Round(time.Minute).String(), New: "online"},
+				fmt.Sprintf("%s is back online, inside %.1f°C", result.DeviceName, p.InsideTemperature))
+		}
+		d.offline, d.alerted = time.Time{}, false
+
+		control := d.control(p)
+		if control == (pt.DeviceControlParameters{}) {
+			if p.InsideTemperature >= d.guard.Below {
+				d.frost = false
+			}
+			results = append(results, result)
+			continue
+		}
+		client := *s.client
+		client.SetDevice(d.guid)
+		if _, err := client.SetState(control); err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+		result.Forced = true
+		log.Warnf("%s: %s is %.1f°C inside, heating at %v°C", d.guard.Name, result.DeviceName, p.InsideTemperature, d.setpoint)
+		if !d.frost {
+			d.frost = true
+			s.notify(ctx, d, watch.Event{Time: now, DeviceGUID: d.guid, DeviceName: result.DeviceName, Type: Frost, Old: p.InsideTemperature, New: d.setpoint},
+				fmt.Sprintf("%s is %.1f°C inside, forced to heat at %v°C", result.DeviceName, p.InsideTemperature, d.setpoint))
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// control returns the parameters forcing a device to heat, none when
+// it is warm enough or already heating at the protective setpoint or
+// above.
+func (d *device) control(p pt.DeviceParameters) pt.DeviceControlParameters {
+	control := pt.DeviceControlParameters{}
+	if p.InsideTemperature >= d.guard.Below {
+		return control
+	}
+	heat := pt.Modes["heat"]
+	if p.Operate == 1 && p.OperationMode == heat && p.TemperatureSet >= d.setpoint {
+		return control
+	}
+	if p.Operate != 1 {
+		operate := 1
+		control.Operate = &operate
+	}
+	if p.OperationMode != heat {
+		control.OperationMode = &heat
+	}
+	// Devices turned on heat at the protective setpoint, not at the
+	// setpoint they were last used with
+	if p.OperationMode != heat || p.Operate != 1 || p.TemperatureSet < d.setpoint {
+		setpoint := d.setpoint
+		control.TemperatureSet = &setpoint
+	}
+	return control
+}
+
+// notify sends an alert to the sinks of a guard.
+func (s *Safeguard) notify(ctx context.Context, d *device, e watch.Event, message string) {
+	log.Infof("%s: %s", d.guard.Name, message)
+	a := alert.Alert{
+		Rule:    d.guard.Name,
+		Title:   fmt.Sprintf("%s: %s", e.DeviceName, e.Type),
+		Message: message,
+		Event:   e,
+	}
+	names := d.guard.Sinks
+	if len(names) == 0 {
+		for name := range s.Sinks {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if err := s.Sinks[name].Send(ctx, a); err != nil {
+			log.Errorf("%s: sending alert to %s: %v", d.guard.Name, name, err)
+		}
+	}
+}
+
+// Run checks the devices every Interval until the context is done.
+func (s *Safeguard) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		for _, r := range s.Check(ctx) {
+			if r.Err != nil {
+				log.Warnf("%s: %s: %v", r.Guard, r.DeviceName, r.Err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package safeguard_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/alert"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/safeguard"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+// recorder is a sink keeping the titles of the alerts.
+type recorder struct {
+	titles []string
+}
+
+func (r *recorder) Send(_ context.Context, a alert.Alert) error {
+	r.titles = append(r.titles, a.Title)
+	return nil
+}
+
+func TestSafeguard(t *testing.T) {
+	c := cloudtest.Default()
+	if err := c.Update(living, func(d *pt.Device) {
+		d.SummerHouse = 2
+		d.HeatTempMin = 8
+		d.Parameters.InsideTemperature = 7
+		d.Parameters.OperationMode = pt.Modes["cool"]
+	}); err != nil {
+		t.Fatal(err)
+	}
+	client := testclient.New(t, c)
+	sink := &recorder{}
+	s, err := safeguard.New(client, safeguard.Config{Guards: []safeguard.Guard{
+		{Name: "cabin", Devices: []string{"Living"}, Below: 9, Setpoint: 8},
+	}}, map[string]alert.Sink{"phone": sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 4, 3, 0, 0, 0, time.Local)
+	s.Now = func() time.Time { return now }
+
+	check := func() safeguard.Result {
+		t.Helper()
+		results := s.Check(context.Background())
+		if len(results) != 1 {
+			t.Fatalf("got %d results, want 1", len(results))
+		}
+		return results[0]
+	}
+
+	// Too cold, forced to heat at the summer house setpoint once
+	if r := check(); !r.Forced || r.Err != nil || r.SummerHouse != 2 {
+		t.Errorf("got %+v, want forced with summer house 2", r)
+	}
+	d, _ := c.Status(living)
+	if p := d.Parameters; p.Operate != 1 || p.OperationMode != pt.Modes["heat"] || p.TemperatureSet != 8 {
+		t.Errorf("got %+v, want heating at 8", p)
+	}
+	if r := check(); r.Forced {
+		t.Errorf("got %+v, want no change while heating", r)
+	}
+
+	// Offline alerts after an hour, once, and when back online
+	if err := c.Update(living, func(d *pt.Device) { d.Parameters.Online = false }); err != nil {
+		t.Fatal(err)
+	}
+	for _, minutes := range []int{1, 30, 61, 120} {
+		now = now.Add(time.Duration(minutes) * time.Minute)
+		if r := check(); r.Online {
+			t.Errorf("got %+v, want offline", r)
+		}
+	}
+	if err := c.Update(living, func(d *pt.Device) { d.Parameters.Online = true }); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	check()
+
+	want := []string{"Living: frost", "Living: offline", "Living: offline"}
+	if diff := cmp.Diff(want, sink.titles); diff != "" {
+		t.Errorf("alerts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetpointRange(t *testing.T) {
+	c := cloudtest.Default()
+	if err := c.Update(living, func(d *pt.Device) { d.Parameters.InsideTemperature = 7 }); err != nil {
+		t.Fatal(err)
+	}
+	client := testclient.New(t, c)
+	s, err := safeguard.New(client, safeguard.Config{Guards: []safeguard.Guard{{Devices: []string{"Living"}, Setpoint: 8}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Check(context.Background())
+	d, _ := c.Status(living)
+	if d.Parameters.TemperatureSet != 16 {
+		t.Errorf("got setpoint %v, want the lowest heating setpoint 16 without summer house", d.Parameters.TemperatureSet)
+	}
+}
+
+func TestSetpointCloudPayload(t *testing.T) {
+	// A summer house unit as the cloud reports it, heating from 10°C
+	var commands []pt.Command
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"Cabin","deviceList":[{"deviceGuid":"device1","deviceName":"Cabin","summerHouse":2,"heatMode":true,"coolTempMin":18,"coolTempMax":30,"heatTempMin":10,"heatTempMax":30}]}]}`))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"deviceGuid":"device1","deviceName":"Cabin","parameters":{"online":true,"operate":0,"operationMode":3,"temperatureSet":20,"insideTemperature":5}}`))
+	})
+	handler.HandleFunc(pt.URLControl, func(w http.ResponseWriter, r *http.Request) {
+		command := pt.Command{}
+		_ = json.NewDecoder(r.Body).Decode(&command)
+		commands = append(commands, command)
+		_, _ = w.Write([]byte(pt.SuccessResponse))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := cloudcontrol.NewClient(server.URL)
+
+	s, err := safeguard.New(&client, safeguard.Config{Guards: []safeguard.Guard{{Devices: []string{"Cabin"}, Below: 7, Setpoint: 8}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := s.Check(context.Background()); len(r) != 1 || !r[0].Forced || r[0].Err != nil {
+		t.Fatalf("got %+v, want forced", r)
+	}
+	if len(commands) != 1 || commands[0].Parameters.TemperatureSet == nil || *commands[0].Parameters.TemperatureSet != 10 {
+		t.Errorf("got commands %+v, want heating at the unit minimum 10", commands)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	for _, g := range []safeguard.Guard{
+		{},
+		{Devices: []string{"Attic"}},
+		{Devices: []string{"Bedroom"}},
+		{Devices: []string{"Living"}, Sinks: []string{"phone"}},
+		{Devices: []string{"Living"}, OfflineAfter: -time.Minute},
+	} {
+		if _, err := safeguard.New(client, safeguard.Config{Guards: []safeguard.Guard{g}}, nil); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", g)
+		}
+	}
+}
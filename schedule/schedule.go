@@ -0,0 +1,420 @@
+// Package schedule runs schedules for Panasonic Comfort Cloud devices
+// locally. Rules set the state of devices or groups at times given by
+// cron expressions, lists of weekdays and times or sunrise and sunset,
+// and can skip or only run on holidays and exception dates.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 30 * time.Second
+	// MaxCatchUp is how far Run looks back for missed minutes, eg after
+	// the computer was suspended
+	MaxCatchUp = 10 * time.Minute
+)
+
+// Holiday handling of a rule
+const (
+	// Ignore runs the rule on holidays as on other days
+	Ignore = "ignore"
+	// Skip does not run the rule on holidays
+	Skip = "skip"
+	// Only runs the rule only on holidays
+	Only = "only"
+)
+
+// Rule sets the state of devices at the times of its trigger. Exactly
+// one of Cron, At and Sun is used as trigger.
+type Rule struct {
+	Name string `json:"name"`
+	// Devices are GUIDs, names, aliases or group names
+	Devices []string `json:"devices"`
+	// Cron is a cron expression, see Cron
+	Cron string `json:"cron"`
+	// At are times of day as 15:04
+	At []string `json:"at"`
+	// Sun is sunrise or sunset, shifted by Offset, eg -30m
+	Sun    string        `json:"sun"`
+	Offset time.Duration `json:"offset"`
+	// Days limit At and Sun to weekdays (mon, tue, ...), weekdays
+	// or weekend, every day when empty
+	Days []string `json:"days"`
+	// Holidays is ignore, skip or only
+	Holidays string `json:"holidays"`
+	// Except are dates as 2006-01-02 on which the rule does not run
+	Except []string  `json:"except"`
+	State  api.State `json:"state"`
+}
+
+// Config is the scheduler configuration.
+type Config struct {
+	// Latitude and Longitude in degrees are needed for sun rules
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Holidays are dates as 2006-01-02 or ranges as
+	// 2006-12-24..2007-01-01
+	Holidays   []string      `json:"holidays"`
+	Retries    int           `json:"retries"`
+	RetryDelay time.Duration `json:"retryDelay"`
+	Rules      []Rule        `json:"rules"`
+}
+
+// rule is a Rule with its trigger parsed and devices resolved.
+type rule struct {
+	Rule
+	devices    []string
+	parameters pt.DeviceControlParameters
+	cron       *Cron
+	at         []int // minutes of the day
+	days       map[time.Weekday]bool
+	except     map[string]bool
+}
+
+// Scheduler applies rules to devices.
+type Scheduler struct {
+	Now        func() time.Time
+	Retries    int
+	RetryDelay time.Duration
+
+	client    *cloudcontrol.Client
+	latitude  float64
+	longitude float64
+	holidays  map[string]bool
+	rules     []*rule
+}
+
+// Action is a rule due at a time.
+type Action struct {
+	Rule string
+	Time time.Time
+}
+
+// dateLayout is the layout of holidays and exception dates.
+const dateLayout = "2006-01-02"
+
+var weekdays = map[string][]time.Weekday{
+	"sun":      {time.Sunday},
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend":  {time.Saturday, time.Sunday},
+}
+
+// dates parses dates and date ranges.
+func dates(values []string) (map[string]bool, error) {
+	result := map[string]bool{}
+	for _, value := range values {
+		bounds := strings.SplitN(value, "..", 2)
+		from, err := time.Parse(dateLayout, strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid date %q", value)
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, err = time.Parse(dateLayout, strings.TrimSpace(bounds[1])); err != nil || to.Before(from) {
+				return nil, fmt.Errorf("error: invalid date range %q", value)
+			}
+		}
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			result[d.Format(dateLayout)] = true
+		}
+	}
+	return result, nil
+}
+
+// New creates a Scheduler from the config. Device names, aliases and
+// groups of the rules are resolved to GUIDs once.
+func New(client *cloudcontrol.Client, config Config) (*Scheduler, error) {
+	holidays, err := dates(config.Holidays)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scheduler{
+		Now:        time.Now,
+		Retries:    config.Retries,
+		RetryDelay: config.RetryDelay,
+		client:     client,
+		latitude:   config.Latitude,
+		longitude:  config.Longitude,
+		holidays:   holidays,
+	}
+	if s.Retries == 0 {
+		s.Retries = DefaultRetries
+	}
+	if s.RetryDelay == 0 {
+		s.RetryDelay = DefaultRetryDelay
+	}
+
+	for i, r := range config.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled, err := s.compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("error: schedule rule %q: %w", r.Name, err)
+		}
+		s.rules = append(s.rules, compiled)
+	}
+
+	return s, nil
+}
+
+// compile validates a rule and resolves its devices.
+func (s *Scheduler) compile(r Rule) (*rule, error) {
+	compiled := &rule{Rule: r}
+	triggers := 0
+	if r.Cron != "" {
+		triggers++
+		var err error
+		if compiled.cron, err = ParseCron(r.Cron); err != nil {
+			return nil, err
+		}
+		if len(r.Days) > 0 {
+			return nil, fmt.Errorf("days can't be combined with cron")
+		}
+	}
+	if len(r.At) > 0 {
+		triggers++
+		for _, at := range r.At {
+			t, err := time.Parse("15:04", at)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time %q, use 15:04", at)
+			}
+			compiled.at = append(compiled.at, t.Hour()*60+t.Minute())
+		}
+	}
+	if r.Sun != "" {
+		triggers++
+		if r.Sun != "sunrise" && r.Sun != "sunset" {
+			return nil, fmt.Errorf("invalid sun %q, use sunrise or sunset", r.Sun)
+		}
+		if s.latitude == 0 && s.longitude == 0 {
+			return nil, fmt.Errorf("sun rules need latitude and longitude")
+		}
+	}
+	if triggers != 1 {
+		return nil, fmt.Errorf("needs exactly one of cron, at and sun")
+	}
+
+	if len(r.Days) > 0 {
+		compiled.days = map[time.Weekday]bool{}
+		for _, day := range r.Days {
+			days, found := weekdays[strings.ToLower(day)]
+			if !found {
+				return nil, fmt.Errorf("invalid day %q", day)
+			}
+			for _, d := range days {
+				compiled.days[d] = true
+			}
+		}
+	}
+	switch r.Holidays {
+	case "", Ignore, Skip, Only:
+	default:
+		return nil, fmt.Errorf("invalid holidays %q, use ignore, skip or only", r.Holidays)
+	}
+	var err error
+	if compiled.except, err = dates(r.Except); err != nil {
+		return nil, err
+	}
+	if compiled.parameters, err = r.State.Parameters(); err != nil {
+		return nil, err
+	}
+
+	if len(r.Devices) == 0 {
+		return nil, fmt.Errorf("no devices")
+	}
+	for _, device := range r.Devices {
+		guids, err := s.client.ResolveDevices(device)
+		if err != nil {
+			return nil, err
+		}
+		compiled.devices = append(compiled.devices, guids...)
+	}
+
+	return compiled, nil
+}
+
+// due reports whether the rule is due in the minute of t.
+func (s *Scheduler) due(r *rule, t time.Time) bool {
+	date := t.Format(dateLayout)
+	if r.except[date] {
+		return false
+	}
+	switch r.Holidays {
+	case Skip:
+		if s.holidays[date] {
+			return false
+		}
+	case Only:
+		if !s.holidays[date] {
+			return false
+		}
+	}
+
+	if r.cron != nil {
+		return r.cron.Matches(t)
+	}
+	if r.days != nil && !r.days[t.Weekday()] {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, at := range r.at {
+		if at == minute {
+			return true
+		}
+	}
+	if r.Sun != "" {
+		// The offset may move the time to another day
+		day := t.Add(-r.Offset)
+		sunrise, sunset, ok := Sun(day, s.latitude, s.longitude)
+		if !ok {
+			return false
+		}
+		at := sunrise
+		if r.Sun == "sunset" {
+			at = sunset
+		}
+		at = at.Add(r.Offset).Truncate(time.Minute)
+		return at.Equal(t.Truncate(time.Minute))
+	}
+	return false
+}
+
+// Due returns the names of the rules due in the minute of t.
+func (s *Scheduler) Due(t time.Time) []string {
+	names := []string{}
+	for _, r := range s.rules {
+		if s.due(r, t) {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// Next returns the next time every rule is due within limit after t,
+// rules that are not due within limit are left out.
+func (s *Scheduler) Next(t time.Time, limit time.Duration) []Action {
+	actions := []Action{}
+	start := t.Truncate(time.Minute).Add(time.Minute)
+	for _, r := range s.rules {
+		for m := start; m.Sub(t) <= limit; m = m.Add(time.Minute) {
+			if s.due(r, m) {
+				actions = append(actions, Action{Rule: r.Name, Time: m})
+				break
+			}
+		}
+	}
+	return actions
+}
+
+// Apply sets the state of the rule on its devices. Devices that fail are
+// retried Retries times, RetryDelay apart.
+func (s *Scheduler) Apply(ctx context.Context, name string) error {
+	for _, r := range s.rules {
+		if r.Name == name {
+			return s.apply(ctx, r)
+		}
+	}
+	return fmt.Errorf("error: schedule rule %q not found", name)
+}
+
+func (s *Scheduler) apply(ctx context.Context, r *rule) error {
+	devices := r.devices
+	var errs []error
+	for attempt := 0; ; attempt++ {
+		results := s.client.Each(devices, cloudcontrol.DefaultParallelism, func(c *cloudcontrol.Client) ([]byte, error) {
+			return c.SetState(r.parameters)
+		})
+		devices, errs = nil, nil
+		for _, result := range results {
+			if result.Err != nil {
+				devices = append(devices, result.DeviceGUID)
+				errs = append(errs, result.Err)
+				log.Warnf("%s: setting %s failed: %v", r.Name, result.DeviceGUID, result.Err)
+				continue
+			}
+			log.Infof("%s: set %s to %s", r.Name, result.DeviceGUID, describe(r.State))
+		}
+		if len(devices) == 0 {
+			return nil
+		}
+		if attempt >= s.Retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.RetryDelay):
+		}
+	}
+
+	return fmt.Errorf("error: schedule rule %q failed for %d device(s): %w", r.Name, len(devices), errs[0])
+}
+
+// describe formats a state for logging.
+func describe(state api.State) string {
+	parts := []string{}
+	if state.Power != nil {
+		parts = append(parts, "power "+*state.Power)
+	}
+	if state.Mode != nil {
+		parts = append(parts, "mode "+*state.Mode)
+	}
+	if state.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature %v", *state.Temperature))
+	}
+	if state.FanSpeed != nil {
+		parts = append(parts, "fan "+*state.FanSpeed)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Run applies the due rules every minute until the context is done.
+// Minutes missed by at most MaxCatchUp are caught up.
+func (s *Scheduler) Run(ctx context.Context) {
+	last := s.Now().Truncate(time.Minute)
+	for {
+		next := last.Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(s.Now())):
+		}
+
+		now := s.Now().Truncate(time.Minute)
+		if now.Sub(last) > MaxCatchUp {
+			log.Warnf("Skipping schedule between %s and %s", last.Format(time.RFC3339), now.Add(-MaxCatchUp).Format(time.RFC3339))
+			last = now.Add(-MaxCatchUp)
+		}
+		for m := last.Add(time.Minute); !m.After(now); m = m.Add(time.Minute) {
+			for _, r := range s.rules {
+				if s.due(r, m) {
+					log.Infof("%s: due at %s", r.Name, m.Format("2006-01-02 15:04"))
+					if err := s.apply(ctx, r); err != nil {
+						log.Error(err)
+					}
+				}
+			}
+		}
+		if now.After(last) {
+			last = now
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package schedule_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/schedule"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+var amsterdam = time.FixedZone("CEST", 2*60*60)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, amsterdam)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		time string
+		want bool
+	}{
+		{"* * * * *", "2021-06-21 05:17", true},
+		{"30 7 * * mon-fri", "2021-06-21 07:30", true},
+		{"30 7 * * mon-fri", "2021-06-20 07:30", false},
+		{"*/15 8-18/2 * * *", "2021-06-21 10:45", true},
+		{"*/15 8-18/2 * * *", "2021-06-21 09:45", false},
+		{"*/15 8-18/2 * * *", "2021-06-21 10:40", false},
+		{"0 6 1,15 jan-mar *", "2021-02-15 06:00", true},
+		{"0 6 1,15 jan-mar *", "2021-06-15 06:00", false},
+		{"0 0 * * 7", "2021-06-20 00:00", true},
+		// Day of month or day of week when both are restricted
+		{"0 0 13 * fri", "2021-06-18 00:00", true},
+		{"0 0 13 * fri", "2021-06-13 00:00", true},
+		{"0 0 13 * fri", "2021-06-14 00:00", false},
+	}
+	for _, tc := range tests {
+		c, err := schedule.ParseCron(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Matches(at(tc.time)); got != tc.want {
+			t.Errorf("%q at %s: got %v, want %v", tc.expr, tc.time, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * foo *", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestSun(t *testing.T) {
+	// Amsterdam on midsummer, sunrise at 5:18 and sunset at 22:06
+	sunrise, sunset, ok := schedule.Sun(at("2021-06-21 12:00"), 52.37, 4.89)
+	if !ok {
+		t.Fatal("no sunrise")
+	}
+	for _, tc := range []struct{ got, want time.Time }{{sunrise, at("2021-06-21 05:18")}, {sunset, at("2021-06-21 22:06")}} {
+		if d := tc.got.Sub(tc.want); d > 2*time.Minute || d < -2*time.Minute {
+			t.Errorf("got %v, want %v", tc.got, tc.want)
+		}
+	}
+	// Midnight sun in Tromsø
+	if _, _, ok := schedule.Sun(at("2021-06-21 12:00"), 69.65, 18.96); ok {
+		t.Error("sun sets in Tromsø on midsummer")
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestDue(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	off := api.State{Power: ptr("off")}
+	s, err := schedule.New(client, schedule.Config{
+		Latitude:  52.37,
+		Longitude: 4.89,
+		Holidays:  []string{"2021-12-24..2021-12-26"},
+		Rules: []schedule.Rule{
+			{Name: "workday", Devices: []string{"Living"}, At: []string{"06:30", "17:15"}, Days: []string{"weekdays"}, Holidays: schedule.Skip, Except: []string{"2021-12-01"}, State: off},
+			{Name: "holiday", Devices: []string{"Living"}, At: []string{"08:00"}, Holidays: schedule.Only, State: off},
+			{Name: "dusk", Devices: []string{"My House"}, Sun: "sunset", Offset: -30 * time.Minute, State: off},
+			{Name: "cron", Devices: []string{"Bedroom"}, Cron: "0 22 * * sat", State: off},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		time string
+		want []string
+	}{
+		{"2021-12-02 06:30", []string{"workday"}},
+		{"2021-12-02 17:15", []string{"workday"}},
+		{"2021-12-01 06:30", []string{}}, // exception date
+		{"2021-12-04 06:30", []string{}}, // saturday
+		{"2021-12-24 06:30", []string{}}, // holiday
+		{"2021-12-25 08:00", []string{"holiday"}},
+		{"2021-12-02 08:00", []string{}},
+		{"2021-12-04 22:00", []string{"cron"}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, s.Due(at(tc.time))); diff != "" {
+			t.Errorf("%s: due mismatch (-want +got):\n%s", tc.time, diff)
+		}
+	}
+
+	// Sunset is at 22:06 on midsummer, and the rule is due once a day
+	next := s.Next(at("2021-06-21 12:00"), 24*time.Hour)
+	for _, a := range next {
+		if a.Rule != "dusk" {
+			continue
+		}
+		if d := a.Time.Sub(at("2021-06-21 21:36")); d > 2*time.Minute || d < -2*time.Minute {
+			t.Errorf("dusk at %v, want about 21:36", a.Time)
+		}
+		if diff := cmp.Diff([]string{"dusk"}, s.Due(a.Time)); diff != "" {
+			t.Errorf("due at %v mismatch (-want +got):\n%s", a.Time, diff)
+		}
+		if diff := cmp.Diff([]string{}, s.Due(a.Time.Add(time.Minute))); diff != "" {
+			t.Errorf("due after %v mismatch (-want +got):\n%s", a.Time, diff)
+		}
+	}
+	names := []string{}
+	for _, a := range next {
+		names = append(names, a.Rule)
+	}
+	if diff := cmp.Diff([]string{"workday", "dusk"}, names); diff != "" {
+		t.Errorf("next actions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestApply(t *testing.T) {
+	c := cloudtest.Default()
+	client := testclient.New(t, c)
+	temperature := 19.0
+	s, err := schedule.New(client, schedule.Config{
+		RetryDelay: time.Millisecond,
+		Rules: []schedule.Rule{
+			{Name: "night", Devices: []string{"My House"}, Cron: "0 23 * * *", State: api.State{Power: ptr("on"), Temperature: &temperature}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first attempt of one device fails and is retried
+	c.AddFault(cloudtest.Fault{Path: pt.URLControl, Status: http.StatusServiceUnavailable, Count: 1})
+	if err := s.Apply(context.Background(), "night"); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.Commands()); got != 2 {
+		t.Errorf("got %d commands, want 2", got)
+	}
+
+	c.AddFault(cloudtest.Fault{Path: pt.URLControl, Status: http.StatusServiceUnavailable})
+	if err := s.Apply(context.Background(), "night"); err == nil {
+		t.Error("Apply with failing cloud succeeded, want error")
+	}
+	if err := s.Apply(context.Background(), "day"); err == nil {
+		t.Error("Apply of unknown rule succeeded, want error")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	off := api.State{Power: ptr("off")}
+	for _, r := range []schedule.Rule{
+		{Devices: []string{"Living"}, State: off},
+		{Devices: []string{"Living"}, At: []string{"7:00"}, Cron: "0 7 * * *", State: off},
+		{Devices: []string{"Living"}, At: []string{"25:00"}, State: off},
+		{Devices: []string{"Living"}, At: []string{"07:00"}, Days: []string{"someday"}, State: off},
+		{Devices: []string{"Living"}, Cron: "0 7 * * *", Days: []string{"mon"}, State: off},
+		{Devices: []string{"Living"}, Sun: "sunrise", State: off},
+		{Devices: []string{"Living"}, At: []string{"07:00"}, Holidays: "sometimes", State: off},
+		{Devices: []string{"Living"}, At: []string{"07:00"}},
+		{Devices: []string{"Attic"}, At: []string{"07:00"}, State: off},
+		{At: []string{"07:00"}, State: off},
+	} {
+		if _, err := schedule.New(client, schedule.Config{Rules: []schedule.Rule{r}}); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", r)
+		}
+	}
+	if _, err := schedule.New(client, schedule.Config{Holidays: []string{"2021-12-26..2021-12-24"}}); err == nil {
+		t.Error("New with reversed holiday range succeeded, want error")
+	}
+}
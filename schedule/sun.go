@@ -0,0 +1,51 @@
+package schedule
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	julianUnixEpoch = 2440587.5 // julian day of 1970-01-01 00:00 UTC
+	julian2000      = 2451545.0 // julian day of 2000-01-01 12:00 UTC
+)
+
+func julian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + julianUnixEpoch
+}
+
+func fromJulian(j float64, loc *time.Location) time.Time {
+	return time.Unix(int64(math.Round((j-julianUnixEpoch)*86400)), 0).In(loc)
+}
+
+func radians(degrees float64) float64 { return degrees * math.Pi / 180 }
+func degrees(radians float64) float64 { return radians * 180 / math.Pi }
+
+// Sun returns the sunrise and sunset on the day of date in its location,
+// computed with the sunrise equation for the latitude and longitude in
+// degrees (east and north positive). The result is accurate to about a
+// minute. ok is false when the sun does not rise or set that day.
+func Sun(date time.Time, latitude, longitude float64) (sunrise, sunset time.Time, ok bool) {
+	y, m, d := date.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, date.Location())
+	n := math.Round(julian(noon) - julian2000 + longitude/360)
+
+	// Mean solar time, solar mean anomaly and equation of the center
+	meanTime := n - longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanTime, 360)
+	center := 1.9148*math.Sin(radians(anomaly)) + 0.02*math.Sin(radians(2*anomaly)) + 0.0003*math.Sin(radians(3*anomaly))
+	// Ecliptic longitude and solar transit
+	ecliptic := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := julian2000 + meanTime + 0.0053*math.Sin(radians(anomaly)) - 0.0069*math.Sin(radians(2*ecliptic))
+	// Declination of the sun and hour angle at sunrise, including
+	// refraction and the size of the sun
+	declination := math.Asin(math.Sin(radians(ecliptic)) * math.Sin(radians(23.4397)))
+	cos := (math.Sin(radians(-0.833)) - math.Sin(radians(latitude))*math.Sin(declination)) /
+		(math.Cos(radians(latitude)) * math.Cos(declination))
+	if cos < -1 || cos > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := degrees(math.Acos(cos))
+
+	return fromJulian(transit-hourAngle/360, date.Location()), fromJulian(transit+hourAngle/360, date.Location()), true
+}
@@ -0,0 +1,316 @@
+// Package solar runs Panasonic devices on surplus solar power. The power
+// exported to the grid is read from an inverter or meter, and devices
+// are turned on or boosted with a raised (heating) or lowered (cooling)
+// setpoint and Powerful mode when the export exceeds their thresholds.
+package solar
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultHysteresis  = 500
+	DefaultBoostOffset = 2
+	DefaultMinOn       = 15 * time.Minute
+	DefaultMinOff      = 5 * time.Minute
+	DefaultInterval    = time.Minute
+)
+
+// States of a device
+const (
+	Idle    = "idle"
+	Running = "on"
+	Boosted = "boost"
+)
+
+// DeviceConfig configures a device.
+type DeviceConfig struct {
+	// Device is a GUID, name or alias of a single device
+	Device string `json:"device"`
+	// Mode is heat or cool, heat when empty
+	Mode string `json:"mode"`
+	// Temperature is the setpoint of a device turned on by surplus
+	Temperature float64 `json:"temperature"`
+	// Start is the export in W at which the device is turned on, Boost
+	// the export at which it is boosted, never when 0
+	Start float64 `json:"start"`
+	Boost float64 `json:"boost"`
+	// Hysteresis is how far in W the export must drop below Start or Boost
+	// before the device is turned off or the boost ends. It should be
+	// more than the power the device draws.
+	Hysteresis float64 `json:"hysteresis"`
+	// BoostOffset is added to the setpoint when heating and subtracted
+	// when cooling during a boost
+	BoostOffset float64 `json:"boostOffset"`
+	// Powerful enables Powerful mode during a boost
+	Powerful bool          `json:"powerful"`
+	MinOn    time.Duration `json:"minOn"`
+	MinOff   time.Duration `json:"minOff"`
+}
+
+// Config configures a Controller.
+type Config struct {
+	Source   SourceConfig   `json:"source"`
+	Interval time.Duration  `json:"interval"`
+	Devices  []DeviceConfig `json:"devices"`
+}
+
+// Result is the outcome of a step for a device.
+type Result struct {
+	DeviceGUID string
+	DeviceName string
+	State      string
+	// Changed is true when the device was changed in the step
+	Changed bool
+	Err     error
+}
+
+// device is a DeviceConfig with its device resolved and its state.
+type device struct {
+	DeviceConfig
+	guid, name string
+	client     cloudcontrol.Client
+	min, max   float64 // setpoint range in mode
+	state      string
+	owned      bool      // turned on by the controller
+	changed    time.Time // last state change
+	setpoint   float64   // setpoint before the boost
+	powerful   bool      // Powerful mode before the boost
+}
+
+// Controller runs devices on surplus solar power.
+type Controller struct {
+	Config
+	Now func() time.Time
+
+	source  Source
+	devices []*device
+}
+
+// New creates a Controller, the devices of the config are resolved once.
+func New(client *cloudcontrol.Client, config Config, source Source) (*Controller, error) {
+	if len(config.Devices) == 0 {
+		return nil, fmt.Errorf("error: no solar devices")
+	}
+	if config.Interval < 0 {
+		return nil, fmt.Errorf("error: solar interval must be positive")
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+
+	c := &Controller{Now: time.Now, source: source}
+	for _, dc := range config.Devices {
+		if dc.Mode == "" {
+			dc.Mode = "heat"
+		}
+		if dc.Mode != "heat" && dc.Mode != "cool" {
+			return nil, fmt.Errorf("error: solar device %q has mode %q, want heat or cool", dc.Device, dc.Mode)
+		}
+		if dc.Hysteresis == 0 {
+			dc.Hysteresis = DefaultHysteresis
+		}
+		if dc.BoostOffset == 0 {
+			dc.BoostOffset = DefaultBoostOffset
+		}
+		if dc.MinOn == 0 {
+			dc.MinOn = DefaultMinOn
+		}
+		if dc.MinOff == 0 {
+			dc.MinOff = DefaultMinOff
+		}
+		if dc.Start <= 0 || dc.Hysteresis < 0 || dc.Boost != 0 && dc.Boost <= dc.Start {
+			return nil, fmt.Errorf("error: solar device %q needs start > 0 and boost above start", dc.Device)
+		}
+		if dc.BoostOffset < 0 || dc.MinOn < 0 || dc.MinOff < 0 {
+			return nil, fmt.Errorf("error: solar device %q has a negative boost offset or minimum time", dc.Device)
+		}
+
+		found, err := client.FindDevice(dc.Device)
+		if err != nil {
+			return nil, fmt.Errorf("error: solar device %q: %w", dc.Device, err)
+		}
+		d := &device{DeviceConfig: dc, guid: found.DeviceGUID, name: found.DeviceName, client: *client, state: Idle}
+		d.min, d.max = float64(found.HeatTempMin), float64(found.HeatTempMax)
+		if dc.Mode == "cool" {
+			d.min, d.max = float64(found.CoolTempMin), float64(found.CoolTempMax)
+		}
+		if dc.Temperature < d.min || dc.Temperature > d.max {
+			return nil, fmt.Errorf("error: solar device %q temperature %v is not between %v and %v", dc.Device, dc.Temperature, d.min, d.max)
+		}
+		d.client.SetDevice(d.guid)
+		c.devices = append(c.devices, d)
+	}
+	c.Config = config
+
+	return c, nil
+}
+
+// boosted returns the setpoint during a boost from a setpoint.
+func (d *device) boosted(setpoint float64, mode int) float64 {
+	switch mode {
+	case pt.Modes["heat"]:
+		setpoint += d.BoostOffset
+	case pt.Modes["cool"]:
+		setpoint -= d.BoostOffset
+	}
+	return math.Round(math.Max(d.min, math.Min(d.max, setpoint))*2) / 2
+}
+
+// decide returns the new state and the parameters to change for the
+// export.
+func (d *device) decide(export float64, p pt.DeviceParameters, now time.Time) (string, pt.DeviceControlParameters) {
+	control := pt.DeviceControlParameters{}
+	on := p.Operate == 1
+	since := now.Sub(d.changed)
+
+	// Turned off by someone else
+	if d.state != Idle && !on {
+		d.owned = false
+		return Idle, control
+	}
+
+	switch d.state {
+	case Idle:
+		if export < d.Start {
+			return Idle, control
+		}
+		if on {
+			// Already running, it can still be boosted
+			return Running, control
+		}
+		if since < d.MinOff {
+			log.Debugf("%s: keeping off for the minimum off time", d.name)
+			return Idle, control
+		}
+		operate, mode, temperature := 1, pt.Modes[d.Mode], d.Temperature
+		control.Operate, control.OperationMode, control.TemperatureSet = &operate, &mode, &temperature
+		d.owned = true
+		return Running, control
+
+	case Running:
+		if export < d.Start-d.Hysteresis {
+			if since < d.MinOn {
+				return Running, control
+			}
+			if d.owned {
+				operate := 0
+				control.Operate = &operate
+			}
+			d.owned = false
+			return Idle, control
+		}
+		if d.Boost == 0 || export < d.Boost {
+			return Running, control
+		}
+		d.setpoint, d.powerful = p.TemperatureSet, p.PowerfulMode
+		if t := d.boosted(p.TemperatureSet, p.OperationMode); t != p.TemperatureSet {
+			control.TemperatureSet = &t
+		}
+		if d.Powerful && !p.PowerfulMode {
+			powerful := true
+			control.PowerfulMode = &powerful
+		}
+		return Boosted, control
+
+	case Boosted:
+		if export >= d.Boost-d.Hysteresis || since < d.MinOn {
+			return Boosted, control
+		}
+		if p.TemperatureSet != d.setpoint {
+			setpoint := d.setpoint
+			control.TemperatureSet = &setpoint
+		}
+		if p.PowerfulMode != d.powerful {
+			powerful := d.powerful
+			control.PowerfulMode = &powerful
+		}
+		if export < d.Start-d.Hysteresis && d.owned {
+			operate := 0
+			control.Operate = &operate
+			d.owned = false
+			return Idle, control
+		}
+		if export < d.Start-d.Hysteresis {
+			return Idle, control
+		}
+		return Running, control
+	}
+
+	return d.state, control
+}
+
+// Step reads the export and changes the devices. A failed reading
+// counts as no export, so devices wind down when the source is gone,
+// and is returned with the results.
+func (c *Controller) Step(ctx context.Context) ([]Result, error) {
+	export, err := c.source.Export(ctx)
+	if err != nil {
+		export = 0
+	}
+	now := c.Now()
+
+	results := []Result{}
+	for _, d := range c.devices {
+		result := Result{DeviceGUID: d.guid, DeviceName: d.name, State: d.state}
+		status, statusErr := d.client.GetDeviceStatus()
+		if statusErr == nil && !status.Parameters.Online {
+			statusErr = cloudcontrol.ErrDeviceOffline
+		}
+		if statusErr != nil {
+			result.Err = statusErr
+			results = append(results, result)
+			continue
+		}
+
+		owned := d.owned
+		state, control := d.decide(export, status.Parameters, now)
+		if control != (pt.DeviceControlParameters{}) {
+			if _, err := d.client.SetState(control); err != nil {
+				d.owned = owned
+				result.Err = err
+				results = append(results, result)
+				continue
+			}
+			result.Changed = true
+			log.Infof("%s: export %.0f W, %s", d.name, export, control.Describe())
+		}
+		if state != d.state {
+			d.state, d.changed = state, now
+		}
+		result.State = d.state
+		results = append(results, result)
+	}
+
+	return results, err
+}
+
+// Run changes the devices every Interval until the context is done.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		results, err := c.Step(ctx)
+		if err != nil {
+			log.Warnf("Reading export: %v", err)
+		}
+		for _, r := range results {
+			if r.Err != nil {
+				log.Warnf("%s: %v", r.DeviceName, r.Err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
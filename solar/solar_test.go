@@ -0,0 +1,238 @@
+package solar_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/solar"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const (
+	living  = "CS-Z25XKEW+4321"
+	bedroom = "CS-TZ20WKEW+8765"
+)
+
+func TestController(t *testing.T) {
+	c := cloudtest.Default()
+	if err := c.Update(bedroom, func(d *pt.Device) { d.Parameters.Operate = 1 }); err != nil {
+		t.Fatal(err)
+	}
+	client := testclient.New(t, c)
+
+	var export float64
+	controller, err := solar.New(client, solar.Config{Devices: []solar.DeviceConfig{
+		{Device: "Living", Temperature: 22, Start: 1500, Boost: 3000, Powerful: true, MinOn: 10 * time.Minute},
+		{Device: "Bedroom", Mode: "cool", Temperature: 24, Start: 1000, Boost: 2000},
+	}}, solar.SourceFunc(func(context.Context) (float64, error) { return export, nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+	var now time.Time
+	controller.Now = func() time.Time { return now }
+
+	// The living room is turned on and boosted by the controller, the
+	// bedroom is already on and only boosted
+	steps := []struct {
+		minute  int
+		export  float64
+		living  string
+		bedroom string
+	}{
+		{0, 2000, solar.Running, solar.Running},
+		{1, 800, solar.Running, solar.Running},
+		{2, 3500, solar.Boosted, solar.Boosted},
+		{5, 2800, solar.Boosted, solar.Boosted},
+		{13, 2000, solar.Running, solar.Boosted},
+		{14, 500, solar.Running, solar.Boosted},
+		{30, 500, solar.Idle, solar.Running},
+		{32, 2000, solar.Idle, solar.Boosted},
+		{40, 2000, solar.Running, solar.Boosted},
+	}
+	for _, s := range steps {
+		now, export = start.Add(time.Duration(s.minute)*time.Minute), s.export
+		results, err := controller.Step(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for _, r := range results {
+			if r.Err != nil {
+				t.Fatalf("minute %d: %s: %v", s.minute, r.DeviceName, r.Err)
+			}
+			got[r.DeviceName] = r.State
+		}
+		if diff := cmp.Diff(map[string]string{"Living": s.living, "Bedroom": s.bedroom}, got); diff != "" {
+			t.Errorf("minute %d mismatch (-want +got):\n%s", s.minute, diff)
+		}
+	}
+
+	d, _ := c.Status(living)
+	if d.Parameters.Operate != 1 || d.Parameters.TemperatureSet != 22 || d.Parameters.PowerfulMode {
+		t.Errorf("living room is %+v, want on at 22 without Powerful mode", d.Parameters)
+	}
+	d, _ = c.Status(bedroom)
+	if d.Parameters.TemperatureSet != 22 {
+		t.Errorf("bedroom setpoint is %v, want boosted to 22", d.Parameters.TemperatureSet)
+	}
+	for _, command := range c.Commands() {
+		if command.DeviceGUID == bedroom && command.Parameters.Operate != nil {
+			t.Errorf("bedroom was switched by %+v, want only setpoint changes", command.Parameters)
+		}
+	}
+
+	// Without a reading the devices wind down
+	export = 0
+	failing := solar.SourceFunc(func(context.Context) (float64, error) { return 0, errors.New("meter offline") })
+	controller, err = solar.New(client, solar.Config{Devices: []solar.DeviceConfig{
+		{Device: "Bedroom", Mode: "cool", Temperature: 24, Start: 1000},
+	}}, failing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := controller.Step(context.Background())
+	if err == nil || len(results) != 1 || results[0].State != solar.Idle {
+		t.Errorf("Step() = %+v, %v, want idle and the source error", results, err)
+	}
+}
+
+// serveModbus answers one read request with the registers.
+func serveModbus(t *testing.T, registers []uint16) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, 12)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		function, start := request[7], binary.BigEndian.Uint16(request[8:])
+		response := []byte{request[0], request[1], 0, 0, 0, 0, request[6], function}
+		if function != 4 || int(start)+int(binary.BigEndian.Uint16(request[10:])) > len(registers) {
+			response = append(response[:7], function|0x80, 2)
+		} else {
+			count := binary.BigEndian.Uint16(request[10:])
+			response = append(response, byte(2*count))
+			for _, r := range registers[start : start+count] {
+				response = binary.BigEndian.AppendUint16(response, r)
+			}
+		}
+		binary.BigEndian.PutUint16(response[4:], uint16(len(response)-6))
+		conn.Write(response)
+	}()
+	return l.Addr().String()
+}
+
+func TestModbus(t *testing.T) {
+	bits := math.Float32bits(1234.5)
+	registers := []uint16{0xfa24 /* -1500 */, 0x0001, 0x0002, uint16(bits >> 16), uint16(bits)}
+	tests := []struct {
+		config solar.SourceConfig
+		want   float64
+	}{
+		{solar.SourceConfig{Register: 0, Input: true}, -1500},
+		{solar.SourceConfig{Register: 0, Input: true, Scale: -1}, 1500},
+		{solar.SourceConfig{Register: 0, Input: true, Format: solar.Uint16}, 64036},
+		{solar.SourceConfig{Register: 1, Input: true, Format: solar.Uint32}, 65538},
+		{solar.SourceConfig{Register: 3, Input: true, Format: solar.Float32, Scale: 2}, 2469},
+	}
+	for _, tc := range tests {
+		tc.config.Type = solar.Modbus
+		tc.config.Address = serveModbus(t, registers)
+		source, err := tc.config.Source(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := source.Export(context.Background())
+		if err != nil {
+			t.Errorf("%+v: %v", tc.config, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%+v: got %v, want %v", tc.config, got, tc.want)
+		}
+	}
+
+	// Holding registers are not served, so the read fails
+	source, err := solar.SourceConfig{Type: solar.Modbus, Address: serveModbus(t, registers)}.Source(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Export(context.Background()); err == nil {
+		t.Error("Export() of a holding register succeeded, want modbus exception")
+	}
+
+	for _, config := range []solar.SourceConfig{
+		{Type: solar.Modbus},
+		{Type: solar.Modbus, Address: "localhost:502", Format: "int64"},
+		{Type: "http"},
+		{Type: "serial"},
+	} {
+		if _, err := config.Source(nil); err == nil {
+			t.Errorf("Source(%+v) succeeded, want error", config)
+		}
+	}
+}
+
+func TestDeviceRange(t *testing.T) {
+	// A unit heating from 10 to 24°C as the cloud reports it
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"Cabin","deviceList":[{"deviceGuid":"device1","deviceName":"Cabin","heatMode":true,"coolMode":true,"coolTempMin":18,"coolTempMax":30,"heatTempMin":10,"heatTempMax":24}]}]}`))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := cloudcontrol.NewClient(server.URL)
+	export := solar.SourceFunc(func(context.Context) (float64, error) { return 0, nil })
+
+	for _, tc := range []struct {
+		dc solar.DeviceConfig
+		ok bool
+	}{
+		{solar.DeviceConfig{Device: "Cabin", Temperature: 12, Start: 1500}, true},
+		{solar.DeviceConfig{Device: "Cabin", Temperature: 26, Start: 1500}, false},
+		{solar.DeviceConfig{Device: "Cabin", Mode: "cool", Temperature: 29, Start: 1500}, true},
+		{solar.DeviceConfig{Device: "Cabin", Mode: "cool", Temperature: 17, Start: 1500}, false},
+	} {
+		if _, err := solar.New(&client, solar.Config{Devices: []solar.DeviceConfig{tc.dc}}, export); (err == nil) != tc.ok {
+			t.Errorf("New(%+v) = %v, want success %v", tc.dc, err, tc.ok)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	export := solar.SourceFunc(func(context.Context) (float64, error) { return 0, nil })
+	for _, dc := range []solar.DeviceConfig{
+		{Device: "Living", Temperature: 22},
+		{Device: "Living", Temperature: 22, Start: 1500, Boost: 1000},
+		{Device: "Living", Mode: "dry", Temperature: 22, Start: 1500},
+		{Device: "Living", Temperature: 40, Start: 1500},
+		{Device: "Attic", Temperature: 22, Start: 1500},
+	} {
+		if _, err := solar.New(client, solar.Config{Devices: []solar.DeviceConfig{dc}}, export); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", dc)
+		}
+	}
+}
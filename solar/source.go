@@ -0,0 +1,195 @@
+package solar
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/thermostat"
+)
+
+// Modbus is the source type reading an inverter or meter register over
+// Modbus TCP. The other source types are those of the thermostat
+// package.
+const Modbus = "modbus"
+
+// DefaultTimeout is the timeout of a Modbus request.
+const DefaultTimeout = 5 * time.Second
+
+// Register formats
+const (
+	Int16   = "int16"
+	Uint16  = "uint16"
+	Int32   = "int32"
+	Uint32  = "uint32"
+	Float32 = "float32"
+)
+
+// Source reads the power exported to the grid in W.
+type Source interface {
+	Export(ctx context.Context) (float64, error)
+}
+
+// SourceFunc is a function used as Source.
+type SourceFunc func(ctx context.Context) (float64, error)
+
+// Export implements Source.
+func (f SourceFunc) Export(ctx context.Context) (float64, error) {
+	return f(ctx)
+}
+
+// SourceConfig configures a Source. The reading is multiplied by Scale,
+// use a negative Scale for meters reporting import as positive.
+type SourceConfig struct {
+	// Type is modbus, http, mqtt, file or command
+	Type    string            `json:"type"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Topic   string            `json:"topic"`
+	Path    string            `json:"path"`
+	Command []string          `json:"command"`
+	Field   string            `json:"field"`
+	MaxAge  time.Duration     `json:"maxAge"`
+	// Address is the host:port of the Modbus TCP server
+	Address string `json:"address"`
+	Unit    int    `json:"unit"`
+	// Register is the address of the first register, read as a holding
+	// register or, with Input, as an input register
+	Register int     `json:"register"`
+	Input    bool    `json:"input"`
+	Format   string  `json:"format"`
+	Scale    float64 `json:"scale"`
+}
+
+// Source creates the Source. The broker is only used by MQTT sources.
+func (sc SourceConfig) Source(broker bridge.Broker) (Source, error) {
+	scale := sc.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	var source Source
+	if sc.Type == Modbus {
+		if sc.Address == "" {
+			return nil, fmt.Errorf("error: modbus source needs an address")
+		}
+		if sc.Format == "" {
+			sc.Format = Int16
+		}
+		if registers(sc.Format) == 0 {
+			return nil, fmt.Errorf("error: unknown register format %q", sc.Format)
+		}
+		if sc.Register < 0 || sc.Register > math.MaxUint16 || sc.Unit < 0 || sc.Unit > 255 {
+			return nil, fmt.Errorf("error: modbus register or unit out of range")
+		}
+		source = &ModbusSource{Address: sc.Address, Unit: byte(sc.Unit), Register: uint16(sc.Register), Input: sc.Input, Format: sc.Format, Timeout: DefaultTimeout}
+	} else {
+		s, err := thermostat.SourceConfig{
+			Type:    sc.Type,
+			Path:    sc.Path,
+			URL:     sc.URL,
+			Headers: sc.Headers,
+			Topic:   sc.Topic,
+			Command: sc.Command,
+			Field:   sc.Field,
+			MaxAge:  sc.MaxAge,
+		}.Source(broker)
+		if err != nil {
+			return nil, err
+		}
+		source = SourceFunc(s.Temperature)
+	}
+
+	return SourceFunc(func(ctx context.Context) (float64, error) {
+		v, err := source.Export(ctx)
+		return v * scale, err
+	}), nil
+}
+
+// registers returns the number of registers of a format, 0 when the
+// format is unknown.
+func registers(format string) int {
+	switch format {
+	case Int16, Uint16:
+		return 1
+	case Int32, Uint32, Float32:
+		return 2
+	}
+	return 0
+}
+
+// ModbusSource reads a register of a Modbus TCP server. Values of two
+// registers are big-endian with the high word first.
+type ModbusSource struct {
+	Address  string
+	Unit     byte
+	Register uint16
+	Input    bool
+	Format   string
+	Timeout  time.Duration
+}
+
+// Export implements Source.
+func (s *ModbusSource) Export(ctx context.Context) (float64, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.Address)
+	if err != nil {
+		return 0, fmt.Errorf("error: connecting to %s: %w", s.Address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	function := byte(3)
+	if s.Input {
+		function = 4
+	}
+	count := registers(s.Format)
+	request := make([]byte, 12)
+	binary.BigEndian.PutUint16(request[0:], 1) // transaction
+	binary.BigEndian.PutUint16(request[4:], 6) // length of unit and PDU
+	request[6] = s.Unit
+	request[7] = function
+	binary.BigEndian.PutUint16(request[8:], s.Register)
+	binary.BigEndian.PutUint16(request[10:], uint16(count))
+	if _, err := conn.Write(request); err != nil {
+		return 0, fmt.Errorf("error: writing to %s: %w", s.Address, err)
+	}
+
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, fmt.Errorf("error: reading from %s: %w", s.Address, err)
+	}
+	if header[7] == function|0x80 {
+		return 0, fmt.Errorf("error: modbus exception %d reading register %d", header[8], s.Register)
+	}
+	if header[7] != function || int(header[8]) != 2*count {
+		return 0, fmt.Errorf("error: invalid modbus response from %s", s.Address)
+	}
+	data := make([]byte, 2*count)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return 0, fmt.Errorf("error: reading from %s: %w", s.Address, err)
+	}
+
+	switch s.Format {
+	case Int16:
+		return float64(int16(binary.BigEndian.Uint16(data))), nil
+	case Uint16:
+		return float64(binary.BigEndian.Uint16(data)), nil
+	case Int32:
+		return float64(int32(binary.BigEndian.Uint32(data))), nil
+	case Uint32:
+		return float64(binary.BigEndian.Uint32(data)), nil
+	}
+	return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+}
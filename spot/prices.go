@@ -0,0 +1,190 @@
+package spot
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Price is the price per kWh of the hour starting at Start.
+type Price struct {
+	Start time.Time `json:"start"`
+	Price float64   `json:"price"`
+}
+
+// Prices are hourly prices sorted by time.
+type Prices []Price
+
+// timeLayouts are the accepted layouts of CSV start times, without a
+// zone the local time is used.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02 15:04:05"}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("error: invalid time %q", value)
+}
+
+// ParseCSV reads prices from CSV with the start time and price of an
+// hour on every line. A header line is skipped.
+func ParseCSV(r io.Reader) (Prices, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error: reading prices: %w", err)
+	}
+	prices := Prices{}
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("error: line %d of prices has %d fields, want start and price", i+1, len(record))
+		}
+		start, err := parseTime(strings.TrimSpace(record[0]))
+		if err != nil && i == 0 {
+			continue // header
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error: line %d of prices: %w", i+1, err)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("error: line %d of prices: invalid price %q", i+1, record[1])
+		}
+		prices = append(prices, Price{Start: start, Price: price})
+	}
+	return prices.sorted(), nil
+}
+
+// ParseJSON reads prices from a JSON array of objects with a start and
+// price, or an object with such an array in prices.
+func ParseJSON(data []byte) (Prices, error) {
+	prices := Prices{}
+	if err := json.Unmarshal(data, &prices); err != nil {
+		wrapped := struct {
+			Prices Prices `json:"prices"`
+		}{}
+		if json.Unmarshal(data, &wrapped) != nil {
+			return nil, fmt.Errorf("error: parsing prices: %w", err)
+		}
+		prices = wrapped.Prices
+	}
+	return prices.sorted(), nil
+}
+
+// Load reads prices from an http or https URL, a .csv file or a JSON
+// file. HTTP responses are read as CSV when their content type is
+// text/csv.
+func Load(ctx context.Context, source string) (Prices, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error: fetching prices: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error: fetching prices: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("error: fetching prices: %s", resp.Status)
+		}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+			return ParseCSV(strings.NewReader(string(data)))
+		}
+		return ParseJSON(data)
+	}
+
+	if strings.HasSuffix(strings.ToLower(source), ".csv") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("error: reading prices: %w", err)
+		}
+		defer f.Close()
+		return ParseCSV(f)
+	}
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("error: reading prices: %w", err)
+	}
+	return ParseJSON(data)
+}
+
+func (p Prices) sorted() Prices {
+	sort.SliceStable(p, func(i, j int) bool { return p[i].Start.Before(p[j].Start) })
+	return p
+}
+
+// At returns the price of the hour containing t.
+func (p Prices) At(t time.Time) (float64, bool) {
+	for _, price := range p {
+		if !t.Before(price.Start) && t.Before(price.Start.Add(time.Hour)) {
+			return price.Price, true
+		}
+	}
+	return 0, false
+}
+
+// From returns the prices of the hour containing t and later.
+func (p Prices) From(t time.Time) Prices {
+	for i, price := range p {
+		if t.Before(price.Start.Add(time.Hour)) {
+			return p[i:]
+		}
+	}
+	return Prices{}
+}
+
+// Synthetic returns hourly prices from the hour of start with a typical
+// day-ahead shape: cheap at night and midday, expensive in the morning
+// and evening peaks. It is a stand-in for a price feed in tests and
+// with the fakecloud command.
+func Synthetic(start time.Time, hours int) Prices {
+	start = start.Truncate(time.Hour)
+	prices := make(Prices, hours)
+	for i := range prices {
+		t := start.Add(time.Duration(i) * time.Hour)
+		h := float64(t.Hour())
+		peaks := math.Exp(-math.Pow(h-8, 2)/4) + 1.3*math.Exp(-math.Pow(h-19, 2)/5)
+		solar := 0.6 * math.Exp(-math.Pow(h-13, 2)/6)
+		price := 0.15 + 0.12*peaks - 0.06*solar
+		if h < 6 {
+			price -= 0.04
+		}
+		prices[i] = Price{Start: t, Price: math.Round(price*10000) / 10000}
+	}
+	return prices
+}
+
+// Handler serves prices as JSON, or as CSV with ?format=csv.
+func Handler(prices func() Prices) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := prices()
+		if r.URL.Query().Get("format") == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			out := csv.NewWriter(w)
+			out.Write([]string{"start", "price"})
+			for _, price := range p {
+				out.Write([]string{price.Start.Format(time.RFC3339), strconv.FormatFloat(price.Price, 'f', -1, 64)})
+			}
+			out.Flush()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	})
+}
@@ -0,0 +1,332 @@
+// Package spot plans the operation of Panasonic devices on hourly spot
+// prices. Devices are pre-heated or pre-cooled in cheap hours before
+// expensive hours and set back in the expensive hours, within comfort
+// bounds per device. Plans are applied with the schedule package and
+// the savings are estimated from the consumption history.
+package spot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/schedule"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/tariff"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// Defaults used by New
+const (
+	DefaultCheap     = 0.25
+	DefaultExpensive = 0.75
+	DefaultWindow    = 4 * time.Hour
+	DefaultShift     = 0.5
+)
+
+// Reasons of plan steps
+const (
+	Normal  = "normal"
+	Boost   = "boost"
+	Setback = "setback"
+)
+
+// Profile are the comfort bounds of a device.
+type Profile struct {
+	// Device is a GUID, name or alias of a single device
+	Device string `json:"device"`
+	// Mode is heat or cool, heat when empty
+	Mode string `json:"mode"`
+	// Comfort is the normal setpoint, Min and Max the setpoints used
+	// for setback and boost
+	Comfort float64 `json:"comfort"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+}
+
+// Config configures an Optimizer.
+type Config struct {
+	// Prices is a price URL or file, see Load
+	Prices string `json:"prices"`
+	// Hours with a price at or below the Cheap quantile of the plan are
+	// cheap, at or above the Expensive quantile expensive
+	Cheap     float64 `json:"cheap"`
+	Expensive float64 `json:"expensive"`
+	// Window is how long before an expensive hour cheap hours boost
+	Window time.Duration `json:"window"`
+	// Shift is the part of the consumption of a setback hour that moves
+	// to the boost hours before it, for the savings estimate
+	Shift   float64   `json:"shift"`
+	Devices []Profile `json:"devices"`
+}
+
+// Step is a setpoint of a device from Start until End.
+type Step struct {
+	DeviceGUID  string
+	DeviceName  string
+	Start       time.Time
+	End         time.Time
+	Mode        string
+	Temperature float64
+	Reason      string
+	// Price is the average price of the hours of the step
+	Price float64
+}
+
+// Plan are the steps of all devices sorted by start time.
+type Plan struct {
+	Steps []Step
+}
+
+// Saving is the estimated cost of a device with and without the plan.
+type Saving struct {
+	DeviceGUID  string
+	DeviceName  string
+	Consumption float64
+	Baseline    float64
+	Optimized   float64
+}
+
+// device is a Profile with its device resolved.
+type device struct {
+	Profile
+	guid, name string
+}
+
+// Optimizer plans devices on spot prices.
+type Optimizer struct {
+	Config
+	client  *cloudcontrol.Client
+	devices []device
+}
+
+// New creates an Optimizer, the devices of the config are resolved once.
+func New(client *cloudcontrol.Client, config Config) (*Optimizer, error) {
+	if config.Cheap == 0 {
+		config.Cheap = DefaultCheap
+	}
+	if config.Expensive == 0 {
+		config.Expensive = DefaultExpensive
+	}
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	if config.Shift == 0 {
+		config.Shift = DefaultShift
+	}
+	if config.Cheap < 0 || config.Cheap >= config.Expensive || config.Expensive > 1 {
+		return nil, fmt.Errorf("error: cheap and expensive must be quantiles with cheap below expensive")
+	}
+	if config.Window < 0 || config.Shift < 0 || config.Shift > 1 {
+		return nil, fmt.Errorf("error: window must be positive and shift between 0 and 1")
+	}
+	if len(config.Devices) == 0 {
+		return nil, fmt.Errorf("error: no devices to optimize")
+	}
+
+	o := &Optimizer{Config: config, client: client}
+	for _, p := range config.Devices {
+		if p.Mode == "" {
+			p.Mode = "heat"
+		}
+		if p.Mode != "heat" && p.Mode != "cool" {
+			return nil, fmt.Errorf("error: device %q has mode %q, want heat or cool", p.Device, p.Mode)
+		}
+		if p.Min > p.Comfort || p.Comfort > p.Max || p.Min <= 0 {
+			return nil, fmt.Errorf("error: device %q needs min <= comfort <= max", p.Device)
+		}
+		found, err := client.FindDevice(p.Device)
+		if err != nil {
+			return nil, err
+		}
+		o.devices = append(o.devices, device{Profile: p, guid: found.DeviceGUID, name: found.DeviceName})
+	}
+
+	return o, nil
+}
+
+// quantile returns the q quantile of sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	return sorted[int(math.Round(q*float64(len(sorted)-1)))]
+}
+
+// reasons classifies the hours of the prices.
+func (o *Optimizer) reasons(prices Prices) []string {
+	values := make([]float64, len(prices))
+	for i, p := range prices {
+		values[i] = p.Price
+	}
+	sort.Float64s(values)
+	cheap, expensive := quantile(values, o.Cheap), quantile(values, o.Expensive)
+
+	reasons := make([]string, len(prices))
+	for i, p := range prices {
+		reasons[i] = Normal
+		if p.Price >= expensive && expensive > cheap {
+			reasons[i] = Setback
+		}
+	}
+	for i, p := range prices {
+		if p.Price > cheap || reasons[i] == Setback {
+			continue
+		}
+		for _, later := range prices[i+1:] {
+			if later.Start.Sub(p.Start) > o.Window {
+				break
+			}
+			if later.Price >= expensive && expensive > cheap {
+				reasons[i] = Boost
+				break
+			}
+		}
+	}
+	return reasons
+}
+
+// setpoint returns the setpoint of a device for a reason.
+func (d device) setpoint(reason string) float64 {
+	boost, setback := d.Max, d.Min
+	if d.Mode == "cool" {
+		boost, setback = d.Min, d.Max
+	}
+	switch reason {
+	case Boost:
+		return boost
+	case Setback:
+		return setback
+	}
+	return d.Comfort
+}
+
+// Plan plans all devices for the prices from the hour containing from.
+// Consecutive hours with the same setpoint are one step.
+func (o *Optimizer) Plan(prices Prices, from time.Time) Plan {
+	prices = prices.From(from)
+	plan := Plan{Steps: []Step{}}
+	if len(prices) == 0 {
+		return plan
+	}
+	reasons := o.reasons(prices)
+
+	for _, d := range o.devices {
+		hours := 0
+		for i, p := range prices {
+			end := p.Start.Add(time.Hour)
+			last := len(plan.Steps) - 1
+			if i > 0 && plan.Steps[last].Reason == reasons[i] && plan.Steps[last].End.Equal(p.Start) {
+				step := &plan.Steps[last]
+				step.Price = (step.Price*float64(hours) + p.Price) / float64(hours+1)
+				step.End = end
+				hours++
+				continue
+			}
+			plan.Steps = append(plan.Steps, Step{
+				DeviceGUID:  d.guid,
+				DeviceName:  d.name,
+				Start:       p.Start,
+				End:         end,
+				Mode:        d.Mode,
+				Temperature: d.setpoint(reasons[i]),
+				Reason:      reasons[i],
+				Price:       p.Price,
+			})
+			hours = 1
+		}
+	}
+	sort.SliceStable(plan.Steps, func(i, j int) bool { return plan.Steps[i].Start.Before(plan.Steps[j].Start) })
+
+	return plan
+}
+
+// Name returns the schedule rule name of a step.
+func (s Step) Name() string {
+	return fmt.Sprintf("%s %s %s", s.DeviceName, s.Reason, s.Start.Format("2006-01-02 15:04"))
+}
+
+// Rules returns schedule rules setting the mode and setpoint of every
+// step at its start.
+func (p Plan) Rules() []schedule.Rule {
+	rules := []schedule.Rule{}
+	for _, s := range p.Steps {
+		mode, temperature := s.Mode, s.Temperature
+		rules = append(rules, schedule.Rule{
+			Name:    s.Name(),
+			Devices: []string{s.DeviceGUID},
+			Cron:    fmt.Sprintf("%d %d %d %d *", s.Start.Minute(), s.Start.Hour(), s.Start.Day(), s.Start.Month()),
+			State:   api.State{Mode: &mode, Temperature: &temperature},
+		})
+	}
+	return rules
+}
+
+// Savings estimates the cost of every device with and without the
+// plan. The hourly consumption of the reference day, eg yesterday, is
+// taken as the consumption in the same hours of the plan. The Shift
+// part of the consumption in setback hours moves to the boost hours
+// within Window before them.
+func (o *Optimizer) Savings(plan Plan, prices Prices, reference time.Time) ([]Saving, error) {
+	savings := []Saving{}
+	for _, d := range o.devices {
+		client := *o.client
+		client.SetDevice(d.guid)
+		history, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], reference)
+		if err != nil {
+			return nil, err
+		}
+		hourly := map[int]float64{}
+		for _, u := range tariff.FromHistory(reference, history) {
+			hourly[u.Time.Hour()] += u.Consumption
+		}
+
+		// Hourly consumption and reason of the plan
+		type hour struct {
+			start  time.Time
+			price  float64
+			energy float64
+			reason string
+		}
+		hours := []*hour{}
+		for _, s := range plan.Steps {
+			if s.DeviceGUID != d.guid {
+				continue
+			}
+			for t := s.Start; t.Before(s.End); t = t.Add(time.Hour) {
+				price, _ := prices.At(t)
+				hours = append(hours, &hour{start: t, price: price, energy: hourly[t.Hour()], reason: s.Reason})
+			}
+		}
+
+		saving := Saving{DeviceGUID: d.guid, DeviceName: d.name}
+		for _, h := range hours {
+			saving.Consumption += h.energy
+			saving.Baseline += h.energy * h.price
+		}
+		for i, h := range hours {
+			if h.reason != Setback {
+				continue
+			}
+			boosts := []*hour{}
+			for _, earlier := range hours[:i] {
+				if earlier.reason == Boost && h.start.Sub(earlier.start) <= o.Window {
+					boosts = append(boosts, earlier)
+				}
+			}
+			if len(boosts) == 0 {
+				continue
+			}
+			moved := h.energy * o.Shift
+			h.energy -= moved
+			for _, b := range boosts {
+				b.energy += moved / float64(len(boosts))
+			}
+		}
+		for _, h := range hours {
+			saving.Optimized += h.energy * h.price
+		}
+		savings = append(savings, saving)
+	}
+
+	return savings, nil
+}
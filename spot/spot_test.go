@@ -0,0 +1,187 @@
+package spot_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/spot"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+var day = time.Date(2021, 1, 4, 0, 0, 0, 0, time.Local)
+
+func TestLoad(t *testing.T) {
+	want := spot.Synthetic(day, 3)
+	server := httptest.NewServer(spot.Handler(func() spot.Prices { return want }))
+	defer server.Close()
+
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "prices.csv")
+	if err := os.WriteFile(csvFile, []byte("start,price\n2021-01-04 02:00,0.11\n2021-01-04T00:00,0.11\n2021-01-04 01:00,0.11\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	jsonFile := filepath.Join(dir, "prices.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"prices": [{"start": "2021-01-04T00:00:00+01:00", "price": 0.11}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, source := range []string{server.URL, server.URL + "?format=csv", csvFile} {
+		got, err := spot.Load(context.Background(), source)
+		if err != nil {
+			t.Fatalf("Load(%q): %v", source, err)
+		}
+		if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+			t.Errorf("Load(%q) mismatch (-want +got):\n%s", source, diff)
+		}
+	}
+	got, err := spot.Load(context.Background(), jsonFile)
+	if err != nil || len(got) != 1 || got[0].Price != 0.11 {
+		t.Errorf("Load(%q) = %v, %v, want one price", jsonFile, got, err)
+	}
+
+	for _, data := range []string{"start,price\nyesterday,1\n", "2021-01-04 00:00,cheap\n", "2021-01-04 00:00\n"} {
+		if _, err := spot.ParseCSV(strings.NewReader(data)); err == nil {
+			t.Errorf("ParseCSV(%q) succeeded, want error", data)
+		}
+	}
+	if _, err := spot.ParseJSON([]byte(`[{"start": "today"}]`)); err == nil {
+		t.Error("ParseJSON with invalid start succeeded, want error")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	o, err := spot.New(client, spot.Config{Devices: []spot.Profile{
+		{Device: "Living", Comfort: 21, Min: 19, Max: 23},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices := spot.Synthetic(day, 24)
+	plan := o.Plan(prices, day.Add(30*time.Minute))
+	type step struct {
+		Start, End  int
+		Reason      string
+		Temperature float64
+	}
+	got := []step{}
+	for _, s := range plan.Steps {
+		got = append(got, step{s.Start.Hour(), int(s.End.Sub(day).Hours()), s.Reason, s.Temperature})
+	}
+	want := []step{
+		{0, 3, spot.Normal, 21},
+		{3, 5, spot.Boost, 23},
+		{5, 7, spot.Normal, 21},
+		{7, 10, spot.Setback, 19},
+		{10, 14, spot.Normal, 21},
+		{14, 15, spot.Boost, 23},
+		{15, 18, spot.Normal, 21},
+		{18, 22, spot.Setback, 19},
+		{22, 24, spot.Normal, 21},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Plan mismatch (-want +got):\n%s", diff)
+	}
+
+	rules := plan.Rules()
+	if len(rules) != len(plan.Steps) {
+		t.Fatalf("got %d rules, want %d", len(rules), len(plan.Steps))
+	}
+	if rules[1].Cron != "0 3 4 1 *" || *rules[1].State.Temperature != 23 || *rules[1].State.Mode != "heat" {
+		t.Errorf("got rule %+v, want heat to 23 at 03:00 on January 4", rules[1])
+	}
+}
+
+func TestSavings(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	o, err := spot.New(client, spot.Config{Devices: []spot.Profile{
+		{Device: "Living", Comfort: 21, Min: 19, Max: 23},
+		{Device: "Bedroom", Mode: "cool", Comfort: 24, Min: 22, Max: 26},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices := spot.Synthetic(day, 24)
+	plan := o.Plan(prices, day)
+	savings, err := o.Savings(plan, prices, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(savings) != 2 {
+		t.Fatalf("got %d savings, want 2", len(savings))
+	}
+	for _, s := range savings {
+		if s.Consumption <= 0 || s.Optimized > s.Baseline {
+			t.Errorf("%s: got consumption %v, baseline %v and optimized %v, want consumption and a saving", s.DeviceName, s.Consumption, s.Baseline, s.Optimized)
+		}
+	}
+}
+
+// flat uses 1 kWh in every history entry.
+type flat struct{}
+
+func (flat) Update(d *pt.Device, now time.Time) {}
+
+func (flat) History(deviceGUID string, from, to time.Time) (pt.HistoryEntry, bool) {
+	return pt.HistoryEntry{Consumption: 1}, true
+}
+
+func TestSavingsDaylightSaving(t *testing.T) {
+	zone, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skipf("TestSavingsDaylightSaving() needs the zone database: %v", err)
+	}
+	cloud := cloudtest.Default()
+	cloud.Model = flat{}
+	client := testclient.New(t, cloud)
+	o, err := spot.New(client, spot.Config{Devices: []spot.Profile{
+		{Device: "Living", Comfort: 21, Min: 19, Max: 23},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks moved forward from 02:00 to 03:00 on 2021-03-28, the
+	// entries of hour 2 and 3 both count for 03:00
+	reference := time.Date(2021, 3, 28, 0, 0, 0, 0, zone)
+	plan := spot.Plan{Steps: []spot.Step{{
+		DeviceGUID: "CS-Z25XKEW+4321",
+		Start:      reference,
+		End:        reference.AddDate(0, 0, 1),
+		Reason:     spot.Normal,
+	}}}
+	savings, err := o.Savings(plan, spot.Synthetic(reference, 23), reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(savings) != 1 || savings[0].Consumption != 24 {
+		t.Errorf("Savings() = %+v, want a consumption of 24", savings)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	living := []spot.Profile{{Device: "Living", Comfort: 21, Min: 19, Max: 23}}
+	for _, config := range []spot.Config{
+		{},
+		{Cheap: 0.8, Expensive: 0.5, Devices: living},
+		{Shift: 2, Devices: living},
+		{Devices: []spot.Profile{{Device: "Living", Mode: "dry", Comfort: 21, Min: 19, Max: 23}}},
+		{Devices: []spot.Profile{{Device: "Living", Comfort: 25, Min: 19, Max: 23}}},
+		{Devices: []spot.Profile{{Device: "Attic", Comfort: 21, Min: 19, Max: 23}}},
+	} {
+		if _, err := spot.New(client, config); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", config)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tariff
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// Usage is the energy consumption (in kWh) of the hour starting at Time.
+type Usage struct {
+	Time        time.Time
+	Consumption float64
+}
+
+// Total is the consumption and cost over a period starting at Start.
+type Total struct {
+	Start       time.Time
+	Consumption float64
+	Cost        float64
+}
+
+// Report is a cost report for a device or account.
+type Report struct {
+	Currency string
+	Days     []Total
+	Months   []Total
+	Total    Total
+}
+
+// FromHistory converts a day-mode history for the given day to
+// hourly usage. Hours without data are skipped. On the day daylight
+// saving time starts two entries can have the same time.
+func FromHistory(day time.Time, history pt.History) []Usage {
+	usage := []Usage{}
+	for _, e := range history.HistoryEntries {
+		if e.Consumption == pt.HistoryNoData {
+			continue
+		}
+		usage = append(usage, Usage{
+			Time:        cloudcontrol.HistoryHour(day, e.DataNumber),
+			Consumption: e.Consumption,
+		})
+	}
+
+	return usage
+}
+
+// add accumulates consumption and cost into the total for start,
+// appending a new total when the period changes.
+func add(totals []Total, start time.Time, consumption, cost float64) []Total {
+	if len(totals) == 0 || !totals[len(totals)-1].Start.Equal(start) {
+		totals = append(totals, Total{Start: start})
+	}
+	totals[len(totals)-1].Consumption += consumption
+	totals[len(totals)-1].Cost += cost
+
+	return totals
+}
+
+// Calculate applies the tariff to hourly usage and returns the cost
+// per day, per month and in total.
+func (t Tariff) Calculate(usage []Usage) (Report, error) {
+	return t.CalculateFrom(usage, time.Time{})
+}
+
+// CalculateFrom is like Calculate but only reports the usage from from
+// onwards. Earlier usage in the same month only counts towards the
+// monthly consumption of tiered tariffs, so pass the usage from the
+// start of the month to report part of a month.
+func (t Tariff) CalculateFrom(usage []Usage, from time.Time) (Report, error) {
+	sorted := append([]Usage{}, usage...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	report := Report{Currency: t.Currency}
+	monthly := 0.0
+	current := time.Time{}
+	for _, u := range sorted {
+		day := time.Date(u.Time.Year(), u.Time.Month(), u.Time.Day(), 0, 0, 0, 0, u.Time.Location())
+		month := time.Date(u.Time.Year(), u.Time.Month(), 1, 0, 0, 0, 0, u.Time.Location())
+		if !current.Equal(month) {
+			current = month
+			monthly = 0
+		}
+		if u.Time.Before(from) {
+			monthly += u.Consumption
+			continue
+		}
+
+		cost := 0.0
+		switch t.Type {
+		case TimeOfUse:
+			price, err := t.priceAt(u.Time)
+			if err != nil {
+				return report, err
+			}
+			cost = u.Consumption * price
+		case Tiered:
+			cost = t.tieredCost(monthly, u.Consumption)
+		default:
+			cost = u.Consumption * t.Price
+		}
+		monthly += u.Consumption
+
+		report.Days = add(report.Days, day, u.Consumption, cost)
+		report.Months = add(report.Months, month, u.Consumption, cost)
+		if report.Total.Start.IsZero() {
+			report.Total.Start = u.Time
+		}
+		report.Total.Consumption += u.Consumption
+		report.Total.Cost += cost
+	}
+
+	return report, nil
+}
+
+// CalculateAccount applies the tariff to the combined usage of all
+// devices. Tiers are applied to the account-wide monthly consumption,
+// as they would be on a shared meter.
+func (t Tariff) CalculateAccount(usage map[string][]Usage) (Report, error) {
+	return t.CalculateAccountFrom(usage, time.Time{})
+}
+
+// CalculateAccountFrom is like CalculateAccount but only reports the
+// usage from from onwards, see CalculateFrom.
+func (t Tariff) CalculateAccountFrom(usage map[string][]Usage, from time.Time) (Report, error) {
+	combined := []Usage{}
+	for _, u := range usage {
+		combined = append(combined, u...)
+	}
+
+	return t.CalculateFrom(combined, from)
+}
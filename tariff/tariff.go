@@ -0,0 +1,204 @@
+// Package tariff calculates energy costs for Panasonic Comfort Cloud
+// devices from hourly consumption using user-defined tariffs.
+package tariff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Tariff types
+const (
+	Flat      = "flat"
+	TimeOfUse = "tou"
+	Tiered    = "tiered"
+)
+
+// weekdays maps the day names used in tariff files to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Tariff defines the price of energy. A flat tariff uses Price for
+// every kWh, a time of use tariff uses the price of the first matching
+// Period and a tiered tariff uses the Tiers based on the consumption
+// so far in the calendar month.
+type Tariff struct {
+	Name     string   `yaml:"name"`
+	Currency string   `yaml:"currency"`
+	Type     string   `yaml:"type"`
+	Price    float64  `yaml:"price"`
+	Periods  []Period `yaml:"periods"`
+	Tiers    []Tier   `yaml:"tiers"`
+}
+
+// Period is a time of use price window. Start and End are "HH:MM"
+// local times, a window ending before it starts wraps past midnight.
+// An empty Start and End match the whole day and empty Days match
+// every day of the week.
+type Period struct {
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+	Days  []string `yaml:"days"`
+	Price float64  `yaml:"price"`
+}
+
+// Tier is a tiered price which applies until the monthly consumption
+// reaches UpTo kWh. The last tier must have UpTo set to 0 which
+// means unlimited, so consumption above the other tiers is charged.
+type Tier struct {
+	UpTo  float64 `yaml:"upTo"`
+	Price float64 `yaml:"price"`
+}
+
+// Load reads a tariff definition from a YAML file.
+func Load(path string) (Tariff, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Tariff{}, err
+	}
+
+	return Parse(data)
+}
+
+// Parse reads a tariff definition from YAML data.
+func Parse(data []byte) (Tariff, error) {
+	t := Tariff{}
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("unmarshal error %v", err)
+	}
+	if t.Type == "" {
+		t.Type = Flat
+	}
+
+	return t, t.Validate()
+}
+
+// Validate checks the tariff definition for errors.
+func (t Tariff) Validate() error {
+	switch t.Type {
+	case Flat:
+	case TimeOfUse:
+		if len(t.Periods) == 0 {
+			return fmt.Errorf("error: tariff %q has no periods", t.Name)
+		}
+		for _, p := range t.Periods {
+			if _, err := parseClock(p.Start); err != nil {
+				return err
+			}
+			if _, err := parseClock(p.End); err != nil {
+				return err
+			}
+			for _, d := range p.Days {
+				if _, ok := weekdays[strings.ToLower(d)]; !ok {
+					return fmt.Errorf("error: unknown day %q in tariff %q", d, t.Name)
+				}
+			}
+		}
+	case Tiered:
+		if len(t.Tiers) == 0 {
+			return fmt.Errorf("error: tariff %q has no tiers", t.Name)
+		}
+		for i, tier := range t.Tiers {
+			if tier.UpTo == 0 && i != len(t.Tiers)-1 {
+				return fmt.Errorf("error: only the last tier of tariff %q can be unlimited", t.Name)
+			}
+			if tier.UpTo != 0 && i == len(t.Tiers)-1 {
+				return fmt.Errorf("error: the last tier of tariff %q must be unlimited", t.Name)
+			}
+			if i > 0 && tier.UpTo != 0 && tier.UpTo <= t.Tiers[i-1].UpTo {
+				return fmt.Errorf("error: tiers of tariff %q must increase", t.Name)
+			}
+		}
+	default:
+		return fmt.Errorf("error: unknown tariff type %q", t.Type)
+	}
+
+	return nil
+}
+
+// parseClock parses a "HH:MM" time into minutes since midnight.
+func parseClock(clock string) (int, error) {
+	if clock == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("error: invalid time %q: %v", clock, err)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matches reports whether the period applies at time t.
+func (p Period) matches(t time.Time) bool {
+	if len(p.Days) > 0 {
+		found := false
+		for _, d := range p.Days {
+			if weekdays[strings.ToLower(d)] == t.Weekday() {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	start, _ := parseClock(p.Start)
+	end, _ := parseClock(p.End)
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return minute >= start && minute < end
+	default:
+		return minute >= start || minute < end
+	}
+}
+
+// priceAt returns the time of use price at time t.
+func (t Tariff) priceAt(at time.Time) (float64, error) {
+	for _, p := range t.Periods {
+		if p.matches(at) {
+			return p.Price, nil
+		}
+	}
+
+	return 0, fmt.Errorf("error: no period of tariff %q matches %s", t.Name, at)
+}
+
+// tieredCost returns the cost of consumption kWh when used kWh have
+// already been consumed in the month.
+func (t Tariff) tieredCost(used, consumption float64) float64 {
+	cost := 0.0
+	for _, tier := range t.Tiers {
+		if consumption <= 0 {
+			break
+		}
+		amount := consumption
+		if tier.UpTo != 0 {
+			if used >= tier.UpTo {
+				continue
+			}
+			if used+amount > tier.UpTo {
+				amount = tier.UpTo - used
+			}
+		}
+		cost += amount * tier.Price
+		used += amount
+		consumption -= amount
+	}
+
+	return cost
+}
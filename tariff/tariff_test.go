@@ -0,0 +1,183 @@
+package tariff_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/tariff"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+var (
+	flatYAML = `
+name: Flat
+currency: EUR
+price: 0.25
+`
+	touYAML = `
+name: Night
+currency: EUR
+type: tou
+periods:
+  - start: "23:00"
+    end: "07:00"
+    price: 0.10
+  - days: [sat, sun]
+    price: 0.15
+  - price: 0.30
+`
+	tieredYAML = `
+name: Tiers
+currency: EUR
+type: tiered
+tiers:
+  - upTo: 1
+    price: 0.10
+  - price: 0.20
+`
+)
+
+// approx compares floats with a small tolerance.
+var approx = cmp.Comparer(func(x, y float64) bool {
+	return math.Abs(x-y) < 1e-9
+})
+
+func usage() []tariff.Usage {
+	// Friday 2021-01-01 and Saturday 2021-01-02
+	return []tariff.Usage{
+		{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Consumption: 0.5},
+		{Time: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC), Consumption: 1.0},
+		{Time: time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC), Consumption: 1.0},
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		days  []float64
+		total float64
+	}{
+		{
+			name:  "flat",
+			input: flatYAML,
+			days:  []float64{0.375, 0.25},
+			total: 0.625,
+		},
+		{
+			name:  "tou",
+			input: touYAML,
+			days:  []float64{0.35, 0.15},
+			total: 0.5,
+		},
+		{
+			name:  "tiered",
+			input: tieredYAML,
+			days:  []float64{0.2, 0.2},
+			total: 0.4,
+		},
+	}
+	for _, c := range cases {
+		tf, err := tariff.Parse([]byte(c.input))
+		if err != nil {
+			t.Fatalf("TestCalculate(%s) returned an error: %v", c.name, err)
+		}
+		report, err := tf.Calculate(usage())
+		if err != nil {
+			t.Fatalf("TestCalculate(%s) returned an error: %v", c.name, err)
+		}
+		got := []float64{}
+		for _, d := range report.Days {
+			got = append(got, d.Cost)
+		}
+		if diff := cmp.Diff(c.days, got, approx); diff != "" {
+			t.Errorf("TestCalculate(%s) days mismatch (-want +got):\n%s", c.name, diff)
+		}
+		if diff := cmp.Diff(c.total, report.Total.Cost, approx); diff != "" {
+			t.Errorf("TestCalculate(%s) total mismatch (-want +got):\n%s", c.name, diff)
+		}
+		if diff := cmp.Diff(1, len(report.Months)); diff != "" {
+			t.Errorf("TestCalculate(%s) months mismatch (-want +got):\n%s", c.name, diff)
+		}
+	}
+}
+
+func TestCalculateFrom(t *testing.T) {
+	tf, err := tariff.Parse([]byte(tieredYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The 1.5 kWh of January 1 use up the cheap tier
+	from := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	report, err := tf.CalculateFrom(usage(), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tariff.Total{Start: time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC), Consumption: 1.0, Cost: 0.2}
+	if diff := cmp.Diff(want, report.Total, approx); diff != "" {
+		t.Errorf("TestCalculateFrom() total mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(1, len(report.Days)); diff != "" {
+		t.Errorf("TestCalculateFrom() days mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	inputs := []string{
+		"type: unknown",
+		"type: tou",
+		"type: tou\nperiods:\n  - start: \"25:00\"",
+		"type: tiered\ntiers:\n  - price: 1\n  - upTo: 2\n    price: 1",
+		"type: tiered\ntiers:\n  - upTo: 1\n    price: 1\n  - upTo: 2\n    price: 2",
+	}
+	for _, input := range inputs {
+		if _, err := tariff.Parse([]byte(input)); err == nil {
+			t.Errorf("TestParseInvalid(%q) did not return an error", input)
+		}
+	}
+}
+
+func TestFromHistory(t *testing.T) {
+	history := pt.History{
+		HistoryEntries: []pt.HistoryEntry{
+			{DataNumber: 0, Consumption: 0.3},
+			{DataNumber: 1, Consumption: pt.HistoryNoData},
+		},
+	}
+	day := time.Date(2021, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	want := []tariff.Usage{{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Consumption: 0.3}}
+	got := tariff.FromHistory(day, history)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TestFromHistory() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFromHistoryDaylightSaving(t *testing.T) {
+	zone, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skipf("TestFromHistoryDaylightSaving() needs the zone database: %v", err)
+	}
+	history := pt.History{
+		HistoryEntries: []pt.HistoryEntry{
+			{DataNumber: 1, Consumption: 0.1},
+			{DataNumber: 12, Consumption: 0.2},
+			{DataNumber: 23, Consumption: 0.3},
+		},
+	}
+	// Clocks moved forward from 02:00 to 03:00 on 2021-03-28
+	day := time.Date(2021, 3, 28, 15, 0, 0, 0, zone)
+
+	want := []tariff.Usage{
+		{Time: time.Date(2021, 3, 28, 1, 0, 0, 0, zone), Consumption: 0.1},
+		{Time: time.Date(2021, 3, 28, 12, 0, 0, 0, zone), Consumption: 0.2},
+		{Time: time.Date(2021, 3, 28, 23, 0, 0, 0, zone), Consumption: 0.3},
+	}
+	got := tariff.FromHistory(day, history)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TestFromHistoryDaylightSaving() mismatch (-want +got):\n%s", diff)
+	}
+}
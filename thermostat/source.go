@@ -0,0 +1,256 @@
+package thermostat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+)
+
+// DefaultMaxAge is the age after which file and MQTT readings are stale.
+const DefaultMaxAge = 10 * time.Minute
+
+// DefaultTimeout is the timeout of an HTTP or command reading.
+const DefaultTimeout = 10 * time.Second
+
+// Source types
+const (
+	File    = "file"
+	HTTP    = "http"
+	MQTT    = "mqtt"
+	Command = "command"
+)
+
+// Source reads the room temperature.
+type Source interface {
+	Temperature(ctx context.Context) (float64, error)
+}
+
+// SourceFunc is a function used as Source.
+type SourceFunc func(ctx context.Context) (float64, error)
+
+// Temperature implements Source.
+func (f SourceFunc) Temperature(ctx context.Context) (float64, error) {
+	return f(ctx)
+}
+
+// SourceConfig configures a Source. The reading is a plain number or,
+// when Field is set, a JSON document with the temperature at the dotted
+// Field path, eg "sensor.temperature".
+type SourceConfig struct {
+	// Type is file, http, mqtt or command
+	Type string `json:"type"`
+	// Path is the file to read
+	Path string `json:"path"`
+	// URL is fetched with a GET request, with the optional Headers
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	// Topic is subscribed to on the MQTT broker
+	Topic string `json:"topic"`
+	// Command is run and its output is read
+	Command []string `json:"command"`
+	Field   string   `json:"field"`
+	// MaxAge is the age after which a file or MQTT reading is stale
+	MaxAge time.Duration `json:"maxAge"`
+}
+
+// Source creates the Source. The broker is only used by MQTT sources.
+func (sc SourceConfig) Source(broker bridge.Broker) (Source, error) {
+	maxAge := sc.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	switch sc.Type {
+	case File:
+		if sc.Path == "" {
+			return nil, fmt.Errorf("error: file source needs a path")
+		}
+		return &FileSource{Path: sc.Path, Field: sc.Field, MaxAge: maxAge}, nil
+	case HTTP:
+		if sc.URL == "" {
+			return nil, fmt.Errorf("error: http source needs a url")
+		}
+		return &HTTPSource{URL: sc.URL, Headers: sc.Headers, Field: sc.Field, Timeout: DefaultTimeout}, nil
+	case MQTT:
+		if sc.Topic == "" || broker == nil {
+			return nil, fmt.Errorf("error: mqtt source needs a topic and an MQTT broker")
+		}
+		return NewMQTTSource(broker, sc.Topic, sc.Field, maxAge)
+	case Command:
+		if len(sc.Command) == 0 {
+			return nil, fmt.Errorf("error: command source needs a command")
+		}
+		return &CommandSource{Command: sc.Command, Field: sc.Field, Timeout: DefaultTimeout}, nil
+	}
+	return nil, fmt.Errorf("error: unknown source type %q", sc.Type)
+}
+
+// parse returns the temperature of a reading.
+func parse(data []byte, field string) (float64, error) {
+	if field == "" {
+		v, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			return 0, fmt.Errorf("error: invalid temperature %q", bytes.TrimSpace(data))
+		}
+		return v, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return 0, fmt.Errorf("error: invalid JSON reading: %w", err)
+	}
+	for _, key := range strings.Split(field, ".") {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("error: field %q not found in reading", field)
+		}
+		v = object[key]
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return parse([]byte(v), "")
+	}
+	return 0, fmt.Errorf("error: field %q is not a temperature", field)
+}
+
+// FileSource reads the temperature from a file, eg written by a sensor
+// daemon or a 1-Wire sysfs file.
+type FileSource struct {
+	Path   string
+	Field  string
+	MaxAge time.Duration
+}
+
+// Temperature implements Source.
+func (s *FileSource) Temperature(context.Context) (float64, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return 0, fmt.Errorf("error: reading temperature: %w", err)
+	}
+	if s.MaxAge > 0 && time.Since(info.ModTime()) > s.MaxAge {
+		return 0, fmt.Errorf("error: temperature in %s is older than %v", s.Path, s.MaxAge)
+	}
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return 0, fmt.Errorf("error: reading temperature: %w", err)
+	}
+	return parse(data, s.Field)
+}
+
+// HTTPSource fetches the temperature from an HTTP endpoint.
+type HTTPSource struct {
+	URL     string
+	Headers map[string]string
+	Field   string
+	Timeout time.Duration
+}
+
+// Temperature implements Source.
+func (s *HTTPSource) Temperature(ctx context.Context) (float64, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error: fetching temperature: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("error: fetching temperature: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("error: fetching temperature: %s", resp.Status)
+	}
+	return parse(data, s.Field)
+}
+
+// CommandSource runs a command and reads the temperature from its
+// output.
+type CommandSource struct {
+	Command []string
+	Field   string
+	Timeout time.Duration
+}
+
+// Temperature implements Source.
+func (s *CommandSource) Temperature(ctx context.Context) (float64, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	out, err := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...).Output()
+	if err != nil {
+		return 0, fmt.Errorf("error: running %s: %w", s.Command[0], err)
+	}
+	return parse(out, s.Field)
+}
+
+// MQTTSource keeps the last temperature published on a topic.
+type MQTTSource struct {
+	Topic  string
+	Field  string
+	MaxAge time.Duration
+
+	mu       sync.Mutex
+	value    float64
+	received time.Time
+	err      error
+}
+
+// NewMQTTSource subscribes to topic.
+func NewMQTTSource(broker bridge.Broker, topic, field string, maxAge time.Duration) (*MQTTSource, error) {
+	s := &MQTTSource{Topic: topic, Field: field, MaxAge: maxAge}
+	if err := broker.Subscribe(topic, s.handle); err != nil {
+		return nil, fmt.Errorf("error: subscribing to %s: %w", topic, err)
+	}
+	return s, nil
+}
+
+func (s *MQTTSource) handle(_ string, payload []byte) {
+	v, err := parse(payload, s.Field)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	if err == nil {
+		s.value, s.received = v, time.Now()
+	}
+}
+
+// Temperature implements Source.
+func (s *MQTTSource) Temperature(context.Context) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.received.IsZero() {
+		return 0, fmt.Errorf("error: no temperature received on %s", s.Topic)
+	}
+	if s.MaxAge > 0 && time.Since(s.received) > s.MaxAge {
+		return 0, fmt.Errorf("error: temperature on %s is older than %v", s.Topic, s.MaxAge)
+	}
+	return s.value, nil
+}
@@ -0,0 +1,283 @@
+// Package thermostat controls Panasonic devices with the room
+// temperature from an external sensor instead of the sensor of the
+// indoor unit. A hysteresis or PID loop adjusts the power and setpoint of
+// the device, keeping minimum on and off times to protect the compressor.
+package thermostat
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Control algorithms
+const (
+	// Hysteresis turns the device on below and off above a band around
+	// the setpoint
+	Hysteresis = "hysteresis"
+	// PID keeps the device on and moves its setpoint
+	PID = "pid"
+)
+
+// Defaults used by New
+const (
+	DefaultHysteresis = 0.5
+	DefaultMinOn      = 10 * time.Minute
+	DefaultMinOff     = 5 * time.Minute
+	DefaultInterval   = time.Minute
+	DefaultKp         = 1
+	DefaultKi         = 0.02
+)
+
+// Range of the room setpoint
+const (
+	MinTemperature = 16
+	MaxTemperature = 30
+)
+
+// PIDConfig are the gains of the PID loop. The error is in °C and time
+// in minutes, so Ki is per °C minute and Kd per °C/minute.
+type PIDConfig struct {
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+}
+
+// Config configures a Controller.
+type Config struct {
+	Name string `json:"name"`
+	// Device is a GUID, name or alias of a single device
+	Device string       `json:"device"`
+	Source SourceConfig `json:"source"`
+	// Setpoint is the wanted room temperature
+	Setpoint float64 `json:"setpoint"`
+	// Mode is heat or cool, heat when empty
+	Mode      string `json:"mode"`
+	Algorithm string `json:"algorithm"`
+	// Hysteresis is the half width of the band around Setpoint
+	Hysteresis float64 `json:"hysteresis"`
+	// Offset is added to the device setpoint when heating and
+	// subtracted when cooling, to compensate for the unit sensor
+	// reading the room too warm
+	Offset   float64       `json:"offset"`
+	PID      PIDConfig     `json:"pid"`
+	MinOn    time.Duration `json:"minOn"`
+	MinOff   time.Duration `json:"minOff"`
+	Interval time.Duration `json:"interval"`
+}
+
+// Controller controls a device with an external temperature.
+type Controller struct {
+	Config
+	Now func() time.Time
+
+	client   cloudcontrol.Client
+	source   Source
+	mode     int
+	min, max float64   // device setpoint range in mode
+	switched time.Time // last power change by the controller
+
+	// PID state
+	integral  float64
+	lastError float64
+	last      time.Time
+}
+
+// New creates a Controller, the device of the config is resolved once.
+func New(client *cloudcontrol.Client, config Config, source Source) (*Controller, error) {
+	if config.Name == "" {
+		config.Name = config.Device
+	}
+	guids, err := client.ResolveDevices(config.Device)
+	if err != nil {
+		return nil, fmt.Errorf("error: thermostat %q: %w", config.Name, err)
+	}
+	if len(guids) != 1 {
+		return nil, fmt.Errorf("error: thermostat %q must control a single device, %q matches %d", config.Name, config.Device, len(guids))
+	}
+
+	if config.Mode == "" {
+		config.Mode = "heat"
+	}
+	if config.Mode != "heat" && config.Mode != "cool" {
+		return nil, fmt.Errorf("error: thermostat %q has mode %q, want heat or cool", config.Name, config.Mode)
+	}
+	if config.Algorithm == "" {
+		config.Algorithm = Hysteresis
+	}
+	if config.Algorithm != Hysteresis && config.Algorithm != PID {
+		return nil, fmt.Errorf("error: thermostat %q has unknown algorithm %q", config.Name, config.Algorithm)
+	}
+	if config.Setpoint < MinTemperature || config.Setpoint > MaxTemperature {
+		return nil, fmt.Errorf("error: thermostat %q setpoint %v is not between %v and %v", config.Name, config.Setpoint, MinTemperature, MaxTemperature)
+	}
+	if config.Hysteresis < 0 || config.MinOn < 0 || config.MinOff < 0 || config.Interval < 0 {
+		return nil, fmt.Errorf("error: thermostat %q has a negative hysteresis, minimum time or interval", config.Name)
+	}
+	if config.Hysteresis == 0 {
+		config.Hysteresis = DefaultHysteresis
+	}
+	if config.MinOn == 0 {
+		config.MinOn = DefaultMinOn
+	}
+	if config.MinOff == 0 {
+		config.MinOff = DefaultMinOff
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.PID == (PIDConfig{}) {
+		config.PID = PIDConfig{Kp: DefaultKp, Ki: DefaultKi}
+	}
+
+	device, err := client.FindDevice(guids[0])
+	if err != nil {
+		return nil, fmt.Errorf("error: thermostat %q: %w", config.Name, err)
+	}
+	c := &Controller{Config: config, Now: time.Now, client: *client, source: source, mode: pt.Modes[config.Mode]}
+	c.min, c.max = float64(device.HeatTempMin), float64(device.HeatTempMax)
+	if config.Mode == "cool" {
+		c.min, c.max = float64(device.CoolTempMin), float64(device.CoolTempMax)
+	}
+	c.client.SetDevice(guids[0])
+	return c, nil
+}
+
+// demand returns how much warmer (heating) or cooler (cooling) the room
+// should be.
+func (c *Controller) demand(room float64) float64 {
+	if c.Mode == "cool" {
+		return room - c.Setpoint
+	}
+	return c.Setpoint - room
+}
+
+// sign is 1 when heating and -1 when cooling.
+func (c *Controller) sign() float64 {
+	if c.Mode == "cool" {
+		return -1
+	}
+	return 1
+}
+
+// target returns the device setpoint while on.
+func (c *Controller) target(room float64, now time.Time) float64 {
+	offset := c.Offset
+	if c.Algorithm == PID {
+		e := c.demand(room)
+		var derivative float64
+		integral := c.integral
+		if !c.last.IsZero() {
+			dt := now.Sub(c.last).Minutes()
+			integral += e * dt
+			if dt > 0 {
+				derivative = (e - c.lastError) / dt
+			}
+		}
+		output := c.PID.Kp*e + c.PID.Ki*integral + c.PID.Kd*derivative
+		// Stop integrating while the device setpoint is saturated
+		unclamped := c.Setpoint + c.sign()*(offset+output)
+		if unclamped >= c.min && unclamped <= c.max {
+			c.integral = integral
+		}
+		c.lastError, c.last = e, now
+		offset += output
+	}
+
+	t := c.Setpoint + c.sign()*offset
+	return math.Round(math.Max(c.min, math.Min(c.max, t))*2) / 2
+}
+
+// decide returns the parameters to change for a room temperature.
+func (c *Controller) decide(room float64, p pt.DeviceParameters, now time.Time) pt.DeviceControlParameters {
+	on := p.Operate == 1
+	want := on
+	switch {
+	case c.Algorithm == PID:
+		want = true
+	case c.demand(room) > c.Hysteresis:
+		want = true
+	case c.demand(room) < -c.Hysteresis:
+		want = false
+	}
+	if want != on {
+		since := now.Sub(c.switched)
+		if on && since < c.MinOn || !on && since < c.MinOff {
+			log.Debugf("%s: keeping power %v for the minimum on/off time", c.Name, on)
+			want = on
+		}
+	}
+
+	control := pt.DeviceControlParameters{}
+	if want != on {
+		operate := 0
+		if want {
+			operate = 1
+		}
+		control.Operate = &operate
+	}
+	if !want {
+		c.last = time.Time{}
+		return control
+	}
+	if p.OperationMode != c.mode {
+		mode := c.mode
+		control.OperationMode = &mode
+	}
+	if t := c.target(room, now); t != p.TemperatureSet {
+		control.TemperatureSet = &t
+	}
+	return control
+}
+
+// Step reads the room temperature and adjusts the device.
+func (c *Controller) Step(ctx context.Context) error {
+	room, err := c.source.Temperature(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := c.client.GetDeviceStatus()
+	if err != nil {
+		return err
+	}
+	if !status.Parameters.Online {
+		return cloudcontrol.ErrDeviceOffline
+	}
+
+	now := c.Now()
+	control := c.decide(room, status.Parameters, now)
+	if control == (pt.DeviceControlParameters{}) {
+		log.Debugf("%s: room %.1f°C, no change", c.Name, room)
+		return nil
+	}
+	if _, err := c.client.SetState(control); err != nil {
+		return err
+	}
+	if control.Operate != nil {
+		c.switched = now
+	}
+	log.Infof("%s: room %.1f°C, %s", c.Name, room, control.Describe())
+	return nil
+}
+
+// Run adjusts the device every Interval until the context is done.
+// Errors are logged and the device is left as it is.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Step(ctx); err != nil {
+			log.Warnf("%s: %v", c.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package thermostat_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/thermostat"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+// step is a room temperature at minutes after the start.
+type step struct {
+	minute int
+	room   float64
+}
+
+// run runs a controller against the fake cloud and returns the
+// commands sent to the device.
+func run(t *testing.T, config thermostat.Config, steps []step) []string {
+	cloud := cloudtest.Default()
+	client := testclient.New(t, cloud)
+
+	var room float64
+	source := thermostat.SourceFunc(func(context.Context) (float64, error) { return room, nil })
+	c, err := thermostat.New(client, config, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.Local)
+	var commands []string
+	for _, s := range steps {
+		room = s.room
+		c.Now = func() time.Time { return start.Add(time.Duration(s.minute) * time.Minute) }
+		before := len(cloud.Commands())
+		if err := c.Step(context.Background()); err != nil {
+			t.Fatalf("minute %d: %v", s.minute, err)
+		}
+		for _, cmd := range cloud.Commands()[before:] {
+			commands = append(commands, fmt.Sprintf("%d:%s", s.minute, format(cmd.Parameters)))
+		}
+	}
+	return commands
+}
+
+// format formats the parameters of a command.
+func format(p pt.DeviceControlParameters) string {
+	s := ""
+	if p.Operate != nil {
+		s += fmt.Sprintf(" operate=%d", *p.Operate)
+	}
+	if p.OperationMode != nil {
+		s += fmt.Sprintf(" mode=%s", pt.ModesReverse[*p.OperationMode])
+	}
+	if p.TemperatureSet != nil {
+		s += fmt.Sprintf(" set=%v", *p.TemperatureSet)
+	}
+	return s
+}
+
+func TestHysteresis(t *testing.T) {
+	config := thermostat.Config{Device: "Living", Setpoint: 21, Offset: 2}
+	got := run(t, config, []step{
+		{0, 20},
+		{1, 21.6}, // minimum on time
+		{10, 21.6},
+		{11, 20}, // minimum off time
+		{15, 20},
+		{16, 21}, // within the band
+		{17, 20.7},
+	})
+	want := []string{
+		"0: operate=1 set=23",
+		"10: operate=0",
+		"15: operate=1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commands mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPID(t *testing.T) {
+	config := thermostat.Config{
+		Device:    "Bedroom",
+		Setpoint:  24,
+		Mode:      "cool",
+		Algorithm: thermostat.PID,
+		PID:       thermostat.PIDConfig{Kp: 1, Ki: 0.1},
+	}
+	got := run(t, config, []step{
+		{0, 26},
+		{10, 25},
+		{20, 24},
+		{30, 40}, // saturated, the integral is kept
+		{40, 24},
+		{50, 23},
+	})
+	want := []string{
+		"0: operate=1 set=22",
+		"20: set=23",
+		"30: set=18",
+		"40: set=23",
+		"50: set=25",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commands mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeviceRange(t *testing.T) {
+	// A unit heating from 10 to 24°C as the cloud reports it
+	var commands []string
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLGroups, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"groupCount":1,"groupList":[{"groupId":1,"groupName":"Cabin","deviceList":[{"deviceGuid":"device1","deviceName":"Cabin","heatMode":true,"coolTempMin":18,"coolTempMax":30,"heatTempMin":10,"heatTempMax":24}]}]}`))
+	})
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"deviceGuid":"device1","parameters":{"online":true,"operate":1,"operationMode":3,"temperatureSet":20}}`))
+	})
+	handler.HandleFunc(pt.URLControl, func(w http.ResponseWriter, r *http.Request) {
+		command := pt.Command{}
+		_ = json.NewDecoder(r.Body).Decode(&command)
+		commands = append(commands, format(command.Parameters))
+		_, _ = w.Write([]byte(pt.SuccessResponse))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := cloudcontrol.NewClient(server.URL)
+
+	var room float64
+	source := thermostat.SourceFunc(func(context.Context) (float64, error) { return room, nil })
+	c, err := thermostat.New(&client, thermostat.Config{Device: "Cabin", Setpoint: 21, Algorithm: thermostat.PID, PID: thermostat.PIDConfig{Kp: 1}}, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, room = range []float64{10, 30} {
+		if err := c.Step(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]string{" set=24", " set=12"}, commands); diff != "" {
+		t.Errorf("commands mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+
+	for _, config := range []thermostat.Config{
+		{Device: "My House", Setpoint: 21},
+		{Device: "Living", Setpoint: 21, Mode: "dry"},
+		{Device: "Living", Setpoint: 21, Algorithm: "fuzzy"},
+		{Device: "Living", Setpoint: 35},
+		{Device: "Living", Setpoint: 21, MinOn: -time.Minute},
+	} {
+		if _, err := thermostat.New(client, config, nil); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", config)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir := t.TempDir()
+	plain, document := filepath.Join(dir, "plain"), filepath.Join(dir, "document")
+	if err := ioutil.WriteFile(plain, []byte("19.5\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(document, []byte(`{"sensor": {"temperature": "20.25"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"temperature": 21.5}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		config thermostat.SourceConfig
+		want   float64
+	}{
+		{thermostat.SourceConfig{Type: thermostat.File, Path: plain}, 19.5},
+		{thermostat.SourceConfig{Type: thermostat.File, Path: document, Field: "sensor.temperature"}, 20.25},
+		{thermostat.SourceConfig{Type: thermostat.HTTP, URL: server.URL, Field: "temperature", Headers: map[string]string{"Authorization": "Bearer secret"}}, 21.5},
+		{thermostat.SourceConfig{Type: thermostat.Command, Command: []string{"echo", "22"}}, 22},
+	}
+	for _, tc := range tests {
+		source, err := tc.config.Source(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := source.Temperature(context.Background())
+		if err != nil || got != tc.want {
+			t.Errorf("%s source: got %v, %v, want %v", tc.config.Type, got, err, tc.want)
+		}
+	}
+
+	for _, config := range []thermostat.SourceConfig{
+		{Type: thermostat.File, Path: document},
+		{Type: thermostat.File, Path: plain, Field: "temperature"},
+		{Type: thermostat.HTTP, URL: server.URL, Field: "temperature"},
+	} {
+		source, err := config.Source(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := source.Temperature(context.Background()); err == nil {
+			t.Errorf("%+v: got %v, want error", config, got)
+		}
+	}
+	if _, err := (thermostat.SourceConfig{Type: thermostat.MQTT, Topic: "room"}).Source(nil); err == nil {
+		t.Error("mqtt source without broker succeeded, want error")
+	}
+}
+
+func TestSourceTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	for _, source := range []thermostat.Source{
+		&thermostat.HTTPSource{URL: server.URL, Timeout: 50 * time.Millisecond},
+		&thermostat.CommandSource{Command: []string{"sleep", "10"}, Timeout: 50 * time.Millisecond},
+	} {
+		start := time.Now()
+		if got, err := source.Temperature(context.Background()); err == nil {
+			t.Errorf("%T: got %v, want error", source, got)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%T: took %v, want the timeout", source, elapsed)
+		}
+	}
+}
+
+// broker is a Broker delivering published messages to subscribers.
+type broker struct {
+	handlers map[string]func(topic string, payload []byte)
+}
+
+func (b *broker) Publish(topic string, _ bool, payload []byte) error {
+	if h := b.handlers[topic]; h != nil {
+		h(topic, payload)
+	}
+	return nil
+}
+
+func (b *broker) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	b.handlers[topic] = handler
+	return nil
+}
+
+func TestMQTTSource(t *testing.T) {
+	b := &broker{handlers: map[string]func(string, []byte){}}
+	source, err := thermostat.NewMQTTSource(b, "room/temperature", "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Temperature(context.Background()); err == nil {
+		t.Error("Temperature before a message succeeded, want error")
+	}
+	b.Publish("room/temperature", false, []byte("20.5"))
+	if got, err := source.Temperature(context.Background()); err != nil || got != 20.5 {
+		t.Errorf("Temperature: got %v, %v, want 20.5", got, err)
+	}
+	b.Publish("room/temperature", false, []byte("unavailable"))
+	if _, err := source.Temperature(context.Background()); err == nil {
+		t.Error("Temperature after an invalid message succeeded, want error")
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 // Exported constants
 const (
 	URLServer       = "https://accsmart.panasonic.com"
@@ -11,6 +13,7 @@ const (
 	URLValidate1    = "/auth/agreement/status/1"
 	SuccessResponse = `{"result":0}`
 	FailureResponse = `{"result":1}`
+	HistoryNoData   = -255 // Value reported for history entries without data
 )
 
 // HistoryDataMode maps out the time intervals to fetch history data
@@ -39,6 +42,52 @@ var ModesReverse = map[int]string{
 	4: "fan",
 }
 
+// FanSpeeds define the different fan speeds of the device
+var FanSpeeds = map[string]int{
+	"auto":    0,
+	"low":     1,
+	"lowMid":  2,
+	"mid":     3,
+	"highMid": 4,
+	"high":    5,
+}
+
+// FanSpeedsReverse define the different fan speeds of the device
+var FanSpeedsReverse = map[int]string{
+	0: "auto",
+	1: "low",
+	2: "lowMid",
+	3: "mid",
+	4: "highMid",
+	5: "high",
+}
+
+// AirSwingUD define the vertical vane positions of the device
+var AirSwingUD = map[string]int{
+	"up":      0,
+	"down":    1,
+	"mid":     2,
+	"upMid":   3,
+	"downMid": 4,
+}
+
+// AirSwingUDReverse define the vertical vane positions of the device
+var AirSwingUDReverse = map[int]string{
+	0: "up",
+	1: "down",
+	2: "mid",
+	3: "upMid",
+	4: "downMid",
+}
+
+// FanAutoMode define which vanes swing automatically
+var FanAutoMode = map[string]int{
+	"both":     0,
+	"disabled": 1,
+	"ud":       2,
+	"lr":       3,
+}
+
 // Operate defines if the AC is on or off
 var Operate = map[int]string{
 	0: "Off",
@@ -102,6 +151,25 @@ type DeviceControlParameters struct {
 	UpdateTime              *int     `json:"updateTime,omitempty"`
 }
 
+// Describe formats the parameters that are set for logging, as in
+// "set power on mode heat setpoint 21.0"
+func (p DeviceControlParameters) Describe() string {
+	s := "set"
+	if p.Operate != nil {
+		s += fmt.Sprintf(" power %s", map[int]string{0: "off", 1: "on"}[*p.Operate])
+	}
+	if p.OperationMode != nil {
+		s += fmt.Sprintf(" mode %s", ModesReverse[*p.OperationMode])
+	}
+	if p.TemperatureSet != nil {
+		s += fmt.Sprintf(" setpoint %.1f", *p.TemperatureSet)
+	}
+	if p.PowerfulMode != nil {
+		s += fmt.Sprintf(" powerful %v", *p.PowerfulMode)
+	}
+	return s
+}
+
 // DeviceParameters are the current device parameters
 // Used when UnMarshalling current device status
 type DeviceParameters struct {
@@ -143,7 +211,7 @@ type Device struct {
 	AutoTempMax        int              `json:"autoTempMax"`
 	AutoTempMin        int              `json:"autoTempMin"`
 	CoolMode           bool             `json:"coolMode"`
-	CoolTempMax        int              `json:"coolTeampMax"`
+	CoolTempMax        int              `json:"coolTempMax"`
 	CoolTempMin        int              `json:"coolTempMin"`
 	DeviceGUID         string           `json:"deviceGuid"`
 	DeviceHashGUID     string           `json:"deviceHashGuid"`
@@ -160,13 +228,13 @@ type Device struct {
 	FanSpeedMode       int              `json:"fanSpeedMode"`
 	HeatMode           bool             `json:"heatMode"`
 	HeatTempMax        int              `json:"heatTempMax"`
-	HeatTempMin        int              `json:"heatTeampMin"`
+	HeatTempMin        int              `json:"heatTempMin"`
 	IautoX             bool             `json:"iAutoX"`
 	ModeAvlAutoMode    bool             `json:"modeAvlList.autoMode"`
 	ModeAvlFanMode     bool             `json:"modeAvlList.fanMode"`
 	Nanoe              bool             `json:"nanoe"`
 	QuietMode          bool             `json:"quietMode"`
-	SummerHouse        int              `json:"summerHouse"`
+	SummerHouse        int              `json:"summerHouse"` // non-zero when summer house mode lowers the heating range
 	TemperatureUnit    int              `json:"temperatureUnit"`
 	TimeStamp          int              `json:"timestamp"`
 	Parameters         DeviceParameters `json:"parameters"`
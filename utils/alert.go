@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/alert"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func alertCommand() *command {
+	fs := flag.NewFlagSet("alert", flag.ExitOnError)
+	interval := fs.Duration("interval", watch.DefaultInterval, "Poll interval")
+	maxBackoff := fs.Duration("max-backoff", watch.DefaultMaxBackoff, "Maximum poll interval after errors")
+	test := fs.Bool("test", false, "Send a test alert to all sinks and exit")
+	return &command{
+		name:  "alert",
+		help:  "Send notifications for device events matching the alerts config",
+		flags: fs,
+		validate: func() error {
+			if *interval <= 0 || *maxBackoff < *interval {
+				return fmt.Errorf("error: -interval must be positive and not above -max-backoff")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			config := alert.Config{}
+			if err := viper.UnmarshalKey("alerts", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid alerts in config: %w", err))
+			}
+			if len(config.Sinks) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no alerts.sinks in config"))
+			}
+			d, err := alert.New(client, config)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+			if *test {
+				return testAlert(d)
+			}
+			if len(config.Rules) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no alerts.rules in config"))
+			}
+
+			devices, err := client.ListDevices()
+			if err != nil {
+				return err
+			}
+			w := watch.New(client, devices)
+			w.Interval = *interval
+			w.MaxBackoff = *maxBackoff
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Watching %d device(s) for %d alert rule(s)", len(devices), len(config.Rules))
+			d.Run(ctx, w.Watch(ctx))
+			return nil
+		},
+	}
+}
+
+// testAlert sends a test alert to every sink.
+func testAlert(d *alert.Dispatcher) error {
+	a := alert.Alert{
+		Rule:    "test",
+		Title:   "go-panasonic: test",
+		Message: "Test alert from go-panasonic",
+		Event:   watch.Event{Time: time.Now(), Type: "test"},
+	}
+	failed := 0
+	for name, sink := range d.Sinks {
+		if err := sink.Send(context.Background(), a); err != nil {
+			log.Errorf("Sending test alert to %s failed: %v", name, err)
+			failed++
+			continue
+		}
+		log.Infof("Sent test alert to %s", name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("error: %d of %d sink(s) failed", failed, len(d.Sinks))
+	}
+
+	return nil
+}
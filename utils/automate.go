@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/automation"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func automateCommand() *command {
+	fs := flag.NewFlagSet("automate", flag.ExitOnError)
+	interval := fs.Duration("interval", automation.DefaultInterval, "Time between evaluations")
+	dryRun := fs.Bool("dry-run", false, "Evaluate the automations without changing devices")
+	once := fs.Bool("once", false, "Evaluate once, print the result of every automation and exit")
+	return &command{
+		name:  "automate",
+		help:  "Run conditional automations from the config",
+		flags: fs,
+		validate: func() error {
+			if *interval <= 0 {
+				return fmt.Errorf("error: -interval must be positive")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			config := automation.Config{}
+			if err := viper.UnmarshalKey("automations", &config.Rules); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid automations in config: %w", err))
+			}
+			if len(config.Rules) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no automations in config"))
+			}
+			e, err := automation.New(client, config)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+			e.DryRun = *dryRun
+
+			if *once {
+				return printEvaluation(e.Evaluate())
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Running %d automation(s)", len(config.Rules))
+			e.Run(ctx, *interval)
+			return nil
+		},
+	}
+}
+
+// printEvaluation prints the result of every automation and device.
+func printEvaluation(results []automation.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AUTOMATION\tDEVICE\tMATCHED\tTRIGGERED\tERROR")
+	failed := 0
+	for _, r := range results {
+		name := r.DeviceName
+		if name == "" {
+			name = r.DeviceGUID
+		}
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%v\t%s\n", r.Rule, name, r.Matched, r.Triggered, errText)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("error: %d of %d evaluation(s) failed", failed, len(results))
+	}
+	return nil
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/hacktobeer/go-panasonic/cloudcontrol"
-	pt "github.com/hacktobeer/go-panasonic/types"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/fixture"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/spf13/viper"
@@ -19,18 +21,24 @@ var (
 
 	configFlag  = flag.String("config", "gopanasonic.yaml", "Path of YAML configuration file")
 	debugFlag   = flag.Bool("debug", false, "Show debug output")
-	deviceFlag  = flag.String("device", "", "Device to issue command to")
-	historyFlag = flag.String("history", "", "Display history: day,week,month,year")
-	listFlag    = flag.Bool("list", false, "List available devices")
-	modeFlag    = flag.String("mode", "", "Set mode: auto,heat,cool,dry,fan")
-	offFlag     = flag.Bool("off", false, "Turn device off")
-	onFlag      = flag.Bool("on", false, "Turn device on")
 	quietFlag   = flag.Bool("quiet", false, "Don't output any log messages")
-	statusFlag  = flag.Bool("status", false, "Display current status of device")
-	tempFlag    = flag.Float64("temp", 0, "Set the temperature (in Celsius)")
+	recordFlag  = flag.String("record", "", "Record the redacted cloud traffic to a fixture file")
+	replayFlag  = flag.String("replay", "", "Replay the cloud traffic from a fixture file instead of contacting the cloud")
+	serverFlag  = flag.String("server", "", "Panasonic Comfort Cloud URL, overrides server from the configuration file")
 	versionFlag = flag.Bool("version", false, "Show build version information")
 )
 
+// globalFlags are the flags accepted in front of a command.
+var globalFlags = map[string]bool{
+	"config":  true,
+	"debug":   true,
+	"quiet":   true,
+	"record":  true,
+	"replay":  true,
+	"server":  true,
+	"version": true,
+}
+
 func readConfig() {
 	viper.SetConfigFile(*configFlag)
 	viper.SetConfigType("yaml")
@@ -40,159 +48,167 @@ func readConfig() {
 	}
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		flag.PrintDefaults()
-		os.Exit(1)
+// serverURL returns the cloud URL from the -server flag or the
+// configuration file.
+func serverURL() string {
+	if *serverFlag != "" {
+		return *serverFlag
 	}
+	return viper.GetString("server")
+}
 
-	flag.Parse()
-
-	if *quietFlag {
-		log.SetLevel(log.PanicLevel)
+// httpClient is shared by all clients so a replay is consumed once.
+var httpClient *http.Client
+
+// newCloudClient creates a client for the cloud, recording or replaying
+// the traffic with -record and -replay.
+func newCloudClient() (cloudcontrol.Client, error) {
+	client := cloudcontrol.NewClient(serverURL())
+	if httpClient == nil {
+		switch {
+		case *recordFlag != "" && *replayFlag != "":
+			return client, withCode(exitValidation, fmt.Errorf("error: -record and -replay can't be combined"))
+		case *recordFlag != "":
+			httpClient = &http.Client{Transport: fixture.NewRecorder(*recordFlag, nil), Timeout: cloudcontrol.DefaultTimeout}
+		case *replayFlag != "":
+			replayer, err := fixture.Open(*replayFlag, fixture.Lenient)
+			if err != nil {
+				return client, withCode(exitValidation, err)
+			}
+			httpClient = &http.Client{Transport: replayer, Timeout: cloudcontrol.DefaultTimeout}
+		default:
+			httpClient = &http.Client{Timeout: cloudcontrol.DefaultTimeout}
+		}
 	}
+	client.HTTPClient = httpClient
+	return client, nil
+}
 
-	if *debugFlag {
-		log.SetLevel(log.DebugLevel)
-		log.Debug("Logging set to debug level")
+// login creates a new session with the configured username and
+// password and writes the session token to the configuration file.
+func login(client *cloudcontrol.Client) error {
+	user := viper.GetString("username")
+	pass := viper.GetString("password")
+	if user == "" || pass == "" {
+		return withCode(exitAuth, fmt.Errorf("error: No username and password given, can't login"))
 	}
 
-	if *versionFlag {
-		fmt.Printf("version: %s\n", version)
-		fmt.Printf("commit: %s\n", commit)
-		fmt.Printf("date: %s\n", date)
-		os.Exit(0)
+	if _, err := client.CreateSession(user, pass); err != nil {
+		return err
+	}
+	viper.Set("token", client.Utoken)
+	if err := viper.WriteConfig(); err != nil {
+		return err
 	}
+	log.Debug("New session token requested and written to config")
 
-	readConfig()
-	user := viper.GetString("username")
-	pass := viper.GetString("password")
-	server := viper.GetString("server")
-	token := viper.GetString("token")
+	return nil
+}
 
-	client := cloudcontrol.NewClient(server)
+// newClient creates a client from the configuration file and makes sure
+// it has a valid session.
+func newClient() (cloudcontrol.Client, error) {
+	client, err := newCloudClient()
+	if err != nil {
+		return client, err
+	}
+	client.SetAliases(viper.GetStringMapString("aliases"))
 
+	token := viper.GetString("token")
 	if token != "" {
-		if body, err := client.ValidateSession(token); err != nil {
-			log.Debugf("ValidateSession Error: %s", string(body))
-			if user != "" && pass != "" {
-				_, err := client.CreateSession(user, pass)
-				if err != nil {
-					log.Fatalln(err)
-				}
-				viper.Set("token", client.Utoken)
-				viper.WriteConfig()
-				log.Debug("New session token requested and written to config")
-			} else {
-				log.Fatalln("No username and password given, can't login.")
-			}
-		} else {
+		body, err := client.ValidateSession(token)
+		if err == nil {
 			log.Debugln("Session token passed validation check")
+			return client, nil
 		}
+		log.Debugf("ValidateSession Error: %s", string(body))
 	}
 
-	if *listFlag {
-		log.Infoln("Listing available devices.....")
-		devices, err := client.ListDevices()
-		if err != nil {
-			log.Fatalln(err)
-		}
+	return client, login(&client)
+}
 
-		if len(devices) != 0 {
-			log.Infof("%d device(s) found:\n", len(devices))
-			for _, device := range devices {
-				fmt.Println(device)
-			}
-		} else {
-			log.Fatalln("error: No devices for configured account")
-		}
-		os.Exit(0)
+// parseDate parses a YYYY-MM-DD flag value in local time, returning def
+// when the value is empty.
+func parseDate(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return date, fmt.Errorf("error: invalid date %s: %v", value, err)
 	}
+	return date, nil
+}
 
-	// Read device from flag
-	if *deviceFlag != "" {
-		log.Debugf("Device set to %s", *deviceFlag)
-		client.SetDevice(*deviceFlag)
+// dateRange parses a -from and -to date range, defaulting to the
+// last 7 days including today.
+func dateRange(fromValue, toValue string) (time.Time, time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from, err := parseDate(fromValue, today.AddDate(0, 0, -7))
+	if err != nil {
+		return from, from, err
 	}
-	// Read device from configuration file
-	configDevice := viper.GetString("device")
-	if configDevice != "" {
-		log.Debugf("Device set to %s", configDevice)
-		client.SetDevice(configDevice)
+	to, err := parseDate(toValue, today.AddDate(0, 0, 1))
+	return from, to, err
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] <command> [command flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands() {
+		fmt.Fprintf(out, "  %-9s %s\n", cmd.name, cmd.help)
 	}
-	// Exit if no devices are configured
-	if client.DeviceGUID == "" {
-		log.Fatalln("error: No device configured, please use -device flag or configuration file")
+	fmt.Fprintf(out, "\nRun '%s help <command>' for the flags of a command.\n\nFlags:\n", os.Args[0])
+	flag.VisitAll(func(f *flag.Flag) {
+		if globalFlags[f.Name] {
+			fmt.Fprintf(out, "  -%s\n    \t%s\n", f.Name, f.Usage)
+		}
+	})
+}
+
+func main() {
+	flag.Usage = usage
+	if len(os.Args) < 2 {
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	if *statusFlag {
-		log.Infoln("Fetching status.....")
-		status, err := client.GetDeviceStatus()
-		if err != nil {
-			log.Fatalln(err)
-		}
+	flag.Parse()
 
-		fmt.Printf("GUID: %s\n", status.DeviceGUID)
-		fmt.Println("Capabilities:")
-		fmt.Printf("Auto mode: %t\n", status.AutoMode)
-		fmt.Printf("Heat mode: %t\n", status.HeatMode)
-		fmt.Printf("Dry mode: %t\n", status.DryMode)
-		fmt.Printf("Cool mode: %t\n", status.CoolMode)
-		fmt.Printf("Fan mode: %t\n", status.FanMode)
-		fmt.Printf("Fan Speed mode: %d\n", status.FanSpeedMode)
-		fmt.Printf("Quiet mode: %t\n", status.QuietMode)
-		fmt.Printf("Eco function: %d\n", status.EcoFunction)
-		fmt.Printf("EcoNavi function: %t\n", status.EcoNavi)
-		fmt.Printf("iAutoX: %t\n", status.IautoX)
-		fmt.Printf("NanoeX: %t\n", status.Nanoe)
-		fmt.Println("Current status:")
-		fmt.Printf("Status: %s\n", pt.Operate[status.Parameters.Operate])
-		fmt.Printf("Online: %t\n", status.Parameters.Online)
-		fmt.Printf("Temperature: %0.1f\n", status.Parameters.TemperatureSet)
-		fmt.Printf("Mode: %s\n", pt.ModesReverse[status.Parameters.OperationMode])
-	}
-
-	if *historyFlag != "" {
-		log.Infof("Fetching historical data for this %s.....\n", *historyFlag)
-		history, err := client.GetDeviceHistory(pt.HistoryDataMode[*historyFlag])
-		if err != nil {
-			log.Fatalln(err)
-		}
-		fmt.Println("#,AverageSettingTemp,AverageInsideTemp,AverageOutsideTemp")
-		for _, v := range history.HistoryEntries {
-			fmt.Printf("%v,%v,%v,%v\n", v.DataNumber+1, v.AverageSettingTemp, v.AverageInsideTemp, v.AverageOutsideTemp)
-		}
+	if *quietFlag {
+		log.SetLevel(log.PanicLevel)
 	}
 
-	if *onFlag {
-		log.Infoln("Turning device on.....")
-		_, err := client.TurnOn()
-		if err != nil {
-			log.Fatalln(err)
-		}
+	if *debugFlag {
+		log.SetLevel(log.DebugLevel)
+		log.Debug("Logging set to debug level")
 	}
 
-	if *offFlag {
-		log.Infoln("Turning device off....")
-		_, err := client.TurnOff()
-		if err != nil {
-			log.Fatalln(err)
-		}
+	if *versionFlag {
+		fmt.Printf("version: %s\n", version)
+		fmt.Printf("commit: %s\n", commit)
+		fmt.Printf("date: %s\n", date)
+		os.Exit(0)
 	}
 
-	if *tempFlag != 0 {
-		log.Infof("Setting temperature to %v degrees Celsius", *tempFlag)
-		_, err := client.SetTemperature(*tempFlag)
-		if err != nil {
-			log.Fatalln(err)
-		}
+	// Flags without a command are the deprecated flat interface
+	if flag.NArg() == 0 {
+		runLegacy()
+		return
 	}
 
-	if *modeFlag != "" {
-		log.Infof("Setting mode to %s", *modeFlag)
-		_, err := client.SetMode(pt.Modes[*modeFlag])
-		if err != nil {
-			log.Fatalln(err)
+	legacy := []string{}
+	flag.Visit(func(f *flag.Flag) {
+		if !globalFlags[f.Name] {
+			legacy = append(legacy, "-"+f.Name)
 		}
+	})
+	if len(legacy) > 0 {
+		fatal(withCode(exitValidation, fmt.Errorf("error: deprecated flag(s) %v can't be combined with the %s command", legacy, flag.Arg(0))))
+	}
+
+	if err := runCommand(flag.Args()); err != nil {
+		fatal(err)
 	}
 }
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/archive"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/tariff"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/viper"
+)
+
+// command is a CLI subcommand with its own flag set.
+type command struct {
+	name  string
+	help  string
+	flags *flag.FlagSet
+	// run is called with a logged in client after the flags are parsed
+	run func(client *cloudcontrol.Client) error
+	// validate checks the parsed flags before logging in
+	validate func() error
+	// standalone commands run without configuration file and client
+	standalone bool
+}
+
+// target holds the -device, -group and -parallel flags
+// shared by the device commands.
+type target struct {
+	device   string
+	group    string
+	parallel int
+}
+
+// register adds the target flags to a flag set.
+func (t *target) register(fs *flag.FlagSet) {
+	fs.StringVar(&t.device, "device", "", "Device GUID, name, model number or alias, defaults to the configured device")
+	fs.StringVar(&t.group, "group", "", "Group name to issue the command to all its devices")
+	fs.IntVar(&t.parallel, "parallel", cloudcontrol.DefaultParallelism, "Maximum number of devices contacted at once with -group")
+}
+
+// validate checks for conflicting target flags.
+func (t *target) validate() error {
+	if t.device != "" && t.group != "" {
+		return fmt.Errorf("error: -device and -group can't be used together")
+	}
+	if t.parallel < 1 {
+		return fmt.Errorf("error: -parallel must be at least 1")
+	}
+	return nil
+}
+
+// resolve returns the GUIDs of the targeted devices.
+func (t *target) resolve(client *cloudcontrol.Client) ([]string, error) {
+	if t.group != "" {
+		devices, err := client.ResolveDevices(t.group)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Group %s resolved to %v", t.group, devices)
+		return devices, nil
+	}
+
+	device := t.device
+	if device == "" {
+		device = viper.GetString("device")
+	}
+	if device == "" {
+		return nil, withCode(exitValidation, fmt.Errorf("error: No device configured, please use -device flag or configuration file"))
+	}
+	if err := client.SetDeviceByName(device); err != nil {
+		return nil, err
+	}
+	log.Debugf("Device %s set to %s", device, client.DeviceGUID)
+
+	return []string{client.DeviceGUID}, nil
+}
+
+// oneOf checks that value is one of the allowed values.
+func oneOf(name, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("error: invalid -%s %q, must be one of %v", name, value, allowed)
+}
+
+// isSet reports whether a flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func loginCommand() *command {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	return &command{
+		name:  "login",
+		help:  "Log in with the configured credentials and store the session token",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			if err := login(client); err != nil {
+				return err
+			}
+			log.Infoln("Logged in, session token written to config")
+			return nil
+		},
+	}
+}
+
+func devicesCommand() *command {
+	fs := flag.NewFlagSet("devices", flag.ExitOnError)
+	o := &output{}
+	o.register(fs, "table", "json", "yaml", "table", "template")
+	fs.StringVar(&o.format, "format", "table", "Deprecated: use -output")
+	return &command{
+		name:  "devices",
+		help:  "List available devices",
+		flags: fs,
+		validate: func() error {
+			return o.validate("json", "yaml", "table", "template")
+		},
+		run: func(client *cloudcontrol.Client) error {
+			return listDevices(client, o)
+		},
+	}
+}
+
+func statusCommand() *command {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	t := &target{}
+	t.register(fs)
+	o := &output{}
+	o.register(fs, "table", "json", "yaml", "table", "template")
+	return &command{
+		name:  "status",
+		help:  "Display current status of a device or group",
+		flags: fs,
+		validate: func() error {
+			if err := t.validate(); err != nil {
+				return err
+			}
+			return o.validate("json", "yaml", "table", "template")
+		},
+		run: func(client *cloudcontrol.Client) error {
+			devices, err := t.resolve(client)
+			if err != nil {
+				return err
+			}
+			if t.group == "" {
+				return deviceStatus(client, o)
+			}
+			return groupStatus(client, devices, t.parallel, o)
+		},
+	}
+}
+
+func setCommand() *command {
+	fs := flag.NewFlagSet("set", flag.ExitOnError)
+	t := &target{}
+	t.register(fs)
+	power := fs.String("power", "", "Turn device on or off: on,off")
+	mode := fs.String("mode", "", "Set mode: auto,heat,cool,dry,fan")
+	temp := fs.Float64("temp", 0, "Set the temperature (in Celsius)")
+
+	params := pt.DeviceControlParameters{}
+	return &command{
+		name:  "set",
+		help:  "Change power, mode and temperature of a device or group in one command",
+		flags: fs,
+		validate: func() error {
+			if err := t.validate(); err != nil {
+				return err
+			}
+			if *power == "" && *mode == "" && !isSet(fs, "temp") {
+				return fmt.Errorf("error: nothing to set, use -power, -mode or -temp")
+			}
+			if *power != "" {
+				if err := oneOf("power", *power, "on", "off"); err != nil {
+					return err
+				}
+				operate := 0
+				if *power == "on" {
+					operate = 1
+				}
+				params.Operate = &operate
+			}
+			if *mode != "" {
+				m, ok := pt.Modes[*mode]
+				if !ok {
+					return oneOf("mode", *mode, "auto", "heat", "cool", "dry", "fan")
+				}
+				params.OperationMode = &m
+			}
+			if isSet(fs, "temp") {
+				if *temp < 0 || *temp > 40 {
+					return fmt.Errorf("error: invalid -temp %v, must be between 0 and 40", *temp)
+				}
+				params.TemperatureSet = temp
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			devices, err := t.resolve(client)
+			if err != nil {
+				return err
+			}
+			log.Infof("Changing state of %d device(s).....", len(devices))
+			return checkResults(client.Each(devices, t.parallel, func(c *cloudcontrol.Client) ([]byte, error) {
+				return c.SetState(params)
+			}))
+		},
+	}
+}
+
+func historyCommand() *command {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	t := &target{}
+	fs.StringVar(&t.device, "device", "", "Device GUID, name, model number or alias, defaults to the configured device")
+	period := fs.String("period", "day", "History period: day,week,month,year")
+	o := &output{}
+	o.register(fs, "csv", "json", "yaml", "table", "template", "csv")
+	return &command{
+		name:  "history",
+		help:  "Display history of a device",
+		flags: fs,
+		validate: func() error {
+			if err := oneOf("period", *period, "day", "week", "month", "year"); err != nil {
+				return err
+			}
+			return o.validate("json", "yaml", "table", "template", "csv")
+		},
+		run: func(client *cloudcontrol.Client) error {
+			if _, err := t.resolve(client); err != nil {
+				return err
+			}
+			return deviceHistory(client, *period, o)
+		},
+	}
+}
+
+func syncCommand() *command {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	path := fs.String("archive", "gopanasonic.db", "Path of local history archive")
+	backfill := fs.Int("backfill", archive.DefaultBackfill, "Days of history to back-fill")
+	interval := fs.Duration("interval", 0, "Keep syncing at this interval")
+	return &command{
+		name:  "sync",
+		help:  "Sync history of all devices to the local archive",
+		flags: fs,
+		validate: func() error {
+			if *backfill < 0 {
+				return fmt.Errorf("error: -backfill can't be negative")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			return syncArchive(client, *path, *backfill, *interval)
+		},
+	}
+}
+
+func queryCommand() *command {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	t := &target{}
+	fs.StringVar(&t.device, "device", "", "Device GUID, name, model number or alias, defaults to the configured device")
+	path := fs.String("archive", "gopanasonic.db", "Path of local history archive")
+	agg := fs.String("aggregate", "day", "Aggregation: hour,day,month")
+	from := fs.String("from", "", "Start date (YYYY-MM-DD), defaults to 7 days ago")
+	to := fs.String("to", "", "End date (YYYY-MM-DD, exclusive), defaults to tomorrow")
+	return &command{
+		name:  "query",
+		help:  "Query the local history archive",
+		flags: fs,
+		validate: func() error {
+			if _, _, err := dateRange(*from, *to); err != nil {
+				return err
+			}
+			return oneOf("aggregate", *agg, "hour", "day", "month")
+		},
+		run: func(client *cloudcontrol.Client) error {
+			if _, err := t.resolve(client); err != nil {
+				return err
+			}
+			start, end, _ := dateRange(*from, *to)
+			return queryArchive(client, *path, archive.Aggregations[*agg], start, end)
+		},
+	}
+}
+
+func costCommand() *command {
+	fs := flag.NewFlagSet("cost", flag.ExitOnError)
+	path := fs.String("tariff", "", "Path of YAML tariff file, defaults to the configured tariff")
+	period := fs.String("period", "day", "Report period: day,month")
+	from := fs.String("from", "", "Start date (YYYY-MM-DD), defaults to 7 days ago")
+	to := fs.String("to", "", "End date (YYYY-MM-DD, exclusive), defaults to tomorrow")
+	return &command{
+		name:  "cost",
+		help:  "Display energy cost report for all devices",
+		flags: fs,
+		validate: func() error {
+			if _, _, err := dateRange(*from, *to); err != nil {
+				return err
+			}
+			return oneOf("period", *period, "day", "month")
+		},
+		run: func(client *cloudcontrol.Client) error {
+			start, end, _ := dateRange(*from, *to)
+			return costReport(client, *path, *period, start, end)
+		},
+	}
+}
+
+func watchCommand() *command {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	t := &target{parallel: 1}
+	fs.StringVar(&t.device, "device", "", "Device GUID, name, model number or alias, defaults to all devices")
+	fs.StringVar(&t.group, "group", "", "Group name to watch all its devices")
+	interval := fs.Duration("interval", watch.DefaultInterval, "Poll interval")
+	maxBackoff := fs.Duration("max-backoff", watch.DefaultMaxBackoff, "Maximum poll interval after errors")
+	o := &output{}
+	o.register(fs, "text", "text", "ndjson")
+	return &command{
+		name:  "watch",
+		help:  "Stream device state changes",
+		flags: fs,
+		validate: func() error {
+			if err := t.validate(); err != nil {
+				return err
+			}
+			if *interval <= 0 || *maxBackoff < *interval {
+				return fmt.Errorf("error: -interval must be positive and not above -max-backoff")
+			}
+			return o.validate("text", "ndjson")
+		},
+		run: func(client *cloudcontrol.Client) error {
+			var devices []string
+			var err error
+			if t.device == "" && t.group == "" {
+				devices, err = client.ListDevices()
+			} else {
+				devices, err = t.resolve(client)
+			}
+			if err != nil {
+				return err
+			}
+			return watchDevices(client, devices, *interval, *maxBackoff, o)
+		},
+	}
+}
+
+// commands returns all available subcommands.
+func commands() []*command {
+	return []*command{
+		loginCommand(),
+		devicesCommand(),
+		statusCommand(),
+		setCommand(),
+		historyCommand(),
+		syncCommand(),
+		queryCommand(),
+		costCommand(),
+		watchCommand(),
+		serveCommand(),
+		mqttCommand(),
+		grpcCommand(),
+		homekitCommand(),
+		alertCommand(),
+		thermostatCommand(),
+		scheduleCommand(),
+		automateCommand(),
+		optimizeCommand(),
+		solarCommand(),
+		safeguardCommand(),
+		windowCommand(),
+		presenceCommand(),
+		fakeCloudCommand(),
+	}
+}
+
+// findCommand returns the command with the given name.
+func findCommand(name string) (*command, error) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, nil
+		}
+	}
+	return nil, fmt.Errorf("error: unknown command %q, run '%s help' for usage", name, os.Args[0])
+}
+
+// commandUsage prints the help of a command.
+func commandUsage(cmd *command) {
+	out := cmd.flags.Output()
+	fmt.Fprintf(out, "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", os.Args[0], cmd.name, cmd.help)
+	cmd.flags.PrintDefaults()
+}
+
+// runCommand parses the arguments of a command and runs it.
+func runCommand(args []string) error {
+	if args[0] == "help" {
+		if len(args) < 2 {
+			flag.Usage()
+			return nil
+		}
+		cmd, err := findCommand(args[1])
+		if err != nil {
+			return withCode(exitValidation, err)
+		}
+		commandUsage(cmd)
+		return nil
+	}
+
+	cmd, err := findCommand(args[0])
+	if err != nil {
+		return withCode(exitValidation, err)
+	}
+	cmd.flags.Usage = func() { commandUsage(cmd) }
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		return withCode(exitValidation, err)
+	}
+	if cmd.flags.NArg() > 0 {
+		return withCode(exitValidation, fmt.Errorf("error: unexpected argument(s) %v for %s", cmd.flags.Args(), cmd.name))
+	}
+	if cmd.validate != nil {
+		if err := cmd.validate(); err != nil {
+			return withCode(exitValidation, err)
+		}
+	}
+
+	if cmd.standalone {
+		return cmd.run(nil)
+	}
+
+	readConfig()
+	client, err := newCloudClient()
+	if err != nil {
+		return err
+	}
+	if cmd.name != "login" {
+		if client, err = newClient(); err != nil {
+			return err
+		}
+	}
+
+	return cmd.run(&client)
+}
+
+// listDevices prints all devices of the account.
+func listDevices(client *cloudcontrol.Client, o *output) error {
+	log.Infoln("Listing available devices.....")
+	devices, err := client.ListGroupDevices()
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("error: No devices for configured account")
+	}
+
+	log.Infof("%d device(s) found:\n", len(devices))
+	return printDevices(devices, o)
+}
+
+// deviceStatus prints the status of the client device. It returns
+// ErrDeviceOffline when the device is not connected.
+func deviceStatus(client *cloudcontrol.Client, o *output) error {
+	log.Infoln("Fetching status.....")
+	status, err := client.GetDeviceStatus()
+	if err != nil {
+		return err
+	}
+
+	if err := writeStatus(status, o); err != nil {
+		return err
+	}
+	if !status.Parameters.Online {
+		return fmt.Errorf("%w: %s", cloudcontrol.ErrDeviceOffline, status.DeviceGUID)
+	}
+
+	return nil
+}
+
+// groupStatus prints the status of every device. Failed devices are
+// logged and reported in the returned error.
+func groupStatus(client *cloudcontrol.Client, devices []string, parallel int, o *output) error {
+	log.Infof("Fetching status of %d device(s).....", len(devices))
+	statuses := []pt.Device{}
+	var errs []error
+	for _, r := range client.EachStatus(devices, parallel) {
+		if r.Err != nil {
+			log.Errorf("%s: %v", r.DeviceGUID, r.Err)
+			errs = append(errs, r.Err)
+			continue
+		}
+		if !r.Status.Parameters.Online {
+			errs = append(errs, fmt.Errorf("%w: %s", cloudcontrol.ErrDeviceOffline, r.DeviceGUID))
+		}
+		statuses = append(statuses, r.Status)
+	}
+
+	if err := writeStatuses(statuses, o); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error: %d of %d device(s) failed: %w", len(errs), len(devices), errs[0])
+	}
+
+	return nil
+}
+
+// checkResults logs failed device commands and returns an error
+// when any of them failed.
+func checkResults(results []cloudcontrol.DeviceResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			log.Errorf("%s: %v", r.DeviceGUID, r.Err)
+			errs = append(errs, r.Err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error: %d of %d device(s) failed: %w", len(errs), len(results), errs[0])
+	}
+
+	return nil
+}
+
+// deviceHistory prints the history of the client device.
+func deviceHistory(client *cloudcontrol.Client, period string, o *output) error {
+	log.Infof("Fetching historical data for this %s.....\n", period)
+	history, err := client.GetDeviceHistory(pt.HistoryDataMode[period])
+	if err != nil {
+		return err
+	}
+
+	return writeHistory(history, o)
+}
+
+// syncArchive syncs history of all devices to the archive at path,
+// repeating at interval when it is not zero.
+func syncArchive(client *cloudcontrol.Client, path string, backfill int, interval time.Duration) error {
+	log.Infoln("Syncing history to local archive.....")
+	a, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	syncer := archive.NewSyncer(client, a)
+	syncer.Backfill = backfill
+	if interval > 0 {
+		return syncer.Run(context.Background(), interval)
+	}
+	n, err := syncer.Sync()
+	if err != nil {
+		return err
+	}
+	log.Infof("%d history record(s) stored\n", n)
+
+	return nil
+}
+
+// queryArchive prints aggregated archive data of the client device.
+func queryArchive(client *cloudcontrol.Client, path string, agg archive.Aggregation, from, to time.Time) error {
+	a, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	points, err := a.Query(client.DeviceGUID, from, to, agg)
+	if err != nil {
+		return err
+	}
+
+	printPoints(points)
+	return nil
+}
+
+// costReport fetches day-mode history for all devices and prints
+// per device and account-wide cost reports. Tiered tariffs fetch the
+// history from the start of the month of from, so the tiers count the
+// whole month.
+func costReport(client *cloudcontrol.Client, path string, period string, from, to time.Time) error {
+	log.Infoln("Calculating energy costs.....")
+	if path == "" {
+		path = viper.GetString("tariff")
+	}
+	if path == "" {
+		return fmt.Errorf("error: No tariff configured, please use -tariff flag or configuration file")
+	}
+	tf, err := tariff.Load(path)
+	if err != nil {
+		return err
+	}
+
+	devices, err := client.ListDevices()
+	if err != nil {
+		return err
+	}
+	start := from
+	if tf.Type == tariff.Tiered {
+		start = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	}
+	usage := map[string][]tariff.Usage{}
+	for _, device := range devices {
+		client.SetDevice(device)
+		for day := start; day.Before(to); day = day.AddDate(0, 0, 1) {
+			history, err := client.GetDeviceHistoryForDate(pt.HistoryDataMode["day"], day)
+			if err != nil {
+				return err
+			}
+			usage[device] = append(usage[device], tariff.FromHistory(day, history)...)
+		}
+	}
+
+	fmt.Println("Device,Start,Consumption,Cost,Currency")
+	for _, device := range devices {
+		report, err := tf.CalculateFrom(usage[device], from)
+		if err != nil {
+			return err
+		}
+		printCosts(device, period, report)
+	}
+	report, err := tf.CalculateAccountFrom(usage, from)
+	if err != nil {
+		return err
+	}
+	printCosts("account", period, report)
+
+	return nil
+}
+
+// watchDevices prints state changes of the devices until interrupted.
+func watchDevices(client *cloudcontrol.Client, devices []string, interval, maxBackoff time.Duration, o *output) error {
+	log.Infof("Watching %d device(s).....", len(devices))
+	w := watch.New(client, devices)
+	w.Interval = interval
+	w.MaxBackoff = maxBackoff
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for e := range w.Watch(ctx) {
+		if o.format == "ndjson" {
+			if err := encoder.Encode(e); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Println(e)
+	}
+
+	return nil
+}
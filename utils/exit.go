@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	log "github.com/sirupsen/logrus"
+)
+
+// Exit codes of the cli tool. These are part of the public interface
+// and documented in the README, don't change existing values.
+const (
+	exitOK         = 0 // Success
+	exitError      = 1 // Any other error, eg an error response from the cloud
+	exitValidation = 2 // Invalid command, flag or argument
+	exitAuth       = 3 // Login failed or no credentials configured
+	exitOffline    = 4 // Device is offline
+	exitNetwork    = 5 // Panasonic Comfort Cloud could not be reached
+)
+
+// codedError attaches an exit code to an error.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns err with the given exit code attached.
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	var coded *codedError
+	var netErr net.Error
+	var urlErr *url.Error
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &coded):
+		return coded.code
+	case cloudcontrol.IsAuthError(err):
+		return exitAuth
+	case errors.Is(err, cloudcontrol.ErrDeviceOffline):
+		return exitOffline
+	case errors.As(err, &netErr), errors.As(err, &urlErr):
+		return exitNetwork
+	default:
+		return exitError
+	}
+}
+
+// fatal logs err and exits with its exit code.
+func fatal(err error) {
+	log.Errorln(err)
+	os.Exit(exitCode(err))
+}
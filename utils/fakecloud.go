@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/sim"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/spot"
+	log "github.com/sirupsen/logrus"
+)
+
+func fakeCloudCommand() *command {
+	fs := flag.NewFlagSet("fakecloud", flag.ExitOnError)
+	listen := fs.String("listen", "localhost:8765", "Address to listen on")
+	tokenTTL := fs.Duration("token-ttl", cloudtest.DefaultTokenTTL, "Lifetime of session tokens")
+	rateLimit := fs.Int("rate-limit", 0, "Maximum requests per token per minute, unlimited when 0")
+	simulate := fs.Bool("simulate", false, "Simulate room temperatures and energy consumption")
+	speed := fs.Float64("speed", 1, "Speed of simulated time relative to real time with -simulate")
+	outside := fs.Float64("outside", 5, "Mean outdoor temperature with -simulate")
+	amplitude := fs.Float64("outside-amplitude", 4, "Daily outdoor temperature swing around the mean with -simulate")
+	prices := fs.Bool("prices", false, "Serve synthetic hourly spot prices for today and tomorrow on /prices")
+	return &command{
+		name:       "fakecloud",
+		help:       "Serve a fake Panasonic Comfort Cloud for testing",
+		flags:      fs,
+		standalone: true,
+		validate: func() error {
+			if *tokenTTL <= 0 || *rateLimit < 0 {
+				return fmt.Errorf("error: -token-ttl must be positive and -rate-limit not negative")
+			}
+			if *speed <= 0 {
+				return fmt.Errorf("error: -speed must be positive")
+			}
+			return nil
+		},
+		run: func(*cloudcontrol.Client) error {
+			cloud := cloudtest.Default()
+			cloud.TokenTTL = *tokenTTL
+			cloud.RateLimit = *rateLimit
+			if *simulate {
+				clock := sim.NewClock(time.Now(), *speed)
+				cloud.Now = clock.Now
+				cloud.Model = sim.New(sim.Daily(*outside, *amplitude))
+				log.Infof("Simulating rooms at %v times real time", *speed)
+			}
+			log.Infof("Serving fake cloud on %s, log in with username %q and password %q", *listen, cloudtest.DefaultUser, cloudtest.DefaultPassword)
+			if !*prices {
+				return serve(*listen, cloud)
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/", cloud)
+			mux.Handle("/prices", spot.Handler(func() spot.Prices {
+				now := time.Now()
+				return spot.Synthetic(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), 48)
+			}))
+			log.Infof("Serving spot prices on http://%s/prices", *listen)
+			return serve(*listen, mux)
+		},
+	}
+}
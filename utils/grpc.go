@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/rpc"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/rpc/pb"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+func grpcCommand() *command {
+	fs := flag.NewFlagSet("grpc", flag.ExitOnError)
+	listen := fs.String("listen", "localhost:9200", "Address to listen on")
+	interval := fs.Duration("interval", watch.DefaultInterval, "Default poll interval of WatchStatus calls")
+	minInterval := fs.Duration("min-interval", rpc.DefaultMinInterval, "Shortest poll interval WatchStatus calls may request")
+	return &command{
+		name:  "grpc",
+		help:  "Serve the gRPC API",
+		flags: fs,
+		validate: func() error {
+			if *minInterval <= 0 || *interval < *minInterval {
+				return fmt.Errorf("error: -min-interval must be positive and not above -interval")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			s := rpc.NewServer(client)
+			s.Interval = *interval
+			s.MinInterval = *minInterval
+			return serveGRPC(*listen, s)
+		},
+	}
+}
+
+// serveGRPC runs the gRPC server until interrupted.
+func serveGRPC(listen string, s pb.PanasonicServer) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	pb.RegisterPanasonicServer(server, s)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Infof("Serving gRPC on %s", listen)
+	return server.Serve(listener)
+}
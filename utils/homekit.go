@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/brutella/hap"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/homekit"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func homekitCommand() *command {
+	fs := flag.NewFlagSet("homekit", flag.ExitOnError)
+	name := fs.String("name", homekit.DefaultName, "Name of the bridge in the Home app")
+	pin := fs.String("pin", "", "8 digit pairing code, defaults to homekit.pin from the config or a random code kept in the store")
+	store := fs.String("store", "homekit", "Directory to keep the pairing data in")
+	listen := fs.String("listen", "", "Address to listen on, a random port when empty")
+	interval := fs.Duration("interval", homekit.DefaultInterval, "Device status poll interval")
+	return &command{
+		name:  "homekit",
+		help:  "Expose devices as HomeKit accessories",
+		flags: fs,
+		validate: func() error {
+			if *interval <= 0 {
+				return fmt.Errorf("error: -interval must be positive")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			code := *pin
+			if code == "" {
+				code = viper.GetString("homekit.pin")
+			}
+			if code != "" {
+				if err := homekit.ValidatePin(code); err != nil {
+					return withCode(exitValidation, err)
+				}
+			}
+
+			b := homekit.New(client, hap.NewFsStore(*store))
+			b.Name = *name
+			b.Pin = code
+			b.Addr = *listen
+			b.Interval = *interval
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Serving HomeKit bridge %q", *name)
+			return b.Run(ctx)
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/archive"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Deprecated flat flags, replaced by commands. These will be
+// removed in the next release.
+var (
+	archiveFlag  = flag.String("archive", "gopanasonic.db", "Deprecated: use the sync or query command")
+	backfillFlag = flag.Int("backfill", archive.DefaultBackfill, "Deprecated: use the sync command")
+	costFlag     = flag.String("cost", "", "Deprecated: use the cost command")
+	deviceFlag   = flag.String("device", "", "Deprecated: use the -device flag of a command")
+	formatFlag   = flag.String("format", "table", "Deprecated: use the devices command")
+	fromFlag     = flag.String("from", "", "Deprecated: use the query or cost command")
+	groupFlag    = flag.String("group", "", "Deprecated: use the -group flag of a command")
+	historyFlag  = flag.String("history", "", "Deprecated: use the history command")
+	intervalFlag = flag.Duration("interval", 0, "Deprecated: use the sync command")
+	listFlag     = flag.Bool("list", false, "Deprecated: use the devices command")
+	modeFlag     = flag.String("mode", "", "Deprecated: use the set command")
+	offFlag      = flag.Bool("off", false, "Deprecated: use the set command")
+	onFlag       = flag.Bool("on", false, "Deprecated: use the set command")
+	parallelFlag = flag.Int("parallel", cloudcontrol.DefaultParallelism, "Deprecated: use the -parallel flag of a command")
+	queryFlag    = flag.String("query", "", "Deprecated: use the query command")
+	statusFlag   = flag.Bool("status", false, "Deprecated: use the status command")
+	syncFlag     = flag.Bool("sync", false, "Deprecated: use the sync command")
+	tariffFlag   = flag.String("tariff", "", "Deprecated: use the cost command")
+	tempFlag     = flag.Float64("temp", 0, "Deprecated: use the set command")
+	toFlag       = flag.String("to", "", "Deprecated: use the query or cost command")
+)
+
+// legacyCommands runs the deprecated control flags on all given
+// devices, one command after the other.
+func legacyCommands(client *cloudcontrol.Client, devices []string) error {
+	commands := []func(*cloudcontrol.Client) ([]byte, error){}
+	if *onFlag {
+		log.Infoln("Turning device(s) on.....")
+		commands = append(commands, (*cloudcontrol.Client).TurnOn)
+	}
+	if *offFlag {
+		log.Infoln("Turning device(s) off.....")
+		commands = append(commands, (*cloudcontrol.Client).TurnOff)
+	}
+	if *tempFlag != 0 {
+		log.Infof("Setting temperature to %v degrees Celsius", *tempFlag)
+		commands = append(commands, func(c *cloudcontrol.Client) ([]byte, error) {
+			return c.SetTemperature(*tempFlag)
+		})
+	}
+	if *modeFlag != "" {
+		log.Infof("Setting mode to %s", *modeFlag)
+		commands = append(commands, func(c *cloudcontrol.Client) ([]byte, error) {
+			return c.SetMode(pt.Modes[*modeFlag])
+		})
+	}
+	for _, command := range commands {
+		if err := checkResults(client.Each(devices, *parallelFlag, command)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runLegacy runs the deprecated flat flag interface.
+func runLegacy() {
+	flag.Visit(func(f *flag.Flag) {
+		if !globalFlags[f.Name] {
+			log.Warnf("Flag -%s is deprecated, run '%s help' for the new commands", f.Name, os.Args[0])
+		}
+	})
+
+	readConfig()
+	client, err := newClient()
+	if err != nil {
+		fatal(err)
+	}
+
+	if *listFlag {
+		if err := listDevices(&client, &output{format: *formatFlag}); err != nil {
+			fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if *syncFlag {
+		if err := syncArchive(&client, *archiveFlag, *backfillFlag, *intervalFlag); err != nil {
+			fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if *costFlag != "" {
+		if err := oneOf("cost", *costFlag, "day", "month"); err != nil {
+			fatal(withCode(exitValidation, err))
+		}
+		from, to, err := dateRange(*fromFlag, *toFlag)
+		if err != nil {
+			fatal(err)
+		}
+		if err := costReport(&client, *tariffFlag, *costFlag, from, to); err != nil {
+			fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if *groupFlag != "" {
+		t := target{group: *groupFlag}
+		devices, err := t.resolve(&client)
+		if err != nil {
+			fatal(err)
+		}
+		if *statusFlag {
+			if err := groupStatus(&client, devices, *parallelFlag, &output{format: "table"}); err != nil {
+				fatal(err)
+			}
+		}
+		if err := legacyCommands(&client, devices); err != nil {
+			fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	t := target{device: *deviceFlag}
+	if _, err := t.resolve(&client); err != nil {
+		fatal(err)
+	}
+
+	if *statusFlag {
+		log.Infoln("Fetching status.....")
+		status, err := client.GetDeviceStatus()
+		if err != nil {
+			fatal(err)
+		}
+		printStatus(status)
+	}
+
+	if *historyFlag != "" {
+		if err := deviceHistory(&client, *historyFlag, &output{format: "csv"}); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *queryFlag != "" {
+		agg, ok := archive.Aggregations[*queryFlag]
+		if !ok {
+			fatal(withCode(exitValidation, fmt.Errorf("error: unknown aggregation %s", *queryFlag)))
+		}
+		from, to, err := dateRange(*fromFlag, *toFlag)
+		if err != nil {
+			fatal(err)
+		}
+		if err := queryArchive(&client, *archiveFlag, agg, from, to); err != nil {
+			fatal(err)
+		}
+	}
+
+	if err := legacyCommands(&client, []string{client.DeviceGUID}); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func mqttCommand() *command {
+	fs := flag.NewFlagSet("mqtt", flag.ExitOnError)
+	broker := fs.String("broker", "", "MQTT broker URL (e.g. tcp://localhost:1883), defaults to mqtt.broker from the config")
+	clientID := fs.String("client-id", "gopanasonic", "MQTT client id")
+	prefix := fs.String("prefix", bridge.DefaultPrefix, "Topic prefix for device state and commands")
+	discoveryPrefix := fs.String("discovery-prefix", bridge.DefaultDiscoveryPrefix, "Home Assistant discovery topic prefix")
+	interval := fs.Duration("interval", bridge.DefaultInterval, "Device status poll interval")
+	return &command{
+		name:  "mqtt",
+		help:  "Bridge devices to MQTT with Home Assistant discovery",
+		flags: fs,
+		validate: func() error {
+			if *interval <= 0 {
+				return fmt.Errorf("error: -interval must be positive")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			url := *broker
+			if url == "" {
+				url = viper.GetString("mqtt.broker")
+			}
+			if url == "" {
+				return withCode(exitValidation, fmt.Errorf("error: no MQTT broker given, use -broker or mqtt.broker in the config"))
+			}
+			return runBridge(client, url, *clientID, *prefix, *discoveryPrefix, *interval)
+		},
+	}
+}
+
+// runBridge connects to the broker and runs the bridge until interrupted.
+func runBridge(client *cloudcontrol.Client, url, clientID, prefix, discoveryPrefix string, interval time.Duration) error {
+	mqtt, err := bridge.Connect(url, clientID, viper.GetString("mqtt.username"), viper.GetString("mqtt.password"))
+	if err != nil {
+		return withCode(exitNetwork, err)
+	}
+	defer mqtt.Close()
+
+	b := bridge.New(client, mqtt)
+	b.Prefix = prefix
+	b.DiscoveryPrefix = discoveryPrefix
+	b.Interval = interval
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Infof("Bridging devices to %s", url)
+	if err := b.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
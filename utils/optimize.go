@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/schedule"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/spot"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func optimizeCommand() *command {
+	fs := flag.NewFlagSet("optimize", flag.ExitOnError)
+	prices := fs.String("prices", "", "Price URL or file, overrides optimizer.prices in the config")
+	apply := fs.Bool("apply", false, "Apply the plan to the devices until it ends")
+	return &command{
+		name:  "optimize",
+		help:  "Plan heating and cooling on hourly spot prices",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			config := spot.Config{}
+			if err := viper.UnmarshalKey("optimizer", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid optimizer in config: %w", err))
+			}
+			if *prices != "" {
+				config.Prices = *prices
+			}
+			if config.Prices == "" {
+				return withCode(exitValidation, fmt.Errorf("error: no prices, set optimizer.prices in config or use -prices"))
+			}
+			o, err := spot.New(client, config)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+			p, err := spot.Load(context.Background(), config.Prices)
+			if err != nil {
+				return withCode(exitNetwork, err)
+			}
+
+			now := time.Now()
+			plan := o.Plan(p, now)
+			if len(plan.Steps) == 0 {
+				return fmt.Errorf("error: no prices from %s on", now.Format("2006-01-02 15:04"))
+			}
+			if err := printPlan(plan); err != nil {
+				return err
+			}
+			savings, err := o.Savings(plan, p, now.AddDate(0, 0, -1))
+			if err != nil {
+				return err
+			}
+			if err := printSavings(savings); err != nil {
+				return err
+			}
+			if !*apply {
+				return nil
+			}
+			return applyPlan(client, plan, now)
+		},
+	}
+}
+
+// applyPlan applies the current steps of the plan and schedules the
+// later steps until the plan ends.
+func applyPlan(client *cloudcontrol.Client, plan spot.Plan, now time.Time) error {
+	s, err := schedule.New(client, schedule.Config{Rules: plan.Rules()})
+	if err != nil {
+		return err
+	}
+	end := now
+	for _, step := range plan.Steps {
+		if step.End.After(end) {
+			end = step.End
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for _, step := range plan.Steps {
+		if !step.Start.After(now) && now.Before(step.End) {
+			if err := s.Apply(ctx, step.Name()); err != nil {
+				log.Errorf("%s: %v", step.Name(), err)
+			}
+		}
+	}
+	ctx, cancel := context.WithDeadline(ctx, end)
+	defer cancel()
+	log.Infof("Applying the plan until %s", end.Format("2006-01-02 15:04"))
+	s.Run(ctx)
+	return nil
+}
+
+// printPlan prints the steps of a plan.
+func printPlan(plan spot.Plan) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tFROM\tUNTIL\tREASON\tMODE\tTEMPERATURE\tPRICE")
+	for _, s := range plan.Steps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.1f\t%.4f\n", s.DeviceName, s.Start.Format("Mon 15:04"), s.End.Format("Mon 15:04"), s.Reason, s.Mode, s.Temperature, s.Price)
+	}
+	return w.Flush()
+}
+
+// printSavings prints the estimated savings of every device.
+func printSavings(savings []spot.Saving) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nDEVICE\tKWH\tBASELINE\tOPTIMIZED\tSAVING")
+	for _, s := range savings {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t%.2f\n", s.DeviceName, s.Consumption, s.Baseline, s.Optimized, s.Baseline-s.Optimized)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/archive"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/tariff"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	"gopkg.in/yaml.v2"
+)
+
+// output holds the -output and -template flags of a command.
+type output struct {
+	format   string
+	template string
+	tmpl     *template.Template
+}
+
+// register adds the output flags to a flag set.
+func (o *output) register(fs *flag.FlagSet, def string, formats ...string) {
+	fs.StringVar(&o.format, "output", def, "Output format: "+strings.Join(formats, ","))
+	fs.StringVar(&o.template, "template", "", "Go text/template used with -output template")
+}
+
+// validate checks the output flags and parses the template.
+func (o *output) validate(formats ...string) error {
+	if err := oneOf("output", o.format, formats...); err != nil {
+		return err
+	}
+	if o.format != "template" {
+		if o.template != "" {
+			return fmt.Errorf("error: -template can only be used with -output template")
+		}
+		return nil
+	}
+	if o.template == "" {
+		return fmt.Errorf("error: -output template requires -template")
+	}
+	tmpl, err := template.New("output").Parse(o.template)
+	if err != nil {
+		return fmt.Errorf("error: invalid template: %v", err)
+	}
+	o.tmpl = tmpl
+
+	return nil
+}
+
+// write renders v in the selected format. The table function is used
+// for the table and csv formats. Templates are executed for every
+// element when v is a slice.
+func (o *output) write(v interface{}, table func(w io.Writer) error) error {
+	switch o.format {
+	case "json":
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+	case "template":
+		items := []interface{}{v}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			items = items[:0]
+			for i := 0; i < rv.Len(); i++ {
+				items = append(items, rv.Index(i).Interface())
+			}
+		}
+		for _, item := range items {
+			if err := o.tmpl.Execute(os.Stdout, item); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+	default:
+		return table(os.Stdout)
+	}
+
+	return nil
+}
+
+// toYAML converts v to YAML using its JSON field names.
+func toYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(generic)
+}
+
+// flatten returns all fields of v as dotted JSON names and values.
+func flatten(v interface{}) ([][2]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	fields := [][2]string{}
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		keys := []string{}
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if nested, ok := m[k].(map[string]interface{}); ok {
+				walk(prefix+k+".", nested)
+				continue
+			}
+			fields = append(fields, [2]string{prefix + k, fmt.Sprint(m[k])})
+		}
+	}
+	walk("", generic)
+
+	return fields, nil
+}
+
+// deviceRow is a single line of the device list.
+type deviceRow struct {
+	Group             string  `json:"group"`
+	Name              string  `json:"name"`
+	GUID              string  `json:"guid"`
+	Model             string  `json:"model"`
+	Online            bool    `json:"online"`
+	Power             string  `json:"power"`
+	Mode              string  `json:"mode"`
+	TemperatureSet    float64 `json:"temperatureSet"`
+	InsideTemperature float64 `json:"insideTemperature"`
+	SummerHouse       int     `json:"summerHouse"`
+}
+
+// newDeviceRow converts a device from the groups response to a row.
+func newDeviceRow(d cloudcontrol.GroupDevice) deviceRow {
+	return deviceRow{
+		Group:             d.GroupName,
+		Name:              d.DeviceName,
+		GUID:              d.DeviceGUID,
+		Model:             d.DeviceModuleNumber,
+		Online:            d.Parameters.Online,
+		Power:             pt.Operate[d.Parameters.Operate],
+		Mode:              pt.ModesReverse[d.Parameters.OperationMode],
+		TemperatureSet:    d.Parameters.TemperatureSet,
+		InsideTemperature: d.Parameters.InsideTemperature,
+		SummerHouse:       d.SummerHouse,
+	}
+}
+
+// printDevices writes the device list.
+func printDevices(devices []cloudcontrol.GroupDevice, o *output) error {
+	rows := []deviceRow{}
+	for _, d := range devices {
+		rows = append(rows, newDeviceRow(d))
+	}
+
+	return o.write(rows, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tNAME\tGUID\tMODEL\tONLINE\tPOWER\tMODE\tSET\tINSIDE\tSUMMER HOUSE")
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%0.1f\t%0.1f\t%d\n", r.Group, r.Name, r.GUID, r.Model, r.Online, r.Power, r.Mode, r.TemperatureSet, r.InsideTemperature, r.SummerHouse)
+		}
+		return w.Flush()
+	})
+}
+
+// writeStatus writes all fields of a device status.
+func writeStatus(status pt.Device, o *output) error {
+	return o.write(status, func(out io.Writer) error {
+		fields, err := flatten(status)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tVALUE")
+		for _, f := range fields {
+			fmt.Fprintf(w, "%s\t%s\n", f[0], f[1])
+		}
+		return w.Flush()
+	})
+}
+
+// writeStatuses writes the status of several devices.
+func writeStatuses(statuses []pt.Device, o *output) error {
+	return o.write(statuses, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "GUID\tNAME\tONLINE\tPOWER\tMODE\tSET\tINSIDE\tOUTSIDE")
+		for _, s := range statuses {
+			p := s.Parameters
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%0.1f\t%0.1f\t%0.1f\n", s.DeviceGUID, s.DeviceName, p.Online, pt.Operate[p.Operate], pt.ModesReverse[p.OperationMode], p.TemperatureSet, p.InsideTemperature, p.OutsideTemperature)
+		}
+		return w.Flush()
+	})
+}
+
+// writeHistory writes history entries. The table format uses aligned
+// columns and the csv format matches the output of earlier releases.
+func writeHistory(history pt.History, o *output) error {
+	if o.format == "template" {
+		return o.write(history.HistoryEntries, nil)
+	}
+
+	return o.write(history, func(out io.Writer) error {
+		if o.format == "csv" {
+			printHistory(history)
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tCONSUMPTION\tCOST\tSETTING\tINSIDE\tOUTSIDE")
+		for _, v := range history.HistoryEntries {
+			fmt.Fprintf(w, "%d\t%v\t%v\t%v\t%v\t%v\n", v.DataNumber+1, v.Consumption, v.Cost, v.AverageSettingTemp, v.AverageInsideTemp, v.AverageOutsideTemp)
+		}
+		return w.Flush()
+	})
+}
+
+// printStatus prints the capabilities and current status of a device.
+func printStatus(status pt.Device) {
+	fmt.Printf("GUID: %s\n", status.DeviceGUID)
+	fmt.Println("Capabilities:")
+	fmt.Printf("Auto mode: %t\n", status.AutoMode)
+	fmt.Printf("Heat mode: %t\n", status.HeatMode)
+	fmt.Printf("Dry mode: %t\n", status.DryMode)
+	fmt.Printf("Cool mode: %t\n", status.CoolMode)
+	fmt.Printf("Fan mode: %t\n", status.FanMode)
+	fmt.Printf("Fan Speed mode: %d\n", status.FanSpeedMode)
+	fmt.Printf("Quiet mode: %t\n", status.QuietMode)
+	fmt.Printf("Eco function: %d\n", status.EcoFunction)
+	fmt.Printf("EcoNavi function: %t\n", status.EcoNavi)
+	fmt.Printf("iAutoX: %t\n", status.IautoX)
+	fmt.Printf("NanoeX: %t\n", status.Nanoe)
+	fmt.Println("Current status:")
+	fmt.Printf("Status: %s\n", pt.Operate[status.Parameters.Operate])
+	fmt.Printf("Online: %t\n", status.Parameters.Online)
+	fmt.Printf("Temperature: %0.1f\n", status.Parameters.TemperatureSet)
+	fmt.Printf("Mode: %s\n", pt.ModesReverse[status.Parameters.OperationMode])
+}
+
+// printHistory prints history entries as CSV.
+func printHistory(history pt.History) {
+	fmt.Println("#,AverageSettingTemp,AverageInsideTemp,AverageOutsideTemp")
+	for _, v := range history.HistoryEntries {
+		fmt.Printf("%v,%v,%v,%v\n", v.DataNumber+1, v.AverageSettingTemp, v.AverageInsideTemp, v.AverageOutsideTemp)
+	}
+}
+
+// printPoints prints aggregated archive data as CSV.
+func printPoints(points []archive.Point) {
+	fmt.Println("Start,Consumption,Cost,AverageSettingTemp,AverageInsideTemp,AverageOutsideTemp")
+	for _, p := range points {
+		fmt.Printf("%s,%v,%v,%0.2f,%0.2f,%0.2f\n", p.Start.Format("2006-01-02 15:04"), p.Consumption, p.Cost, p.AverageSettingTemp, p.AverageInsideTemp, p.AverageOutsideTemp)
+	}
+}
+
+// printCosts prints the per day or per month totals of a cost report.
+func printCosts(name string, period string, report tariff.Report) {
+	totals := report.Days
+	if period == "month" {
+		totals = report.Months
+	}
+	for _, t := range totals {
+		fmt.Printf("%s,%s,%0.2f,%0.2f,%s\n", name, t.Start.Format("2006-01-02"), t.Consumption, t.Cost, report.Currency)
+	}
+}
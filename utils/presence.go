@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/presence"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func presenceCommand() *command {
+	fs := flag.NewFlagSet("presence", flag.ExitOnError)
+	listen := fs.String("listen", ":9102", "Address to serve the webhook detectors on")
+	once := fs.Bool("once", false, "Print the detector readings and the presence once and exit without switching devices")
+	return &command{
+		name:  "presence",
+		help:  "Switch devices between comfort and eco states when somebody is home or away",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			config := presence.Config{}
+			if err := viper.UnmarshalKey("presence", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid presence in config: %w", err))
+			}
+			p, err := presence.New(client, config)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+
+			if *once {
+				state, forced, readings := p.Detect(context.Background())
+				printPresence(state, forced, readings)
+				return nil
+			}
+
+			log.Infof("Checking presence every %v", p.Interval)
+			if p.Webhooks() {
+				log.Infof("Serving presence webhooks on %s/presence/<detector>", *listen)
+				return serve(*listen, p.Handler(), func(ctx context.Context) error {
+					p.Run(ctx)
+					return nil
+				})
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			p.Run(ctx)
+			return nil
+		},
+	}
+}
+
+func printPresence(state presence.State, forced bool, readings []presence.Reading) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DETECTOR\tSTATE\tERROR")
+	for _, r := range readings {
+		s, e := string(r.State), ""
+		if s == "" {
+			s = "unknown"
+		}
+		if r.Err != nil {
+			e = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Detector, s, e)
+	}
+	w.Flush()
+	switch {
+	case state == presence.Unknown:
+		fmt.Println("Presence is unknown, devices are left alone")
+	case forced:
+		fmt.Printf("Presence is %s (override)\n", state)
+	default:
+		fmt.Printf("Presence is %s\n", state)
+	}
+}
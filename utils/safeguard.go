@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/alert"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/safeguard"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func safeguardCommand() *command {
+	fs := flag.NewFlagSet("safeguard", flag.ExitOnError)
+	once := fs.Bool("once", false, "Check every device once, print the result and exit")
+	return &command{
+		name:  "safeguard",
+		help:  "Keep unattended devices frost free and alert when they are offline",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			config := safeguard.Config{}
+			if err := viper.UnmarshalKey("safeguard", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid safeguard in config: %w", err))
+			}
+			if len(config.Guards) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no safeguard.guards in config"))
+			}
+			// Alerts go to the sinks of the alert command
+			var sinkConfigs []alert.SinkConfig
+			if err := viper.UnmarshalKey("alerts.sinks", &sinkConfigs); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid alerts.sinks in config: %w", err))
+			}
+			sinks := map[string]alert.Sink{}
+			for _, sc := range sinkConfigs {
+				sink, err := sc.Sink()
+				if err != nil {
+					return withCode(exitValidation, err)
+				}
+				sinks[sc.Name] = sink
+			}
+			if len(sinks) == 0 {
+				log.Warn("No alerts.sinks in config, safeguard alerts are only logged")
+			}
+
+			s, err := safeguard.New(client, config, sinks)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+			if *once {
+				return printSafeguard(s.Check(context.Background()))
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Running %d safeguard(s) every %v", len(config.Guards), s.Interval)
+			s.Run(ctx)
+			return nil
+		},
+	}
+}
+
+// printSafeguard prints the result of a check of every device.
+func printSafeguard(results []safeguard.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GUARD\tDEVICE\tONLINE\tINSIDE\tSUMMER HOUSE\tFORCED\tERROR")
+	failed := 0
+	for _, r := range results {
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%.1f\t%d\t%v\t%s\n", r.Guard, r.DeviceName, r.Online, r.Inside, r.SummerHouse, r.Forced, errText)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("error: %d of %d check(s) failed", failed, len(results))
+	}
+	return nil
+}
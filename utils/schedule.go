@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/schedule"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func scheduleCommand() *command {
+	fs := flag.NewFlagSet("schedule", flag.ExitOnError)
+	list := fs.Bool("list", false, "List the next time every rule runs within a week and exit")
+	apply := fs.String("apply", "", "Apply the rule with this name now and exit")
+	return &command{
+		name:  "schedule",
+		help:  "Run the schedule rules from the config",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			config := schedule.Config{}
+			if err := viper.UnmarshalKey("schedule", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid schedule in config: %w", err))
+			}
+			if len(config.Rules) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no schedule.rules in config"))
+			}
+			s, err := schedule.New(client, config)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+
+			switch {
+			case *list:
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "RULE\tNEXT")
+				for _, a := range s.Next(time.Now(), 7*24*time.Hour) {
+					fmt.Fprintf(w, "%s\t%s\n", a.Rule, a.Time.Format("Mon 2006-01-02 15:04"))
+				}
+				return w.Flush()
+			case *apply != "":
+				return s.Apply(context.Background(), *apply)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Running %d schedule rule(s)", len(config.Rules))
+			s.Run(ctx)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/api"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cache"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/exporter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func serveCommand() *command {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", ":9100", "Address to listen on")
+	interval := fs.Duration("interval", exporter.DefaultInterval, "Device status poll interval")
+	historyInterval := fs.Duration("history-interval", exporter.DefaultHistoryInterval, "Energy history poll interval")
+	withAPI := fs.Bool("api", false, "Serve the REST API on "+api.Prefix+" using the API keys in the config")
+	return &command{
+		name:  "serve",
+		help:  "Serve Prometheus metrics on /metrics and optionally a REST API",
+		flags: fs,
+		validate: func() error {
+			if *interval <= 0 || *historyInterval <= 0 {
+				return fmt.Errorf("error: -interval and -history-interval must be positive")
+			}
+			return nil
+		},
+		run: func(client *cloudcontrol.Client) error {
+			e := exporter.New(client)
+			e.Interval = *interval
+			e.HistoryInterval = *historyInterval
+
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", e)
+			pollers := []func(ctx context.Context) error{e.Run}
+			if *withAPI {
+				// The API serves from the cache polled by the exporter
+				server, err := newAPIServer(client, e.Cache)
+				if err != nil {
+					return err
+				}
+				mux.Handle(api.Prefix+"/", server)
+			}
+			return serve(*listen, mux, pollers...)
+		},
+	}
+}
+
+// newAPIServer creates the API server with the keys from the config.
+func newAPIServer(client *cloudcontrol.Client, statuses *cache.Cache) (*api.Server, error) {
+	keys := []api.Key{}
+	if err := viper.UnmarshalKey("api.keys", &keys); err != nil {
+		return nil, withCode(exitValidation, fmt.Errorf("error: invalid api.keys in config: %w", err))
+	}
+	if len(keys) == 0 {
+		return nil, withCode(exitValidation, fmt.Errorf("error: no api.keys in config, the API would refuse all requests"))
+	}
+	server, err := api.New(client, statuses, keys)
+	if err != nil {
+		return nil, withCode(exitValidation, err)
+	}
+	log.Infof("Serving API with %d key(s)", len(keys))
+
+	return server, nil
+}
+
+// serve runs an HTTP server and background pollers until interrupted.
+func serve(listen string, handler http.Handler, pollers ...func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, poll := range pollers {
+		go func(poll func(ctx context.Context) error) {
+			if err := poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Errorf("Polling stopped: %v", err)
+			}
+		}(poll)
+	}
+
+	server := &http.Server{Addr: listen, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	log.Infof("Listening on %s", listen)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
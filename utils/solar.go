@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/solar"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/thermostat"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func solarCommand() *command {
+	fs := flag.NewFlagSet("solar", flag.ExitOnError)
+	clientID := fs.String("client-id", "gopanasonic-solar", "MQTT client id for an mqtt source")
+	once := fs.Bool("once", false, "Read the export, change the devices once and exit")
+	return &command{
+		name:  "solar",
+		help:  "Run devices on surplus solar power",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			config := solar.Config{}
+			if err := viper.UnmarshalKey("solar", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid solar in config: %w", err))
+			}
+			if len(config.Devices) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no solar.devices in config"))
+			}
+
+			var broker bridge.Broker
+			if config.Source.Type == thermostat.MQTT {
+				url := viper.GetString("mqtt.broker")
+				if url == "" {
+					return withCode(exitValidation, fmt.Errorf("error: mqtt sources need mqtt.broker in the config"))
+				}
+				paho, err := bridge.Connect(url, *clientID, viper.GetString("mqtt.username"), viper.GetString("mqtt.password"))
+				if err != nil {
+					return withCode(exitNetwork, err)
+				}
+				defer paho.Close()
+				broker = paho
+			}
+			source, err := config.Source.Source(broker)
+			if err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: solar source: %w", err))
+			}
+			c, err := solar.New(client, config, source)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+
+			if *once {
+				results, err := c.Step(context.Background())
+				if err != nil {
+					log.Errorf("Reading export: %v", err)
+				}
+				return printSolar(results)
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Running %d device(s) on solar surplus", len(config.Devices))
+			c.Run(ctx)
+			return nil
+		},
+	}
+}
+
+// printSolar prints the state of every device after a step.
+func printSolar(results []solar.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tSTATE\tCHANGED\tERROR")
+	failed := 0
+	for _, r := range results {
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", r.DeviceName, r.State, r.Changed, errText)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("error: %d of %d device(s) failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/bridge"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/thermostat"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func thermostatCommand() *command {
+	fs := flag.NewFlagSet("thermostat", flag.ExitOnError)
+	clientID := fs.String("client-id", "gopanasonic-thermostat", "MQTT client id for mqtt sources")
+	once := fs.Bool("once", false, "Adjust every device once and exit")
+	return &command{
+		name:  "thermostat",
+		help:  "Control devices with external temperature sensors",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			var configs []thermostat.Config
+			if err := viper.UnmarshalKey("thermostats", &configs); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid thermostats in config: %w", err))
+			}
+			if len(configs) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no thermostats in config"))
+			}
+
+			var broker *bridge.Paho
+			for _, config := range configs {
+				if config.Source.Type != thermostat.MQTT || broker != nil {
+					continue
+				}
+				url := viper.GetString("mqtt.broker")
+				if url == "" {
+					return withCode(exitValidation, fmt.Errorf("error: mqtt sources need mqtt.broker in the config"))
+				}
+				var err error
+				if broker, err = bridge.Connect(url, *clientID, viper.GetString("mqtt.username"), viper.GetString("mqtt.password")); err != nil {
+					return withCode(exitNetwork, err)
+				}
+				defer broker.Close()
+			}
+
+			controllers := []*thermostat.Controller{}
+			for _, config := range configs {
+				var b bridge.Broker
+				if broker != nil {
+					b = broker
+				}
+				source, err := config.Source.Source(b)
+				if err != nil {
+					return withCode(exitValidation, fmt.Errorf("error: thermostat %q: %w", config.Name, err))
+				}
+				c, err := thermostat.New(client, config, source)
+				if err != nil {
+					return withCode(exitValidation, err)
+				}
+				controllers = append(controllers, c)
+			}
+
+			if *once {
+				failed := 0
+				for _, c := range controllers {
+					if err := c.Step(context.Background()); err != nil {
+						log.Errorf("%s: %v", c.Name, err)
+						failed++
+					}
+				}
+				if failed > 0 {
+					return fmt.Errorf("error: %d of %d thermostat(s) failed", failed, len(controllers))
+				}
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Running %d thermostat(s)", len(controllers))
+			var wg sync.WaitGroup
+			for _, c := range controllers {
+				wg.Add(1)
+				go func(c *thermostat.Controller) {
+					defer wg.Done()
+					c.Run(ctx)
+				}(c)
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+}
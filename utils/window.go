@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/window"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func windowCommand() *command {
+	fs := flag.NewFlagSet("window", flag.ExitOnError)
+	pause := fs.Duration("pause", 0, "Time to turn devices off after an open window, overrides window.pause in the config, 0 only flags")
+	return &command{
+		name:  "window",
+		help:  "Detect open windows from inside temperature drops while heating",
+		flags: fs,
+		run: func(client *cloudcontrol.Client) error {
+			config := window.Config{}
+			if err := viper.UnmarshalKey("window", &config); err != nil {
+				return withCode(exitValidation, fmt.Errorf("error: invalid window in config: %w", err))
+			}
+			if len(config.Devices) == 0 {
+				return withCode(exitValidation, fmt.Errorf("error: no window.devices in config"))
+			}
+			fs.Visit(func(f *flag.Flag) {
+				if f.Name == "pause" {
+					config.Pause = *pause
+				}
+			})
+			m, err := window.New(client, config)
+			if err != nil {
+				return withCode(exitValidation, err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			log.Infof("Watching for open windows every %v, drop of %v°C within %v", m.Interval, m.Drop, m.Window)
+			m.Run(ctx)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,200 @@
+// Package watch polls Panasonic Comfort Cloud devices and
+// emits events when their state changes.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// EventType is the kind of state change.
+type EventType string
+
+// Event types
+const (
+	Power              EventType = "power"
+	Mode               EventType = "mode"
+	Setpoint           EventType = "setpoint"
+	InsideTemperature  EventType = "insideTemperature"
+	OutsideTemperature EventType = "outsideTemperature"
+	ErrorState         EventType = "error"
+	Online             EventType = "online"
+)
+
+// Defaults used by New
+const (
+	DefaultInterval   = time.Minute
+	DefaultMaxBackoff = 15 * time.Minute
+)
+
+// Event is a change in device state.
+type Event struct {
+	Time       time.Time   `json:"time"`
+	DeviceGUID string      `json:"deviceGuid"`
+	DeviceName string      `json:"deviceName"`
+	Type       EventType   `json:"type"`
+	Old        interface{} `json:"old"`
+	New        interface{} `json:"new"`
+	Status     pt.Device   `json:"-"`
+}
+
+func (e Event) String() string {
+	name := e.DeviceName
+	if name == "" {
+		name = e.DeviceGUID
+	}
+	return fmt.Sprintf("%s %s %s: %v -> %v", e.Time.Format(time.RFC3339), name, e.Type, e.Old, e.New)
+}
+
+// errorState returns the error code string of a device, or
+// an empty string when the device reports no error.
+func errorState(p pt.DeviceParameters) string {
+	if !p.ErrorStatusFlg {
+		return ""
+	}
+	if p.ErrorCodeStr != "" {
+		return p.ErrorCodeStr
+	}
+	return fmt.Sprint(p.ErrorCode)
+}
+
+// Diff returns the events describing the change from old to new.
+func Diff(old, new pt.Device, at time.Time) []Event {
+	o, n := old.Parameters, new.Parameters
+	events := []Event{}
+	add := func(t EventType, before, after interface{}) {
+		events = append(events, Event{
+			Time:       at,
+			DeviceGUID: new.DeviceGUID,
+			DeviceName: new.DeviceName,
+			Type:       t,
+			Old:        before,
+			New:        after,
+			Status:     new,
+		})
+	}
+
+	if o.Online != n.Online {
+		add(Online, o.Online, n.Online)
+	}
+	if o.Operate != n.Operate {
+		add(Power, pt.Operate[o.Operate], pt.Operate[n.Operate])
+	}
+	if o.OperationMode != n.OperationMode {
+		add(Mode, pt.ModesReverse[o.OperationMode], pt.ModesReverse[n.OperationMode])
+	}
+	if o.TemperatureSet != n.TemperatureSet {
+		add(Setpoint, o.TemperatureSet, n.TemperatureSet)
+	}
+	if o.InsideTemperature != n.InsideTemperature {
+		add(InsideTemperature, o.InsideTemperature, n.InsideTemperature)
+	}
+	if o.OutsideTemperature != n.OutsideTemperature {
+		add(OutsideTemperature, o.OutsideTemperature, n.OutsideTemperature)
+	}
+	if errorState(o) != errorState(n) {
+		add(ErrorState, errorState(o), errorState(n))
+	}
+
+	return events
+}
+
+// Watcher polls devices and emits change events.
+type Watcher struct {
+	Client     *cloudcontrol.Client
+	Devices    []string
+	Interval   time.Duration
+	MaxBackoff time.Duration // Maximum poll interval after errors
+	// OnError is called when polling a device fails, errors
+	// are logged when it is nil.
+	OnError func(deviceGUID string, err error)
+	// OnStatus is called with every successfully polled status.
+	OnStatus func(status pt.Device)
+}
+
+// New creates a Watcher for the given devices using the default
+// interval and backoff.
+func New(client *cloudcontrol.Client, devices []string) *Watcher {
+	return &Watcher{
+		Client:     client,
+		Devices:    devices,
+		Interval:   DefaultInterval,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// Watch starts polling all devices and returns the channel on which
+// events are delivered. The first poll of a device records its state
+// without emitting events. The channel is closed after the context
+// is cancelled.
+func (w *Watcher) Watch(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+
+	var wg sync.WaitGroup
+	for _, device := range w.Devices {
+		wg.Add(1)
+		go func(device string) {
+			defer wg.Done()
+			w.poll(ctx, device, events)
+		}(device)
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	return events
+}
+
+// poll watches a single device until the context is cancelled.
+func (w *Watcher) poll(ctx context.Context, device string, events chan<- Event) {
+	client := *w.Client
+	client.SetDevice(device)
+
+	var last *pt.Device
+	delay := w.Interval
+	for {
+		status, err := client.GetDeviceStatus()
+		if err != nil {
+			if w.OnError != nil {
+				w.OnError(device, err)
+			} else {
+				log.Errorf("Polling %s failed: %v", device, err)
+			}
+			delay *= 2
+			if delay > w.MaxBackoff {
+				delay = w.MaxBackoff
+			}
+		} else {
+			delay = w.Interval
+			if status.DeviceGUID == "" {
+				status.DeviceGUID = device
+			}
+			if w.OnStatus != nil {
+				w.OnStatus(status)
+			}
+			if last != nil {
+				for _, e := range Diff(*last, status, time.Now()) {
+					select {
+					case events <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			last = &status
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package watch_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/watch"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+func TestDiff(t *testing.T) {
+	old := pt.Device{DeviceGUID: "device1"}
+	old.Parameters = pt.DeviceParameters{Online: true, Operate: 0, OperationMode: 3, TemperatureSet: 20, InsideTemperature: 19}
+	new := old
+	new.Parameters.Operate = 1
+	new.Parameters.TemperatureSet = 21.5
+	new.Parameters.ErrorStatusFlg = true
+	new.Parameters.ErrorCodeStr = "H11"
+
+	at := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := []watch.Event{
+		{Time: at, DeviceGUID: "device1", Type: watch.Power, Old: "Off", New: "On"},
+		{Time: at, DeviceGUID: "device1", Type: watch.Setpoint, Old: 20.0, New: 21.5},
+		{Time: at, DeviceGUID: "device1", Type: watch.ErrorState, Old: "", New: "H11"},
+	}
+	got := watch.Diff(old, new, at)
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(watch.Event{}, "Status")); diff != "" {
+		t.Errorf("TestDiff() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := watch.Diff(old, old, at); len(got) != 0 {
+		t.Errorf("TestDiff() want no events for equal state, got %v", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	temperature := 19.0
+	polls := 0
+	handler := http.NewServeMux()
+	handler.HandleFunc(pt.URLDeviceStatus, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		// Fail once to exercise the backoff
+		if polls == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"deviceGuid":"device1","parameters":{"online":true,"insideTemperature":%v}}`, temperature)
+		temperature++
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := cloudcontrol.NewClient(server.URL)
+	w := watch.New(&client, []string{"device1"})
+	w.Interval = 5 * time.Millisecond
+	w.MaxBackoff = 20 * time.Millisecond
+	errors := 0
+	w.OnError = func(string, error) { errors++ }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := w.Watch(ctx)
+
+	e := <-events
+	want := watch.Event{DeviceGUID: "device1", Type: watch.InsideTemperature, Old: 19.0, New: 20.0}
+	if diff := cmp.Diff(want, e, cmpopts.IgnoreFields(watch.Event{}, "Time", "Status")); diff != "" {
+		t.Errorf("TestWatch() mismatch (-want +got):\n%s", diff)
+	}
+	cancel()
+	for range events {
+	}
+	if errors != 1 {
+		t.Errorf("TestWatch() want 1 error, got %d", errors)
+	}
+}
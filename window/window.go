@@ -0,0 +1,301 @@
+// Package window detects open windows from the inside temperature of
+// heating Panasonic devices. A sudden drop of the temperature while
+// heating is flagged as an open window, and the device can be paused for
+// a while and restored to its prior state afterwards.
+package window
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hacktobeer/go-panasonic/cloudcontrol"
+	pt "github.com/hacktobeer/go-panasonic/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults used by New
+const (
+	DefaultDrop     = 1.5
+	DefaultWindow   = 10 * time.Minute
+	DefaultInterval = time.Minute
+)
+
+// Sample is an inside temperature at a time.
+type Sample struct {
+	Time   time.Time
+	Inside float64
+}
+
+// Analyzer flags a drop of the inside temperature of at least Drop °C
+// within Window.
+type Analyzer struct {
+	Drop    float64
+	Window  time.Duration
+	samples []Sample
+}
+
+// Add adds a sample and reports whether the temperature dropped by at
+// least Drop from the highest sample within Window. The samples are
+// cleared after a drop, so a drop is reported once.
+func (a *Analyzer) Add(s Sample) bool {
+	kept := a.samples[:0]
+	for _, old := range a.samples {
+		if s.Time.Sub(old.Time) <= a.Window {
+			kept = append(kept, old)
+		}
+	}
+	a.samples = append(kept, s)
+
+	highest := s.Inside
+	for _, old := range a.samples {
+		if old.Inside > highest {
+			highest = old.Inside
+		}
+	}
+	if highest-s.Inside >= a.Drop {
+		a.Reset()
+		return true
+	}
+	return false
+}
+
+// Reset clears the samples, eg when the device stops heating.
+func (a *Analyzer) Reset() {
+	a.samples = nil
+}
+
+// Config configures a Monitor.
+type Config struct {
+	// Devices are GUIDs, names, aliases or group names
+	Devices []string      `json:"devices"`
+	Drop    float64       `json:"drop"`
+	Window  time.Duration `json:"window"`
+	// Pause is how long a device with an open window is turned off, it
+	// is only flagged when 0
+	Pause    time.Duration `json:"pause"`
+	Interval time.Duration `json:"interval"`
+}
+
+// Result is the outcome of a check of a device.
+type Result struct {
+	DeviceGUID string
+	DeviceName string
+	Inside     float64
+	// Open is true when an open window was detected in the check,
+	// Paused while the device is paused and Restored when it was
+	// restored in the check
+	Open     bool
+	Paused   bool
+	Restored bool
+	Err      error
+}
+
+// device is the detection state of a device.
+type device struct {
+	analyzer Analyzer
+	until    time.Time // end of the pause
+	saved    pt.DeviceParameters
+}
+
+// Monitor detects open windows of devices.
+type Monitor struct {
+	Config
+	Now func() time.Time
+
+	client  *cloudcontrol.Client
+	guids   []string
+	mu      sync.Mutex
+	devices map[string]*device
+}
+
+// New creates a Monitor, the devices of the config are resolved once.
+func New(client *cloudcontrol.Client, config Config) (*Monitor, error) {
+	if config.Drop == 0 {
+		config.Drop = DefaultDrop
+	}
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Drop < 0 || config.Window < 0 || config.Pause < 0 || config.Interval < 0 {
+		return nil, fmt.Errorf("error: window drop, window, pause and interval must be positive")
+	}
+	if len(config.Devices) == 0 {
+		return nil, fmt.Errorf("error: no window devices")
+	}
+
+	m := &Monitor{Config: config, Now: time.Now, client: client, devices: map[string]*device{}}
+	for _, d := range config.Devices {
+		guids, err := client.ResolveDevices(d)
+		if err != nil {
+			return nil, fmt.Errorf("error: window: %w", err)
+		}
+		for _, guid := range guids {
+			if m.devices[guid] == nil {
+				m.devices[guid] = &device{analyzer: Analyzer{Drop: config.Drop, Window: config.Window}}
+				m.guids = append(m.guids, guid)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Check gets the status of all devices, flags open windows, pauses
+// devices and restores them when their pause is over. A device that was
+// turned on during its pause is left alone.
+func (m *Monitor) Check() []Result {
+	statuses := m.client.EachStatus(m.guids, cloudcontrol.DefaultParallelism)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.Now()
+	results := []Result{}
+	for _, status := range statuses {
+		d := m.devices[status.DeviceGUID]
+		p := status.Status.Parameters
+		result := Result{DeviceGUID: status.DeviceGUID, DeviceName: status.Status.DeviceName, Inside: p.InsideTemperature, Err: status.Err}
+		if result.Err == nil && !p.Online {
+			result.Err = cloudcontrol.ErrDeviceOffline
+		}
+		if result.Err != nil {
+			d.analyzer.Reset()
+			results = append(results, result)
+			continue
+		}
+
+		client := *m.client
+		client.SetDevice(status.DeviceGUID)
+		if !d.until.IsZero() {
+			if now.Before(d.until) {
+				result.Paused = true
+				results = append(results, result)
+				continue
+			}
+			if p.Operate == 0 {
+				if _, err := client.SetState(restore(d.saved)); err != nil {
+					result.Paused, result.Err = true, err
+					results = append(results, result)
+					continue
+				}
+				result.Restored = true
+				log.Infof("%s: pause over, restored heating at %.1f°C", result.DeviceName, d.saved.TemperatureSet)
+			}
+			d.until = time.Time{}
+		}
+
+		if p.Operate != 1 || p.OperationMode != pt.Modes["heat"] {
+			d.analyzer.Reset()
+			results = append(results, result)
+			continue
+		}
+		if !d.analyzer.Add(Sample{Time: now, Inside: p.InsideTemperature}) {
+			results = append(results, result)
+			continue
+		}
+
+		result.Open = true
+		log.Warnf("%s: inside temperature dropped to %.1f°C while heating, window open", result.DeviceName, p.InsideTemperature)
+		if m.Pause > 0 {
+			operate := 0
+			if _, err := client.SetState(pt.DeviceControlParameters{Operate: &operate}); err != nil {
+				result.Err = err
+				results = append(results, result)
+				continue
+			}
+			d.saved, d.until = p, now.Add(m.Pause)
+			result.Paused = true
+			log.Infof("%s: paused until %s", result.DeviceName, d.until.Format("15:04"))
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// restore returns the parameters turning a device on in its saved state,
+// including the air swing, eco mode and nanoe settings.
+func restore(saved pt.DeviceParameters) pt.DeviceControlParameters {
+	operate := 1
+	return pt.DeviceControlParameters{
+		Operate:        &operate,
+		OperationMode:  &saved.OperationMode,
+		TemperatureSet: &saved.TemperatureSet,
+		FanSpeed:       &saved.FanSpeed,
+		FanAutoMode:    &saved.FanAutoMode,
+		AirSwingUD:     &saved.AirSwingUD,
+		AirSwingLR:     &saved.AirSwingLR,
+		EcoMode:        &saved.EcoMode,
+		Nanoe:          &saved.Nanoe,
+	}
+}
+
+// Restore ends the pause of all paused devices and restores those that
+// are still off, as Check does when a pause is over. Devices that cannot
+// be reached stay paused.
+func (m *Monitor) Restore() []Result {
+	m.mu.Lock()
+	guids := []string{}
+	for _, guid := range m.guids {
+		if !m.devices[guid].until.IsZero() {
+			guids = append(guids, guid)
+		}
+	}
+	m.mu.Unlock()
+	if len(guids) == 0 {
+		return nil
+	}
+	statuses := m.client.EachStatus(guids, cloudcontrol.DefaultParallelism)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	results := []Result{}
+	for _, status := range statuses {
+		d := m.devices[status.DeviceGUID]
+		result := Result{DeviceGUID: status.DeviceGUID, DeviceName: status.Status.DeviceName, Paused: true, Err: status.Err}
+		if result.Err == nil && status.Status.Parameters.Operate == 0 {
+			client := *m.client
+			client.SetDevice(status.DeviceGUID)
+			if _, err := client.SetState(restore(d.saved)); err != nil {
+				result.Err = err
+			} else {
+				result.Restored = true
+				log.Infof("%s: stopping, restored heating at %.1f°C", result.DeviceName, d.saved.TemperatureSet)
+			}
+		}
+		if result.Err == nil {
+			result.Paused, d.until = false, time.Time{}
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Run checks the devices every Interval until the context is done, then
+// restores the paused devices so none is left off.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		for _, r := range m.Check() {
+			if r.Err != nil {
+				log.Warnf("%s: %v", r.DeviceGUID, r.Err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			for _, r := range m.Restore() {
+				if r.Err != nil {
+					log.Warnf("%s: restoring: %v", r.DeviceGUID, r.Err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
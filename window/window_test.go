@@ -0,0 +1,194 @@
+package window_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/cloudtest/testclient"
+	"github.com/hacktobeer/go-panasonic/cloudcontrol/window"
+	pt "github.com/hacktobeer/go-panasonic/types"
+)
+
+const living = "CS-Z25XKEW+4321"
+
+var start = time.Date(2021, 1, 4, 8, 0, 0, 0, time.Local)
+
+// detect returns the minutes at which a drop is reported for a series
+// of one sample every step minutes.
+func detect(minutes, step int, inside func(minute int) float64) []int {
+	a := window.Analyzer{Drop: window.DefaultDrop, Window: window.DefaultWindow}
+	got := []int{}
+	for m := 0; m < minutes; m += step {
+		if a.Add(window.Sample{Time: start.Add(time.Duration(m) * time.Minute), Inside: inside(m)}) {
+			got = append(got, m)
+		}
+	}
+	return got
+}
+
+func TestAnalyzer(t *testing.T) {
+	tests := []struct {
+		name   string
+		step   int
+		inside func(minute int) float64
+		want   []int
+	}{
+		{"steady with sensor noise", 1, func(m int) float64 { return 21 + 0.5*float64(m%3-1) }, []int{}},
+		{"slow cooling at night", 1, func(m int) float64 { return 21 - 0.02*float64(m) }, []int{}},
+		{"window opened at minute 30", 1, func(m int) float64 {
+			if m < 30 {
+				return 21
+			}
+			return math.Max(21-0.4*float64(m-30), 18.5)
+		}, []int{34}},
+		{"window opened twice", 1, func(m int) float64 {
+			switch {
+			case m >= 30 && m < 40:
+				return 19
+			case m >= 70 && m < 80:
+				return 18
+			}
+			return 21
+		}, []int{30, 70}},
+		{"drop between sparse polls", 20, func(m int) float64 { return 21 - 0.1*float64(m) }, []int{}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, detect(120, tc.step, tc.inside)); diff != "" {
+			t.Errorf("%s: detections mismatch (-want +got):\n%s", tc.name, diff)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	c := cloudtest.Default()
+	heating := func(d *pt.Device) {
+		d.Parameters.Operate = 1
+		d.Parameters.FanSpeed = pt.FanSpeeds["low"]
+		d.Parameters.FanAutoMode = pt.FanAutoMode["lr"]
+		d.Parameters.AirSwingUD = pt.AirSwingUD["down"]
+		d.Parameters.EcoMode = 2
+		d.Parameters.InsideTemperature = 21
+	}
+	if err := c.Update(living, heating); err != nil {
+		t.Fatal(err)
+	}
+	client := testclient.New(t, c)
+
+	m, err := window.New(client, window.Config{Devices: []string{"My House"}, Pause: 30 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var now time.Time
+	m.Now = func() time.Time { return now }
+	check := func(minute int, inside float64) window.Result {
+		t.Helper()
+		now = start.Add(time.Duration(minute) * time.Minute)
+		if err := c.Update(living, func(d *pt.Device) { d.Parameters.InsideTemperature = inside }); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range m.Check() {
+			if r.Err != nil {
+				t.Fatalf("minute %d: %s: %v", minute, r.DeviceName, r.Err)
+			}
+			if r.DeviceGUID == living {
+				return r
+			}
+		}
+		t.Fatalf("minute %d: no result for the living room", minute)
+		return window.Result{}
+	}
+
+	// The bedroom is off and never flagged, the living room is paused
+	// and restored
+	check(0, 21)
+	check(2, 20.5)
+	if r := check(4, 19); !r.Open || !r.Paused {
+		t.Errorf("got %+v, want open window and paused", r)
+	}
+	if d, _ := c.Status(living); d.Parameters.Operate != 0 {
+		t.Error("living room is on, want paused")
+	}
+	// The device forgets its settings while it is off
+	if err := c.Update(living, func(d *pt.Device) {
+		d.Parameters.FanAutoMode = pt.FanAutoMode["disabled"]
+		d.Parameters.AirSwingUD = pt.AirSwingUD["up"]
+		d.Parameters.EcoMode = 0
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r := check(20, 17); r.Open || !r.Paused {
+		t.Errorf("got %+v, want paused without a new detection", r)
+	}
+	if r := check(34, 17); !r.Restored {
+		t.Errorf("got %+v, want restored", r)
+	}
+	d, _ := c.Status(living)
+	if p := d.Parameters; p.Operate != 1 || p.OperationMode != pt.Modes["heat"] || p.TemperatureSet != 21 || p.FanSpeed != pt.FanSpeeds["low"] ||
+		p.FanAutoMode != pt.FanAutoMode["lr"] || p.AirSwingUD != pt.AirSwingUD["down"] || p.EcoMode != 2 {
+		t.Errorf("living room is %+v, want heating at 21 with low fan speed, vertical vane down and eco mode 2", p)
+	}
+
+	// A device turned on during the pause is not restored
+	check(40, 20)
+	if r := check(42, 18); !r.Paused {
+		t.Fatalf("got %+v, want paused", r)
+	}
+	if err := c.Update(living, heating); err != nil {
+		t.Fatal(err)
+	}
+	commands := len(c.Commands())
+	if r := check(80, 21); r.Restored || r.Paused {
+		t.Errorf("got %+v, want neither restored nor paused", r)
+	}
+	if got := len(c.Commands()); got != commands {
+		t.Errorf("got %d commands after the pause, want none", got-commands)
+	}
+}
+
+func TestRunRestores(t *testing.T) {
+	c := cloudtest.Default()
+	if err := c.Update(living, func(d *pt.Device) { d.Parameters.Operate, d.Parameters.InsideTemperature = 1, 21 }); err != nil {
+		t.Fatal(err)
+	}
+	client := testclient.New(t, c)
+	m, err := window.New(client, window.Config{Devices: []string{"Living"}, Pause: 30 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := start
+	m.Now = func() time.Time { return now }
+	m.Check()
+	now = now.Add(4 * time.Minute)
+	if err := c.Update(living, func(d *pt.Device) { d.Parameters.InsideTemperature = 19 }); err != nil {
+		t.Fatal(err)
+	}
+	if r := m.Check(); len(r) != 1 || !r[0].Paused {
+		t.Fatalf("got %+v, want paused", r)
+	}
+
+	// Stopping during the pause turns the device back on
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+	d, _ := c.Status(living)
+	if p := d.Parameters; p.Operate != 1 || p.OperationMode != pt.Modes["heat"] || p.TemperatureSet != 21 {
+		t.Errorf("living room is %+v after stopping, want heating at 21", p)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	client := testclient.New(t, cloudtest.Default())
+	for _, config := range []window.Config{
+		{},
+		{Devices: []string{"Attic"}},
+		{Devices: []string{"Living"}, Pause: -time.Minute},
+	} {
+		if _, err := window.New(client, config); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", config)
+		}
+	}
+}